@@ -98,6 +98,16 @@ func (sl *StringLiteral) expressionNode()      {}
 func (sl *StringLiteral) TokenLiteral() string { return sl.Token.Literal }
 func (sl *StringLiteral) String() string       { return "\"" + sl.Value + "\"" }
 
+// FlagLiteral represents a command option: -l, -la, --all, --name=value
+type FlagLiteral struct {
+	Token token.Token
+	Value string // The option text including the leading dash
+}
+
+func (fl *FlagLiteral) expressionNode()      {}
+func (fl *FlagLiteral) TokenLiteral() string { return fl.Token.Literal }
+func (fl *FlagLiteral) String() string       { return fl.Value }
+
 // VariableReference represents $VAR syntax
 type VariableReference struct {
 	Token token.Token // the DOLLAR token
@@ -124,6 +134,30 @@ const (
 	CMD_PRINT      CommandType = "print"
 	CMD_SHOW       CommandType = "show"
 	CMD_CLEAR      CommandType = "clear"
+	CMD_SET        CommandType = "set"
+	CMD_TRASH      CommandType = "trash"
+	CMD_UNDO       CommandType = "undo"
+	CMD_COPY       CommandType = "cp"
+	CMD_MOVE       CommandType = "mv"
+	CMD_LINK       CommandType = "ln"
+	CMD_TOUCH      CommandType = "touch"
+	CMD_GREP       CommandType = "grep"
+	CMD_HEAD       CommandType = "head"
+	CMD_TAIL       CommandType = "tail"
+	CMD_WC         CommandType = "wc"
+	CMD_SORT       CommandType = "sort"
+	CMD_UNIQ       CommandType = "uniq"
+	CMD_FIND       CommandType = "find"
+	CMD_WHERE      CommandType = "where"
+	CMD_SORTBY     CommandType = "sort-by"
+	CMD_SELECT     CommandType = "select"
+	CMD_FROMJSON   CommandType = "from-json"
+	CMD_TOJSON     CommandType = "to-json"
+	CMD_FROMCSV    CommandType = "from-csv"
+	CMD_TOCSV      CommandType = "to-csv"
+	CMD_FROMTSV    CommandType = "from-tsv"
+	CMD_TOTSV      CommandType = "to-tsv"
+	CMD_BIND       CommandType = "bind"
 	CMD_TILDE      CommandType = "~"
 	CMD_EXTERNAL   CommandType = "external"
 )
@@ -134,6 +168,9 @@ type Command struct {
 	Type      CommandType  // The command type
 	Name      string       // The command name as string
 	Arguments []Expression // Command arguments
+	// The line read as an expression, for an external command that can be
+	// one: y -z is y - z when y is a variable
+	Expression Expression
 }
 
 func (c *Command) expressionNode()      {}
@@ -197,18 +234,19 @@ func (re *RedirectionExpression) String() string {
 	return out.String()
 }
 
-// AssignmentStatement represents variable assignment: x = value
+// AssignmentStatement represents assignment to a variable or to an element
+// of one: x = value, arr[0] = value, row["name"] = value
 type AssignmentStatement struct {
-	Token token.Token // the ASSIGN token
-	Name  *Identifier
-	Value Expression
+	Token  token.Token // the ASSIGN token
+	Target Expression  // an *Identifier or an *IndexExpression
+	Value  Expression
 }
 
 func (as *AssignmentStatement) statementNode()       {}
 func (as *AssignmentStatement) TokenLiteral() string { return as.Token.Literal }
 func (as *AssignmentStatement) String() string {
 	var out bytes.Buffer
-	out.WriteString(as.Name.String())
+	out.WriteString(as.Target.String())
 	out.WriteString(" = ")
 	if as.Value != nil {
 		out.WriteString(as.Value.String())
@@ -279,6 +317,109 @@ func (is *IfStatement) String() string {
 	return out.String()
 }
 
+// MatchStatement represents: match value { "a" => { ... }, 1 | 2 => { ... } }
+type MatchStatement struct {
+	Token token.Token // the match name
+	Value Expression  // the value being matched
+	Arms  []*MatchArm
+}
+
+func (ms *MatchStatement) statementNode()       {}
+func (ms *MatchStatement) TokenLiteral() string { return ms.Token.Literal }
+func (ms *MatchStatement) String() string {
+	var out bytes.Buffer
+	out.WriteString(ms.Token.Literal + " ")
+	out.WriteString(ms.Value.String())
+	out.WriteString(" { ")
+	for _, arm := range ms.Arms {
+		out.WriteString(arm.String())
+		out.WriteString(" ")
+	}
+	out.WriteString("}")
+	return out.String()
+}
+
+// MatchArm is one arm of a match: its patterns and the block run when any
+// of them matches
+type MatchArm struct {
+	Patterns []*MatchPattern
+	Body     *BlockStatement
+}
+
+func (ma *MatchArm) String() string {
+	var out bytes.Buffer
+	for i, pattern := range ma.Patterns {
+		if i > 0 {
+			out.WriteString(" | ")
+		}
+		out.WriteString(pattern.String())
+	}
+	out.WriteString(" => ")
+	out.WriteString(ma.Body.String())
+	return out.String()
+}
+
+// MatchPattern is a pattern in a match arm: _, which matches anything, a
+// regular expression written =~ "pattern", or a value. A string literal
+// containing *, ? or [ is a glob.
+type MatchPattern struct {
+	Token token.Token // the pattern's first token
+	Regex bool        // written =~ "pattern"
+	Value Expression  // nil for _
+}
+
+func (mp *MatchPattern) String() string {
+	switch {
+	case mp.Value == nil:
+		return "_"
+	case mp.Regex:
+		return "=~ " + mp.Value.String()
+	}
+	return mp.Value.String()
+}
+
+// TryStatement represents: try { ... } catch err { ... } finally { ... }
+type TryStatement struct {
+	Token     token.Token     // the TRY token
+	Body      *BlockStatement // the try body
+	CatchName *Identifier     // optional name the error is bound to
+	Catch     *BlockStatement // optional catch body
+	Finally   *BlockStatement // optional finally body
+}
+
+func (ts *TryStatement) statementNode()       {}
+func (ts *TryStatement) TokenLiteral() string { return ts.Token.Literal }
+func (ts *TryStatement) String() string {
+	var out bytes.Buffer
+	out.WriteString("try ")
+	out.WriteString(ts.Body.String())
+	if ts.Catch != nil {
+		out.WriteString(" catch ")
+		if ts.CatchName != nil {
+			out.WriteString(ts.CatchName.String() + " ")
+		}
+		out.WriteString(ts.Catch.String())
+	}
+	if ts.Finally != nil {
+		out.WriteString(" finally ")
+		out.WriteString(ts.Finally.String())
+	}
+	return out.String()
+}
+
+// PrefixExpression represents unary operations: -x, +x, !done
+type PrefixExpression struct {
+	Token    token.Token // the operator token
+	Operator string
+	Right    Expression
+}
+
+func (pe *PrefixExpression) expressionNode()      {}
+func (pe *PrefixExpression) TokenLiteral() string { return pe.Token.Literal }
+func (pe *PrefixExpression) String() string {
+	return "(" + pe.Operator + pe.Right.String() + ")"
+}
+
 // InfixExpression represents binary operations: left op right
 type InfixExpression struct {
 	Token    token.Token // the operator token
@@ -333,18 +474,46 @@ func (al *ArrayLiteral) expressionNode()      {}
 func (al *ArrayLiteral) TokenLiteral() string { return al.Token.Literal }
 func (al *ArrayLiteral) String() string {
 	var out bytes.Buffer
-	out.WriteString("[")
 	if al.TypeHint != "" {
-		out.WriteString("]" + al.TypeHint)
-	} else {
-		for i, el := range al.Elements {
-			if i > 0 {
-				out.WriteString(", ")
-			}
-			out.WriteString(el.String())
+		out.WriteString("[]" + al.TypeHint)
+		if len(al.Elements) == 0 {
+			return out.String()
 		}
-		out.WriteString("]")
 	}
+	out.WriteString("[")
+	for i, el := range al.Elements {
+		if i > 0 {
+			out.WriteString(", ")
+		}
+		out.WriteString(el.String())
+	}
+	out.WriteString("]")
+	return out.String()
+}
+
+// SliceExpression represents slicing: arr[1:3], arr[:2], s[1:]
+type SliceExpression struct {
+	Token token.Token // the LBRACKET token
+	Left  Expression  // the array or string
+	Start Expression  // nil when omitted
+	End   Expression  // nil when omitted
+}
+
+func (se *SliceExpression) expressionNode()      {}
+func (se *SliceExpression) TokenLiteral() string { return se.Token.Literal }
+func (se *SliceExpression) String() string {
+	var out bytes.Buffer
+	out.WriteString("(")
+	out.WriteString(se.Left.String())
+	out.WriteString("[")
+	if se.Start != nil {
+		out.WriteString(se.Start.String())
+	}
+	out.WriteString(":")
+	if se.End != nil {
+		out.WriteString(se.End.String())
+	}
+	out.WriteString("])")
 	return out.String()
 }
 
@@ -175,6 +175,25 @@ func TestIdentifierString(t *testing.T) {
 	}
 }
 
+func TestSliceExpressionString(t *testing.T) {
+	arr := &Identifier{Token: token.Token{Type: token.IDENT, Literal: "arr"}, Value: "arr"}
+	one := &IntegerLiteral{Token: token.Token{Type: token.INTEGER, Literal: "1"}, Value: 1}
+
+	tests := []struct {
+		slice    *SliceExpression
+		expected string
+	}{
+		{&SliceExpression{Left: arr, Start: one}, "(arr[1:])"},
+		{&SliceExpression{Left: arr, End: one}, "(arr[:1])"},
+		{&SliceExpression{Left: arr, Start: one, End: one}, "(arr[1:1])"},
+	}
+	for _, tt := range tests {
+		if got := tt.slice.String(); got != tt.expected {
+			t.Errorf("slice.String() wrong. expected=%q, got=%q", tt.expected, got)
+		}
+	}
+}
+
 func TestTokenLiteralMethods(t *testing.T) {
 	// Test that TokenLiteral returns the correct values
 	cmd := &Command{
@@ -0,0 +1,93 @@
+package evaluator
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"ravenshell/ast"
+	"sort"
+)
+
+// KeyBinder is the line editor whose keys bind changes. The REPL sets it
+// with SetKeyBinder; scripts run without one.
+type KeyBinder interface {
+	BindAction(key, action string) error
+	BindCommand(key, command string) error
+	BindText(key, text string) error
+	Unbind(key string) error
+	Bindings() map[string]string // what each bound key does, by key name
+	Actions() []string           // names of the editor actions
+}
+
+// SetKeyBinder sets the line editor bind changes
+func (e *Evaluator) SetKeyBinder(k KeyBinder) {
+	e.keys = k
+}
+
+// execBind lists key bindings or editor actions, or binds or unbinds a key
+func (e *Evaluator) execBind(flags flagSet, args []string) (string, error) {
+	if e.keys == nil {
+		return "", errors.New("bind: key bindings only apply in the interactive shell")
+	}
+	spec := builtinSpecs[ast.CMD_BIND]
+
+	switch {
+	case flags.has("unset"):
+		if len(args) != 1 {
+			return "", spec.usageError("--unset takes a key")
+		}
+		return "", bindError(e.keys.Unbind(args[0]))
+
+	case flags.has("actions"):
+		if len(args) > 0 {
+			return "", spec.usageError("--actions takes no arguments")
+		}
+		var output bytes.Buffer
+		for _, name := range e.keys.Actions() {
+			fmt.Fprintln(&output, name)
+		}
+		return e.printBindOutput(output.String()), nil
+
+	case len(args) == 0:
+		bindings := e.keys.Bindings()
+		keys := make([]string, 0, len(bindings))
+		width := 0
+		for key := range bindings {
+			keys = append(keys, key)
+			width = max(width, len(key))
+		}
+		sort.Strings(keys)
+		var output bytes.Buffer
+		for _, key := range keys {
+			fmt.Fprintf(&output, "%-*s  %s\n", width, key, bindings[key])
+		}
+		return e.printBindOutput(output.String()), nil
+
+	}
+
+	if len(args) != 2 {
+		return "", spec.usageError("expected a key and what to bind it to")
+	}
+	key, target := args[0], args[1]
+	switch {
+	case flags.has("command"):
+		return "", bindError(e.keys.BindCommand(key, target))
+	case flags.has("insert"):
+		return "", bindError(e.keys.BindText(key, target))
+	}
+	return "", bindError(e.keys.BindAction(key, target))
+}
+
+// printBindOutput prints a listing of bind and returns it
+func (e *Evaluator) printBindOutput(output string) string {
+	fmt.Fprint(e.stdout, output)
+	return output
+}
+
+// bindError prefixes an error from the line editor with bind
+func bindError(err error) error {
+	if err != nil {
+		return fmt.Errorf("bind: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package evaluator
+
+import (
+	"ravenshell/readline"
+	"strings"
+	"testing"
+)
+
+func TestBind(t *testing.T) {
+	e, out := newTestEvaluator(t, t.TempDir())
+	rl := readline.New("")
+	rl.SetCommandRunner(func(string) {})
+	e.SetKeyBinder(rl)
+
+	input := `bind "ctrl-t" beginning_of_line
+bind "alt-b" "kill-word"
+bind --insert "f5" "| wc -l"
+bind --command "ctrl-g" "git status"
+bind --unset "ctrl-l"
+bind`
+	if err := evalInput(t, e, input); err != nil {
+		t.Fatal(err)
+	}
+	listing := out.String()
+	for _, want := range []string{
+		"ctrl-t         beginning_of_line\n",
+		"alt-b          kill_word\n",
+		"f5             insert \"| wc -l\"\n",
+		"ctrl-g         command \"git status\"\n",
+		"enter          accept_line\n",
+	} {
+		if !strings.Contains(listing, want) {
+			t.Errorf("listing is missing %q:\n%s", want, listing)
+		}
+	}
+	if strings.Contains(listing, "ctrl-l") {
+		t.Errorf("ctrl-l still bound after --unset:\n%s", listing)
+	}
+
+	out.Reset()
+	if err := evalInput(t, e, "bind --actions"); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(out.String(), "accept_line\n") || !strings.Contains(out.String(), "\nyank_pop\n") {
+		t.Errorf("wrong action list:\n%s", out.String())
+	}
+}
+
+func TestBindErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`bind "ctrl-t" nope`, "bind: unknown action: nope"},
+		{`bind "hyper-x" yank`, `bind: unknown key: "hyper-x"`},
+		{`bind "ctrl-1" yank`, `bind: unknown key: "ctrl-1"`},
+		{`bind "ctrl-t"`, "bind: expected a key and what to bind it to"},
+		{`bind --unset`, "bind: --unset takes a key"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			e, _ := newTestEvaluator(t, t.TempDir())
+			e.SetKeyBinder(readline.New(""))
+			err := evalInput(t, e, tt.input)
+			if err == nil || !strings.HasPrefix(err.Error(), tt.expected) {
+				t.Errorf("expected error %q, got %v", tt.expected, err)
+			}
+		})
+	}
+
+	e, _ := newTestEvaluator(t, t.TempDir())
+	if err := evalInput(t, e, "bind"); err == nil || !strings.Contains(err.Error(), "interactive shell") {
+		t.Errorf("expected an error without a line editor, got %v", err)
+	}
+}
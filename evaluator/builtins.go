@@ -0,0 +1,528 @@
+package evaluator
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// builtinFunc implements a function callable from scripts. Its arguments
+// are already evaluated and their count checked; name is the name it was
+// called by, for error messages.
+type builtinFunc func(e *Evaluator, name string, args []Value) (Value, error)
+
+// builtin describes one function in the registry
+type builtin struct {
+	minArgs int
+	maxArgs int // -1 for no limit
+	fn      builtinFunc
+}
+
+// builtins maps each function name to its implementation. It is filled in
+// by init because the functions refer back to the evaluator, which looks
+// functions up here.
+var builtins map[string]builtin
+
+func init() {
+	builtins = map[string]builtin{
+		// Arrays and sequences
+		"range":   {1, 3, (*Evaluator).builtinRange},
+		"append":  {2, 2, (*Evaluator).builtinAppend},
+		"len":     {1, 1, (*Evaluator).builtinLen},
+		"reverse": {1, 1, (*Evaluator).builtinReverse},
+		"sort":    {1, 1, (*Evaluator).builtinSort},
+		"slice":   {2, 3, (*Evaluator).builtinSlice},
+		"join":    {2, 2, (*Evaluator).builtinJoin},
+
+		// Strings
+		"upper":       {1, 1, (*Evaluator).builtinUpper},
+		"lower":       {1, 1, (*Evaluator).builtinLower},
+		"trim":        {1, 2, (*Evaluator).builtinTrim},
+		"contains":    {2, 2, (*Evaluator).builtinContains},
+		"starts_with": {2, 2, (*Evaluator).builtinStartsWith},
+		"ends_with":   {2, 2, (*Evaluator).builtinEndsWith},
+		"substr":      {2, 3, (*Evaluator).builtinSubstr},
+		"split":       {1, 2, (*Evaluator).builtinSplit},
+		"replace":     {3, 3, (*Evaluator).builtinReplace},
+		"find_all":    {2, 2, (*Evaluator).builtinFindAll},
+
+		// Numbers and conversions
+		"min":  {1, -1, (*Evaluator).builtinMinMax},
+		"max":  {1, -1, (*Evaluator).builtinMinMax},
+		"sum":  {1, -1, (*Evaluator).builtinSum},
+		"abs":  {1, 1, (*Evaluator).builtinAbs},
+		"int":  {1, 1, (*Evaluator).builtinInt},
+		"str":  {1, 1, (*Evaluator).builtinStr},
+		"type": {1, 1, (*Evaluator).builtinType},
+
+		// Errors
+		"raise": {1, 2, (*Evaluator).builtinRaise},
+
+		// Structured data
+		"from-json": {1, 2, (*Evaluator).builtinFromJSON},
+		"to-json":   {1, 2, (*Evaluator).builtinToJSON},
+		"from-csv":  {1, 1, (*Evaluator).builtinFromCSV},
+		"from-tsv":  {1, 1, (*Evaluator).builtinFromCSV},
+		"to-csv":    {1, 1, (*Evaluator).builtinToCSV},
+		"to-tsv":    {1, 1, (*Evaluator).builtinToCSV},
+	}
+}
+
+// checkArity returns an error unless n arguments suit the function
+func (b builtin) checkArity(name string, n int) error {
+	if n >= b.minArgs && (b.maxArgs < 0 || n <= b.maxArgs) {
+		return nil
+	}
+	switch {
+	case b.minArgs == b.maxArgs:
+		return fmt.Errorf("%s() takes exactly %s", name, pluralArgs(b.minArgs))
+	case b.maxArgs < 0:
+		return fmt.Errorf("%s() takes at least %s", name, pluralArgs(b.minArgs))
+	case b.maxArgs == b.minArgs+1:
+		return fmt.Errorf("%s() takes %d or %d arguments", name, b.minArgs, b.maxArgs)
+	}
+	return fmt.Errorf("%s() takes %d to %d arguments", name, b.minArgs, b.maxArgs)
+}
+
+func pluralArgs(n int) string {
+	if n == 1 {
+		return "1 argument"
+	}
+	return fmt.Sprintf("%d arguments", n)
+}
+
+// typeName describes a value's type in error messages
+func typeName(val Value) string {
+	switch val.(type) {
+	case int64, int:
+		return "integer"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case bool:
+		return "bool"
+	case []Value:
+		return "array"
+	case *Record:
+		return "record"
+	case *ShellError:
+		return "error"
+	case time.Time:
+		return "time"
+	case nil:
+		return "nil"
+	}
+	return fmt.Sprintf("%T", val)
+}
+
+// argTypeError reports an argument of the wrong type, counting from 1
+func argTypeError(name string, i int, want string, got Value) error {
+	return fmt.Errorf("%s() argument %d must be %s, got %s", name, i+1, want, typeName(got))
+}
+
+// stringArg returns argument i as text. Numbers and bools convert; arrays
+// and records don't.
+func (e *Evaluator) stringArg(name string, args []Value, i int) (string, error) {
+	switch args[i].(type) {
+	case []Value, *Record:
+		return "", argTypeError(name, i, "a string", args[i])
+	}
+	return e.valueToString(args[i]), nil
+}
+
+// intArg returns argument i as an integer. Text holding a whole number, as
+// read from a file or CSV field, converts.
+func (e *Evaluator) intArg(name string, args []Value, i int) (int64, error) {
+	n, err := e.valueToInt64(args[i])
+	if err != nil {
+		return 0, argTypeError(name, i, "an integer", args[i])
+	}
+	return n, nil
+}
+
+// arrayArg returns argument i, which must be an array
+func arrayArg(name string, args []Value, i int) ([]Value, error) {
+	arr, ok := args[i].([]Value)
+	if !ok {
+		return nil, argTypeError(name, i, "an array", args[i])
+	}
+	return arr, nil
+}
+
+// stringsToValues converts a list of strings to an array
+func stringsToValues(strs []string) []Value {
+	result := make([]Value, len(strs))
+	for i, s := range strs {
+		result[i] = s
+	}
+	return result
+}
+
+// builtinRange implements range(stop), range(start, stop) and
+// range(start, stop, step)
+func (e *Evaluator) builtinRange(name string, args []Value) (Value, error) {
+	nums := make([]int64, len(args))
+	for i := range args {
+		n, err := e.intArg(name, args, i)
+		if err != nil {
+			return nil, err
+		}
+		nums[i] = n
+	}
+
+	start, stop, step := int64(0), nums[0], int64(1)
+	if len(nums) > 1 {
+		start, stop = nums[0], nums[1]
+	}
+	if len(nums) > 2 {
+		step = nums[2]
+	}
+	if step == 0 {
+		return nil, fmt.Errorf("range() step must not be zero")
+	}
+
+	result := []Value{}
+	for i := start; (step > 0 && i < stop) || (step < 0 && i > stop); i += step {
+		result = append(result, i)
+	}
+	return result, nil
+}
+
+// builtinAppend implements append(arr, val) - returns new array with val appended
+func (e *Evaluator) builtinAppend(name string, args []Value) (Value, error) {
+	arr, err := arrayArg(name, args, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	// Create a new array with the value appended
+	result := make([]Value, len(arr)+1)
+	copy(result, arr)
+	result[len(arr)] = args[1]
+	return result, nil
+}
+
+// builtinLen implements len(x): characters in a string, elements in an
+// array or fields in a record
+func (e *Evaluator) builtinLen(name string, args []Value) (Value, error) {
+	switch v := args[0].(type) {
+	case []Value:
+		return int64(len(v)), nil
+	case *Record:
+		return int64(len(v.Keys())), nil
+	case string:
+		return int64(utf8.RuneCountInString(v)), nil
+	}
+	return nil, argTypeError(name, 0, "a string, array or record", args[0])
+}
+
+// builtinReverse implements reverse(x) for strings and arrays
+func (e *Evaluator) builtinReverse(name string, args []Value) (Value, error) {
+	switch v := args[0].(type) {
+	case []Value:
+		result := make([]Value, len(v))
+		for i, elem := range v {
+			result[len(v)-1-i] = elem
+		}
+		return result, nil
+	case string:
+		runes := []rune(v)
+		for i, j := 0, len(runes)-1; i < j; i, j = i+1, j-1 {
+			runes[i], runes[j] = runes[j], runes[i]
+		}
+		return string(runes), nil
+	}
+	return nil, argTypeError(name, 0, "a string or array", args[0])
+}
+
+// builtinSort implements sort(arr), returning a sorted copy. Numbers sort
+// by value and anything else as text, as in sort-by.
+func (e *Evaluator) builtinSort(name string, args []Value) (Value, error) {
+	arr, err := arrayArg(name, args, 0)
+	if err != nil {
+		return nil, err
+	}
+	sorted := append([]Value{}, arr...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return e.compareValues(sorted[i], sorted[j]) < 0
+	})
+	return sorted, nil
+}
+
+// sliceBounds turns start and optional stop arguments into bounds within
+// 0..length. Negative positions count back from the end.
+func (e *Evaluator) sliceBounds(name string, args []Value, length int) (int, int, error) {
+	start, err := e.intArg(name, args, 1)
+	if err != nil {
+		return 0, 0, err
+	}
+	lo, hi := clampIndex(start, length), length
+	if len(args) > 2 {
+		stop, err := e.intArg(name, args, 2)
+		if err != nil {
+			return 0, 0, err
+		}
+		hi = clampIndex(stop, length)
+	}
+	return lo, max(lo, hi), nil
+}
+
+// builtinSlice implements slice(x, start) and slice(x, start, stop) for
+// arrays and strings. stop is exclusive.
+func (e *Evaluator) builtinSlice(name string, args []Value) (Value, error) {
+	switch v := args[0].(type) {
+	case []Value:
+		lo, hi, err := e.sliceBounds(name, args, len(v))
+		if err != nil {
+			return nil, err
+		}
+		return append([]Value{}, v[lo:hi]...), nil
+	case string:
+		runes := []rune(v)
+		lo, hi, err := e.sliceBounds(name, args, len(runes))
+		if err != nil {
+			return nil, err
+		}
+		return string(runes[lo:hi]), nil
+	}
+	return nil, argTypeError(name, 0, "a string or array", args[0])
+}
+
+// builtinJoin implements join(arr, sep)
+func (e *Evaluator) builtinJoin(name string, args []Value) (Value, error) {
+	arr, err := arrayArg(name, args, 0)
+	if err != nil {
+		return nil, err
+	}
+	sep, err := e.stringArg(name, args, 1)
+	if err != nil {
+		return nil, err
+	}
+	strs := make([]string, len(arr))
+	for i, elem := range arr {
+		strs[i] = e.valueToString(elem)
+	}
+	return strings.Join(strs, sep), nil
+}
+
+// builtinUpper implements upper(s)
+func (e *Evaluator) builtinUpper(name string, args []Value) (Value, error) {
+	s, err := e.stringArg(name, args, 0)
+	if err != nil {
+		return nil, err
+	}
+	return strings.ToUpper(s), nil
+}
+
+// builtinLower implements lower(s)
+func (e *Evaluator) builtinLower(name string, args []Value) (Value, error) {
+	s, err := e.stringArg(name, args, 0)
+	if err != nil {
+		return nil, err
+	}
+	return strings.ToLower(s), nil
+}
+
+// builtinTrim implements trim(s), which removes surrounding whitespace, and
+// trim(s, chars), which removes any of chars instead
+func (e *Evaluator) builtinTrim(name string, args []Value) (Value, error) {
+	s, err := e.stringArg(name, args, 0)
+	if err != nil {
+		return nil, err
+	}
+	if len(args) == 1 {
+		return strings.TrimSpace(s), nil
+	}
+	chars, err := e.stringArg(name, args, 1)
+	if err != nil {
+		return nil, err
+	}
+	return strings.Trim(s, chars), nil
+}
+
+// builtinContains implements contains(s, sub) for strings, contains(arr,
+// val) for arrays and contains(record, field) for records
+func (e *Evaluator) builtinContains(name string, args []Value) (Value, error) {
+	switch v := args[0].(type) {
+	case []Value:
+		for _, elem := range v {
+			if e.compareValues(elem, args[1]) == 0 {
+				return true, nil
+			}
+		}
+		return false, nil
+	case *Record:
+		_, ok := v.Get(e.valueToString(args[1]))
+		return ok, nil
+	}
+	s, err := e.stringArg(name, args, 0)
+	if err != nil {
+		return nil, err
+	}
+	sub, err := e.stringArg(name, args, 1)
+	if err != nil {
+		return nil, err
+	}
+	return strings.Contains(s, sub), nil
+}
+
+// builtinStartsWith implements starts_with(s, prefix)
+func (e *Evaluator) builtinStartsWith(name string, args []Value) (Value, error) {
+	s, err := e.stringArg(name, args, 0)
+	if err != nil {
+		return nil, err
+	}
+	prefix, err := e.stringArg(name, args, 1)
+	if err != nil {
+		return nil, err
+	}
+	return strings.HasPrefix(s, prefix), nil
+}
+
+// builtinEndsWith implements ends_with(s, suffix)
+func (e *Evaluator) builtinEndsWith(name string, args []Value) (Value, error) {
+	s, err := e.stringArg(name, args, 0)
+	if err != nil {
+		return nil, err
+	}
+	suffix, err := e.stringArg(name, args, 1)
+	if err != nil {
+		return nil, err
+	}
+	return strings.HasSuffix(s, suffix), nil
+}
+
+// builtinSubstr implements substr(s, start) and substr(s, start, length),
+// counting in characters. A negative start counts back from the end.
+func (e *Evaluator) builtinSubstr(name string, args []Value) (Value, error) {
+	s, err := e.stringArg(name, args, 0)
+	if err != nil {
+		return nil, err
+	}
+	runes := []rune(s)
+	lo, hi, err := e.sliceBounds(name, args[:2], len(runes))
+	if err != nil {
+		return nil, err
+	}
+	if len(args) > 2 {
+		length, err := e.intArg(name, args, 2)
+		if err != nil {
+			return nil, err
+		}
+		if length < 0 {
+			return nil, fmt.Errorf("%s() length must not be negative", name)
+		}
+		hi = min(hi, lo+int(min(length, int64(len(runes)))))
+	}
+	return string(runes[lo:hi]), nil
+}
+
+// numberArgs returns the values min, max and sum work on: the elements of
+// a single array argument, or else the arguments themselves
+func numberArgs(name string, args []Value) ([]Value, error) {
+	values := args
+	if len(args) == 1 {
+		arr, ok := args[0].([]Value)
+		if !ok {
+			return nil, argTypeError(name, 0, "an array", args[0])
+		}
+		values = arr
+	}
+	return values, nil
+}
+
+// builtinMinMax implements min and max, of an array or of their arguments:
+// min(3, 1, 2) or max(sizes)
+func (e *Evaluator) builtinMinMax(name string, args []Value) (Value, error) {
+	values, err := numberArgs(name, args)
+	if err != nil {
+		return nil, err
+	}
+	if len(values) == 0 {
+		return nil, fmt.Errorf("%s() of an empty array", name)
+	}
+	best := values[0]
+	for _, val := range values[1:] {
+		c := e.compareValues(val, best)
+		if (name == "min" && c < 0) || (name == "max" && c > 0) {
+			best = val
+		}
+	}
+	return best, nil
+}
+
+// builtinSum implements sum(arr) and sum(a, b, ...) for integers
+func (e *Evaluator) builtinSum(name string, args []Value) (Value, error) {
+	values, err := numberArgs(name, args)
+	if err != nil {
+		return nil, err
+	}
+	var total int64
+	for i, val := range values {
+		n, err := e.valueToInt64(val)
+		if err != nil {
+			if len(args) == 1 {
+				return nil, fmt.Errorf("%s() element %d must be an integer, got %s", name, i, typeName(val))
+			}
+			return nil, argTypeError(name, i, "an integer", val)
+		}
+		total += n
+	}
+	return total, nil
+}
+
+// builtinAbs implements abs(n)
+func (e *Evaluator) builtinAbs(name string, args []Value) (Value, error) {
+	n, err := e.intArg(name, args, 0)
+	if err != nil {
+		return nil, err
+	}
+	if n < 0 {
+		if n == math.MinInt64 {
+			return nil, fmt.Errorf("%s() of %d overflows", name, n)
+		}
+		return -n, nil
+	}
+	return n, nil
+}
+
+// builtinInt implements int(x), converting text, numbers and bools to an
+// integer. Decimal numbers are truncated.
+func (e *Evaluator) builtinInt(name string, args []Value) (Value, error) {
+	switch v := args[0].(type) {
+	case int64:
+		return v, nil
+	case int:
+		return int64(v), nil
+	case float64:
+		if math.IsNaN(v) || math.IsInf(v, 0) || v >= math.MaxInt64 || v < math.MinInt64 {
+			return nil, fmt.Errorf("%s() cannot convert %v to an integer", name, v)
+		}
+		return int64(v), nil
+	case bool:
+		if v {
+			return int64(1), nil
+		}
+		return int64(0), nil
+	case string:
+		text := strings.TrimSpace(v)
+		if n, err := strconv.ParseInt(text, 10, 64); err == nil {
+			return n, nil
+		}
+		if f, err := strconv.ParseFloat(text, 64); err == nil && !math.IsInf(f, 0) && !math.IsNaN(f) {
+			return e.builtinInt(name, []Value{f})
+		}
+		return nil, fmt.Errorf("%s() cannot convert '%s' to an integer", name, v)
+	}
+	return nil, argTypeError(name, 0, "a string, number or bool", args[0])
+}
+
+// builtinStr implements str(x), the text print would show for x
+func (e *Evaluator) builtinStr(name string, args []Value) (Value, error) {
+	return e.valueToString(args[0]), nil
+}
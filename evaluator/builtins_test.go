@@ -0,0 +1,89 @@
+package evaluator
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestBuiltinFunctions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected Value
+	}{
+		{`len([1, 2, 3])`, int64(3)},
+		{`len("héllo")`, int64(5)},
+		{`upper("abc") + lower("DEF")`, "ABCdef"},
+		{`trim("  x  ")`, "x"},
+		{`trim("--x--", "-")`, "x"},
+		{`split("a b  c")`, []Value{"a", "b", "c"}},
+		{`join(["a", 1, "b"], "-")`, "a-1-b"},
+		{`contains("hello", "ell")`, true},
+		{`contains([1, 2], 3)`, false},
+		{`starts_with("hello", "he")`, true},
+		{`ends_with("hello", "he")`, false},
+		{`substr("hello", 1, 3)`, "ell"},
+		{`substr("hello", -3)`, "llo"},
+		{`substr("hello", 2, 100)`, "llo"},
+		{`reverse([1, 2, 3])`, []Value{int64(3), int64(2), int64(1)}},
+		{`reverse("abc")`, "cba"},
+		{`sort([10, 9, 100])`, []Value{int64(9), int64(10), int64(100)}},
+		{`sort(["pear", "apple"])`, []Value{"apple", "pear"}},
+		{`slice([1, 2, 3, 4], 1, 3)`, []Value{int64(2), int64(3)}},
+		{`slice([1, 2, 3, 4], -1)`, []Value{int64(4)}},
+		{`slice("hello", 3, 1)`, ""},
+		{`min([5, 3, 12])`, int64(3)},
+		{`max(5, 30, 12)`, int64(30)},
+		{`sum([1, 2, "3"])`, int64(6)},
+		{`abs(3 - 10)`, int64(7)},
+		{`int("42") + 1`, int64(43)},
+		{`int("3.9")`, int64(3)},
+		{`str(5) + "x"`, "5x"},
+		{`range(3)`, []Value{int64(0), int64(1), int64(2)}},
+		{`range(2, 8, 3)`, []Value{int64(2), int64(5)}},
+		{`range(3, 0, -1)`, []Value{int64(3), int64(2), int64(1)}},
+		{`range(5, 1)`, []Value{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			e, _ := newTestEvaluator(t, t.TempDir())
+			if err := evalInput(t, e, "result = "+tt.input); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(e.vars["result"], tt.expected) {
+				t.Errorf("wrong result. expected=%#v, got=%#v", tt.expected, e.vars["result"])
+			}
+		})
+	}
+}
+
+func TestBuiltinErrors(t *testing.T) {
+	tests := []struct {
+		input   string
+		wantErr string
+	}{
+		{`x = len()`, "len() takes exactly 1 argument"},
+		{`x = join([1])`, "join() takes exactly 2 arguments"},
+		{`x = substr("a")`, "substr() takes 2 or 3 arguments"},
+		{`x = range(1, 2, 3, 4)`, "range() takes 1 to 3 arguments"},
+		{`x = max()`, "max() takes at least 1 argument"},
+		{`x = len(5)`, "len() argument 1 must be a string, array or record, got integer"},
+		{`x = upper([1])`, "upper() argument 1 must be a string, got array"},
+		{`x = slice([1], "a")`, "slice() argument 2 must be an integer, got string"},
+		{`x = sum([1, "a"])`, "sum() element 1 must be an integer, got string"},
+		{`x = min([])`, "min() of an empty array"},
+		{`x = range(1, 5, 0)`, "range() step must not be zero"},
+		{`x = int("abc")`, "int() cannot convert 'abc' to an integer"},
+		{`x = nosuch(1)`, "unknown function: nosuch"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			_, err := runInput(t, t.TempDir(), tt.input)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package evaluator
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// The methods below feed tab completion in the REPL (readline.Provider),
+// so that it offers what the shell knows about at the moment Tab is
+// pressed. Each returns its names sorted.
+
+// Commands returns the names of the built-in commands
+func (e *Evaluator) Commands() []string {
+	names := []string{"exit", "quit"} // handled by the REPL itself
+	for _, spec := range builtinSpecs {
+		names = append(names, spec.name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// Functions returns the names of the built-in functions
+func (e *Evaluator) Functions() []string {
+	names := make([]string, 0, len(builtins))
+	for name := range builtins {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// Variables returns the names of the script variables
+func (e *Evaluator) Variables() []string {
+	names := make([]string, 0, len(e.vars))
+	for name := range e.vars {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// EnvVars returns the names $NAME can expand: the shell's own environment
+// variables and the process environment
+func (e *Evaluator) EnvVars() []string {
+	names := make([]string, 0, len(e.env))
+	for name := range e.env {
+		names = append(names, name)
+	}
+	for _, kv := range os.Environ() {
+		if name, _, ok := strings.Cut(kv, "="); ok && name != "" {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+	return slices.Compact(names)
+}
+
+// Executables returns the names of the programs in the $PATH directories
+func (e *Evaluator) Executables() []string {
+	var names []string
+	for _, dir := range filepath.SplitList(os.Getenv("PATH")) {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			if entry.IsDir() {
+				continue
+			}
+			info, err := entry.Info()
+			if err != nil {
+				continue
+			}
+			// Follow symlinks, which many package managers install
+			if info.Mode()&os.ModeSymlink != 0 {
+				if info, err = os.Stat(filepath.Join(dir, entry.Name())); err != nil || info.IsDir() {
+					continue
+				}
+			}
+			if info.Mode()&0111 != 0 {
+				names = append(names, entry.Name())
+			}
+		}
+	}
+	slices.Sort(names)
+	return slices.Compact(names)
+}
+
+// Flags returns the options a built-in command accepts, long forms first.
+// An option that takes a value is offered as --name=.
+func (e *Evaluator) Flags(command string) []string {
+	var long, short []string
+	for _, spec := range builtinSpecs {
+		if spec.name != command {
+			continue
+		}
+		for _, f := range spec.flags {
+			if f.long != "" {
+				if f.arg != "" {
+					long = append(long, "--"+f.long+"=")
+				} else {
+					long = append(long, "--"+f.long)
+				}
+			}
+			if f.short != 0 {
+				short = append(short, "-"+string(f.short))
+			}
+		}
+		long = append(long, "--help")
+	}
+	slices.Sort(long)
+	slices.Sort(short)
+	return append(long, short...)
+}
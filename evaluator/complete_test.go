@@ -0,0 +1,87 @@
+package evaluator
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestCompletionNames(t *testing.T) {
+	e, _ := newTestEvaluator(t, t.TempDir())
+	if err := evalInput(t, e, "count = 3\nnames = []"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	e.SetEnv("RAVEN_TEST", "1")
+
+	if got := e.Variables(); !slices.Equal(got, []string{"count", "names"}) {
+		t.Errorf("wrong variables: %v", got)
+	}
+	for _, name := range []string{"cd", "ls", "from-json", "exit"} {
+		if !slices.Contains(e.Commands(), name) {
+			t.Errorf("commands are missing %s", name)
+		}
+	}
+	for _, name := range []string{"len", "range", "raise"} {
+		if !slices.Contains(e.Functions(), name) {
+			t.Errorf("functions are missing %s", name)
+		}
+	}
+	if !slices.Contains(e.EnvVars(), "RAVEN_TEST") {
+		t.Errorf("env vars are missing RAVEN_TEST")
+	}
+	if !slices.IsSorted(e.Commands()) || !slices.IsSorted(e.EnvVars()) {
+		t.Errorf("names are not sorted")
+	}
+}
+
+func TestCompletionCommandsFromRegistry(t *testing.T) {
+	e, _ := newTestEvaluator(t, t.TempDir())
+	commands := e.Commands()
+	for _, spec := range builtinSpecs {
+		if !slices.Contains(commands, spec.name) {
+			t.Errorf("commands are missing %s", spec.name)
+		}
+	}
+	if len(commands) != len(builtinSpecs)+2 {
+		t.Errorf("expected the %d built-in commands and exit and quit, got %v", len(builtinSpecs), commands)
+	}
+}
+
+func TestCompletionFlags(t *testing.T) {
+	e, _ := newTestEvaluator(t, t.TempDir())
+
+	flags := e.Flags("ls")
+	for _, flag := range []string{"--all", "--help", "--reverse", "-a", "-l"} {
+		if !slices.Contains(flags, flag) {
+			t.Errorf("ls flags are missing %s: %v", flag, flags)
+		}
+	}
+	if slices.Index(flags, "--all") > slices.Index(flags, "-a") {
+		t.Errorf("long options should come first: %v", flags)
+	}
+	if got := e.Flags("nosuchcommand"); len(got) != 0 {
+		t.Errorf("expected no flags for an unknown command, got %v", got)
+	}
+}
+
+func TestCompletionExecutables(t *testing.T) {
+	dir := t.TempDir()
+	for name, mode := range map[string]os.FileMode{"tool": 0755, "data.txt": 0644} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, mode); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Mkdir(filepath.Join(dir, "subdir"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(dir, "tool"), filepath.Join(dir, "tool-link")); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir)
+
+	e, _ := newTestEvaluator(t, t.TempDir())
+	if got := e.Executables(); !slices.Equal(got, []string{"tool", "tool-link"}) {
+		t.Errorf("wrong executables: %v", got)
+	}
+}
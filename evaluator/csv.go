@@ -0,0 +1,308 @@
+package evaluator
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"ravenshell/ast"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Delimited text maps onto a table: each row after the header becomes a
+// record whose fields are named by the header. Without a header each row is
+// a list. Fields are always read as text; comparisons like where size > 10
+// still treat numeric text as numbers.
+
+// csvOptions holds the layout shared by the CSV and TSV commands
+type csvOptions struct {
+	delimiter  rune
+	noHeader   bool     // --no-header: no header row is read or written
+	columns    []string // --columns: field names for data without a header row
+	lazyQuotes bool     // --lazy-quotes
+	quoteAll   bool     // --quote-all
+}
+
+// isTSV reports whether a command works on tab-separated text
+func isTSV(t ast.CommandType) bool {
+	return t == ast.CMD_FROMTSV || t == ast.CMD_TOTSV
+}
+
+// newCSVOptions reads the options of a CSV or TSV command. TSV always
+// separates fields with a tab and tolerates stray quotes, since TSV files
+// are rarely quoted.
+func newCSVOptions(cmd ast.CommandType, flags flagSet) (csvOptions, error) {
+	spec := builtinSpecs[cmd]
+	opts := csvOptions{
+		delimiter:  ',',
+		noHeader:   flags.has("no-header"),
+		lazyQuotes: flags.has("lazy-quotes"),
+		quoteAll:   flags.has("quote-all"),
+	}
+	if isTSV(cmd) {
+		opts.delimiter = '\t'
+		opts.lazyQuotes = true
+	}
+
+	if text := flags.value("delimiter", ""); text != "" {
+		d, err := parseDelimiter(text)
+		if err != nil {
+			return opts, spec.usageError("%v", err)
+		}
+		opts.delimiter = d
+	}
+	if text := flags.value("columns", ""); text != "" {
+		if opts.noHeader {
+			return opts, spec.usageError("--columns and --no-header can't be used together")
+		}
+		for _, name := range strings.Split(text, ",") {
+			name = strings.TrimSpace(name)
+			if name == "" {
+				return opts, spec.usageError("empty column name in '%s'", text)
+			}
+			opts.columns = append(opts.columns, name)
+		}
+	}
+	return opts, nil
+}
+
+// parseDelimiter accepts a single character, or a tab written as \t or tab
+func parseDelimiter(text string) (rune, error) {
+	if text == `\t` || text == "tab" {
+		return '\t', nil
+	}
+	d, size := utf8.DecodeRuneInString(text)
+	if size != len(text) || d == utf8.RuneError || d == '"' || d == '\r' || d == '\n' {
+		return 0, fmt.Errorf("invalid delimiter '%s' (use a single character other than a quote)", text)
+	}
+	return d, nil
+}
+
+// decodeDelimited reads every row of r as a table, or as a list of rows
+// when there is no header
+func decodeDelimited(r io.Reader, opts csvOptions) (Value, error) {
+	reader := csv.NewReader(r)
+	reader.Comma = opts.delimiter
+	reader.LazyQuotes = opts.lazyQuotes
+	reader.FieldsPerRecord = -1 // checked below, so the message can name the columns
+
+	columns := opts.columns
+	readHeader := !opts.noHeader && columns == nil
+	rows := []Value{}
+	for {
+		fields, err := reader.Read()
+		if err == io.EOF {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, csvSyntaxError(err)
+		}
+
+		switch {
+		case readHeader:
+			seen := make(map[string]bool)
+			for _, name := range fields {
+				if seen[name] {
+					return nil, fmt.Errorf("duplicate column '%s' in header", name)
+				}
+				seen[name] = true
+			}
+			columns, readHeader = fields, false
+
+		case columns == nil:
+			row := make([]Value, len(fields))
+			for i, field := range fields {
+				row[i] = field
+			}
+			rows = append(rows, row)
+
+		default:
+			if len(fields) != len(columns) {
+				line, _ := reader.FieldPos(0)
+				return nil, fmt.Errorf("line %d has %d fields, but there are %d columns (%s)",
+					line, len(fields), len(columns), strings.Join(columns, ", "))
+			}
+			rec := NewRecord()
+			for i, name := range columns {
+				rec.Set(name, fields[i])
+			}
+			rows = append(rows, rec)
+		}
+	}
+}
+
+// csvSyntaxError rewords reader errors without the "parse error" prefix
+func csvSyntaxError(err error) error {
+	var parse *csv.ParseError
+	if errors.As(err, &parse) {
+		return fmt.Errorf("line %d, column %d: %v", parse.Line, parse.Column, parse.Err)
+	}
+	return err
+}
+
+// encodeDelimited writes val as delimited rows. A table gets a header row
+// naming its fields; a list of lists is written one list per row and any
+// other list one value per row.
+func (e *Evaluator) encodeDelimited(out *bytes.Buffer, val Value, opts csvOptions) error {
+	if rec, ok := val.(*Record); ok {
+		val = []Value{rec}
+	}
+	list, ok := val.([]Value)
+	if !ok {
+		return fmt.Errorf("input is not a table or a list of rows")
+	}
+
+	if rows, ok := asTable(list); ok && len(rows) > 0 {
+		columns := tableColumns(rows)
+		if !opts.noHeader {
+			writeDelimitedRow(out, columns, opts)
+		}
+		for _, row := range rows {
+			fields := make([]string, len(columns))
+			for i, col := range columns {
+				val, _ := row.(*Record).Get(col)
+				fields[i] = e.csvField(val)
+			}
+			writeDelimitedRow(out, fields, opts)
+		}
+		return nil
+	}
+
+	for _, elem := range list {
+		cells, ok := elem.([]Value)
+		if !ok {
+			cells = []Value{elem}
+		}
+		fields := make([]string, len(cells))
+		for i, cell := range cells {
+			fields[i] = e.csvField(cell)
+		}
+		writeDelimitedRow(out, fields, opts)
+	}
+	return nil
+}
+
+// csvField renders one field. Times are written in RFC 3339 form so they
+// read back unambiguously.
+func (e *Evaluator) csvField(val Value) string {
+	if t, ok := val.(time.Time); ok {
+		return t.Format(time.RFC3339)
+	}
+	return e.valueToString(val)
+}
+
+// writeDelimitedRow writes one row, quoting the fields that contain the
+// delimiter, a quote or a line break, or every field with --quote-all
+func writeDelimitedRow(out *bytes.Buffer, fields []string, opts csvOptions) {
+	special := string(opts.delimiter) + "\"\r\n"
+	for i, field := range fields {
+		if i > 0 {
+			out.WriteRune(opts.delimiter)
+		}
+		if opts.quoteAll || strings.ContainsAny(field, special) {
+			out.WriteString(`"` + strings.ReplaceAll(field, `"`, `""`) + `"`)
+		} else {
+			out.WriteString(field)
+		}
+	}
+	out.WriteString("\n")
+}
+
+// readDelimited parses the file named in args, or stdin, for from-csv or
+// from-tsv
+func (e *Evaluator) readDelimited(cmd ast.CommandType, flags flagSet, args []string) (Value, error) {
+	spec := builtinSpecs[cmd]
+	if len(args) > 1 {
+		return nil, spec.usageError("too many files")
+	}
+	opts, err := newCSVOptions(cmd, flags)
+	if err != nil {
+		return nil, err
+	}
+
+	var val Value
+	err = e.eachInput(spec.name, args, func(in textInput) error {
+		v, err := decodeDelimited(in.reader, opts)
+		if err != nil {
+			if in.name != "" {
+				return fmt.Errorf("%s: %s: %v", spec.name, in.name, err)
+			}
+			return fmt.Errorf("%s: %v", spec.name, err)
+		}
+		val = v
+		return nil
+	})
+	return val, err
+}
+
+func (e *Evaluator) execFromCSV(cmd ast.CommandType, flags flagSet, args []string) (string, error) {
+	val, err := e.readDelimited(cmd, flags, args)
+	if err != nil {
+		return "", err
+	}
+	return e.printValue(val), nil
+}
+
+// execToCSV reports to-csv or to-tsv used without a value to convert
+func (e *Evaluator) execToCSV(cmd *ast.Command) (string, error) {
+	return "", builtinSpecs[cmd.Type].usageError("pipe a table in, e.g. ls | %s, or call %s(rows)", cmd.Name, cmd.Name)
+}
+
+// pipeToCSV converts the table or list of rows produced by left to
+// delimited text
+func (e *Evaluator) pipeToCSV(left ast.Expression, stage *ast.Command) (string, error) {
+	spec := builtinSpecs[stage.Type]
+	flags, args, err := e.parseCommandArgs(spec, stage.Arguments)
+	if err != nil {
+		return "", err
+	}
+	if len(args) > 0 {
+		return "", spec.usageError("unexpected argument '%s'", args[0])
+	}
+	opts, err := newCSVOptions(stage.Type, flags)
+	if err != nil {
+		return "", err
+	}
+
+	val, err := e.pipedValue(left)
+	if err != nil {
+		return "", err
+	}
+	var out bytes.Buffer
+	if err := e.encodeDelimited(&out, val, opts); err != nil {
+		return "", fmt.Errorf("%s: %v", spec.name, err)
+	}
+	result := out.String()
+	fmt.Fprint(e.stdout, result)
+	return result, nil
+}
+
+// builtinFromCSV implements from-csv(text) and from-tsv(text)
+func (e *Evaluator) builtinFromCSV(name string, args []Value) (Value, error) {
+	opts := csvOptions{delimiter: ',', lazyQuotes: name == "from-tsv"}
+	if name == "from-tsv" {
+		opts.delimiter = '\t'
+	}
+	val, err := decodeDelimited(strings.NewReader(e.valueToString(args[0])), opts)
+	if err != nil {
+		return nil, fmt.Errorf("%s(): %v", name, err)
+	}
+	return val, nil
+}
+
+// builtinToCSV implements to-csv(rows) and to-tsv(rows). Like to-json(),
+// the text has no final newline.
+func (e *Evaluator) builtinToCSV(name string, args []Value) (Value, error) {
+	opts := csvOptions{delimiter: ','}
+	if name == "to-tsv" {
+		opts.delimiter = '\t'
+	}
+	var out bytes.Buffer
+	if err := e.encodeDelimited(&out, args[0], opts); err != nil {
+		return nil, fmt.Errorf("%s(): %v", name, err)
+	}
+	return strings.TrimSuffix(out.String(), "\n"), nil
+}
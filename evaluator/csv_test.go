@@ -0,0 +1,113 @@
+package evaluator
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDelimitedCommands(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"fruit": "name,price,note\napple,3,\"red, round\"\npear,12,\"says \"\"hi\"\"\"\n",
+		"tabs":  "a\tb\n1\t2\n",
+		"semi":  "x;1\ny;2\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"from-csv fruit | where price > 5 | select name note", "name  note\npear  says \"hi\"\n"},
+		{"from-csv fruit | to-csv", files["fruit"]},
+		{"show fruit | from-csv | select name | to-csv -n", "apple\npear\n"},
+		{"from-csv fruit | select name price | to-tsv -q", "\"name\"\t\"price\"\n\"apple\"\t\"3\"\n\"pear\"\t\"12\"\n"},
+		{"from-tsv tabs | to-json", `[{"a":"1","b":"2"}]` + "\n"},
+		{`from-csv -d ";" --columns="key, value" semi | sort-by -r value | to-csv`, "key,value\ny,2\nx,1\n"},
+		{"from-csv -n semi | to-json", `[["x;1"],["y;2"]]` + "\n"},
+		{`from-csv fruit | select name note | to-csv -d "|"`, "name|note\napple|red, round\npear|\"says \"\"hi\"\"\"\n"},
+		{"ls | where name == \"tabs\" | select name size | to-csv", "name,size\ntabs,8\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := runInput(t, dir, tt.input)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("wrong output.\nexpected=%q\ngot=%q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestDelimitedFunctions(t *testing.T) {
+	e, out := newTestEvaluator(t, t.TempDir())
+
+	if err := evalInput(t, e, `rows = from-tsv('id	name
+1	ann
+2	bob')`); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	rows, ok := asTable(e.vars["rows"])
+	if !ok || len(rows) != 2 {
+		t.Fatalf("expected a table of 2 rows, got %v", e.vars["rows"])
+	}
+	if name, _ := rows[1].(*Record).Get("name"); name != "bob" {
+		t.Errorf("wrong field. expected=bob, got=%v", name)
+	}
+
+	if err := evalInput(t, e, `print to-csv(rows)`); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out.String() != "id,name\n1,ann\n2,bob\n" {
+		t.Errorf("wrong output. got=%q", out.String())
+	}
+
+	val, err := decodeDelimited(strings.NewReader("a,b\n"), csvOptions{delimiter: ',', noHeader: true})
+	if err != nil {
+		t.Fatalf("decode returned error: %v", err)
+	}
+	if expected := []Value{[]Value{"a", "b"}}; !reflect.DeepEqual(val, expected) {
+		t.Errorf("wrong rows. expected=%v, got=%v", expected, val)
+	}
+}
+
+func TestDelimitedErrors(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "ragged"), []byte("a,b\n1,2\n3\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "quotes"), []byte("a,b\n1,\"x\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		input   string
+		wantErr string
+	}{
+		{"from-csv ragged", "from-csv: ragged: line 3 has 1 fields, but there are 2 columns (a, b)"},
+		{"from-csv quotes", "from-csv: quotes: line 2, column"},
+		{`from-csv -d "ab" ragged`, "invalid delimiter 'ab'"},
+		{`from-csv -n --columns="a" ragged`, "--columns and --no-header can't be used together"},
+		{"show ragged | to-csv", "to-csv: input is not a table or a list of rows"},
+		{"to-tsv", "to-tsv: pipe a table in"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			_, err := runInput(t, dir, tt.input)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package evaluator
+
+import (
+	"errors"
+	"fmt"
+	"ravenshell/ast"
+)
+
+// ShellError is a runtime error a script can catch. The catch block gets
+// it as a value: printing it shows the message, and err["message"],
+// err["command"] and err["status"] read its fields.
+type ShellError struct {
+	Message string
+	Command string // the command or function that failed; empty for raise
+	Status  int64  // exit status: an external program's own, otherwise 1
+}
+
+func (err *ShellError) Error() string {
+	return err.Message
+}
+
+// record returns the error's fields as a record
+func (err *ShellError) record() *Record {
+	rec := NewRecord()
+	rec.Set("message", err.Message)
+	rec.Set("command", err.Command)
+	rec.Set("status", err.Status)
+	return rec
+}
+
+// asShellError returns err as a ShellError, wrapping errors that don't
+// name a command
+func asShellError(err error) *ShellError {
+	var shellErr *ShellError
+	if errors.As(err, &shellErr) {
+		return shellErr
+	}
+	return &ShellError{Message: err.Error(), Status: 1}
+}
+
+// commandError records that err came from the command or function name.
+// Errors that already name one, like those of a command inside a pipeline,
+// are kept as they are.
+func commandError(name string, err error) error {
+	var shellErr *ShellError
+	if err == nil || errors.As(err, &shellErr) {
+		return err
+	}
+	return &ShellError{Message: err.Error(), Command: name, Status: 1}
+}
+
+// evalTryStatement runs the try block. If it fails, the catch block runs
+// with the error bound to the catch name. The finally block runs either
+// way, and an error from it replaces any earlier one; otherwise an error
+// from the catch block, or an uncaught one, is passed on.
+func (e *Evaluator) evalTryStatement(stmt *ast.TryStatement) error {
+	err := e.evalBlockStatement(stmt.Body)
+	if err != nil && stmt.Catch != nil {
+		caught := asShellError(err)
+		err = nil
+		if stmt.CatchName != nil {
+			err = e.setVar(stmt.CatchName.Value, caught, "")
+		}
+		if err == nil {
+			err = e.evalBlockStatement(stmt.Catch)
+		}
+	}
+
+	if stmt.Finally != nil {
+		if finallyErr := e.evalBlockStatement(stmt.Finally); finallyErr != nil {
+			return finallyErr
+		}
+	}
+	return err
+}
+
+// builtinRaise implements raise(message) and raise(message, status), and
+// raise(err) to raise a caught error again
+func (e *Evaluator) builtinRaise(name string, args []Value) (Value, error) {
+	if caught, ok := args[0].(*ShellError); ok && len(args) == 1 {
+		return nil, caught
+	}
+
+	message, err := e.stringArg(name, args, 0)
+	if err != nil {
+		return nil, err
+	}
+	status := int64(1)
+	if len(args) == 2 {
+		if status, err = e.intArg(name, args, 1); err != nil {
+			return nil, err
+		}
+		if status < 1 || status > 255 {
+			return nil, fmt.Errorf("%s() status must be between 1 and 255", name)
+		}
+	}
+	return nil, &ShellError{Message: message, Status: status}
+}
@@ -0,0 +1,149 @@
+package evaluator
+
+import (
+	"errors"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestTryCatch(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`try {
+    print "before"
+    x = len(5)
+    print "after"
+} catch err {
+    print err["command"] err["status"]
+} finally {
+    print "cleanup"
+}`, "before\nlen 1\ncleanup\n"},
+		{`try {
+    raise("disk full", 3)
+} catch err {
+    print type(err) err
+    print to-json(err)
+}`, "error disk full\n{\"message\":\"disk full\",\"command\":\"\",\"status\":3}\n"},
+		{`try {
+    rmdir nowhere
+} catch {
+    print "failed"
+}`, "failed\n"},
+		{`try {
+    print "ok"
+} finally {
+    print "cleanup"
+}`, "ok\ncleanup\n"},
+		{`try {
+    try {
+        raise("inner")
+    } finally {
+        print "inner cleanup"
+    }
+} catch err {
+    print "caught" err
+}`, "inner cleanup\ncaught inner\n"},
+		{`try {
+    try {
+        raise("first")
+    } catch err {
+        raise(err)
+    }
+} catch again {
+    print again again["status"]
+}`, "first 1\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := runInput(t, t.TempDir(), tt.input)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("wrong output.\nexpected=%q\ngot=%q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestUncaughtErrors(t *testing.T) {
+	tests := []struct {
+		input   string
+		wantErr string
+		status  int64
+	}{
+		{`raise("stop", 4)`, "stop", 4},
+		{`try { raise("a") } catch { raise("b") }`, "b", 1},
+		{`try { print "x" } finally { raise("in finally") }`, "in finally", 1},
+		{`try { raise("a") } finally { print "x" }`, "a", 1},
+		{`raise("x", 300)`, "raise() status must be between 1 and 255", 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			_, err := runInput(t, t.TempDir(), tt.input)
+			var shellErr *ShellError
+			if !errors.As(err, &shellErr) {
+				t.Fatalf("expected a ShellError, got %v", err)
+			}
+			if shellErr.Message != tt.wantErr || shellErr.Status != tt.status {
+				t.Errorf("expected %q with status %d, got %q with status %d",
+					tt.wantErr, tt.status, shellErr.Message, shellErr.Status)
+			}
+		})
+	}
+}
+
+func TestExternalCommands(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not found")
+	}
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`sh -c "echo hi"`, "hi\n"},
+		{`greeting = sh "-c" "echo hello"
+print greeting "world"`, "hello world\n"},
+		{`sh -c "exit 3"
+print status`, "3\n"},
+		{`print "a b" | sh -c "wc -w"`, "2\n"},
+		{`set errexit
+try {
+    sh -c "exit 2"
+} catch err {
+    print err err["command"] err["status"]
+}`, "sh: exit status 2 sh 2\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := runInput(t, t.TempDir(), tt.input)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if strings.TrimLeft(got, " ") != tt.expected {
+				t.Errorf("wrong output.\nexpected=%q\ngot=%q", tt.expected, got)
+			}
+		})
+	}
+
+	_, err := runInput(t, t.TempDir(), "nosuchprogramhere")
+	if err == nil || asShellError(err).Status != 127 {
+		t.Errorf("expected status 127 for a missing program, got %v", err)
+	}
+
+	// A variable followed by an option word is a subtraction, not a program
+	if _, err := runInput(t, t.TempDir(), "y = 3\nz = 1\ny -z\nprint \"a\" | y -z"); err != nil {
+		t.Errorf("expected y -z to subtract, got %v", err)
+	}
+	_, err = runInput(t, t.TempDir(), "nosuchprogramhere -z")
+	if err == nil || asShellError(err).Status != 127 {
+		t.Errorf("expected status 127 for a missing program with an option, got %v", err)
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"os"
 	"path/filepath"
 	"ravenshell/ast"
+	"regexp"
 	"strconv"
 	"strings"
 )
@@ -16,22 +17,37 @@ type Value interface{}
 
 // Evaluator executes AST nodes
 type Evaluator struct {
-	cwd    string            // Current working directory
-	env    map[string]string // Environment variables (for $VAR)
-	vars   map[string]Value  // Script variables
-	stdout io.Writer         // Standard output (for redirections)
-	stdin  io.Reader         // Standard input (for redirections)
+	cwd     string            // Current working directory
+	env     map[string]string // Environment variables (for $VAR)
+	vars    map[string]Value  // Script variables
+	options map[string]string // Shell options changed with set
+	stdout  io.Writer         // Standard output (for redirections)
+	stdin   io.Reader         // Standard input (for redirections)
+	stderr  io.Writer         // Prompts and diagnostics, never redirected
+	ttyIn   io.Reader         // Answers to confirmation prompts
+	journal []fileOp          // File operations that undo can reverse
+	keys    KeyBinder         // The REPL's line editor, for bind
+	discard bool              // The next command runs for its output alone
+
+	patterns   map[string]*regexp.Regexp // Compiled regular expressions, by pattern
+	arrayTypes map[string]string         // Element types of typed array variables
 }
 
 // New creates a new Evaluator
 func New() *Evaluator {
 	cwd, _ := os.Getwd()
 	return &Evaluator{
-		cwd:    cwd,
-		env:    make(map[string]string),
-		vars:   make(map[string]Value),
-		stdout: os.Stdout,
-		stdin:  os.Stdin,
+		cwd:     cwd,
+		env:     make(map[string]string),
+		vars:    make(map[string]Value),
+		options: make(map[string]string),
+		stdout:  os.Stdout,
+		stdin:   os.Stdin,
+		stderr:  os.Stderr,
+		ttyIn:   os.Stdin,
+
+		patterns:   make(map[string]*regexp.Regexp),
+		arrayTypes: make(map[string]string),
 	}
 }
 
@@ -48,7 +64,7 @@ func (e *Evaluator) Eval(program *ast.Program) error {
 func (e *Evaluator) evalStatement(stmt ast.Statement) error {
 	switch s := stmt.(type) {
 	case *ast.ExpressionStatement:
-		_, err := e.evalExpressionValue(s.Expression)
+		_, err := e.evalDiscarding(e.commandOrVariable(s.Expression))
 		return err
 	case *ast.AssignmentStatement:
 		return e.evalAssignment(s)
@@ -56,6 +72,10 @@ func (e *Evaluator) evalStatement(stmt ast.Statement) error {
 		return e.evalForStatement(s)
 	case *ast.IfStatement:
 		return e.evalIfStatement(s)
+	case *ast.MatchStatement:
+		return e.evalMatchStatement(s)
+	case *ast.TryStatement:
+		return e.evalTryStatement(s)
 	}
 	return nil
 }
@@ -65,7 +85,7 @@ func (e *Evaluator) evalExpressionValue(expr ast.Expression) (Value, error) {
 	switch node := expr.(type) {
 	case *ast.Command:
 		result, err := e.evalCommand(node)
-		return result, err
+		return result, commandError(node.Name, err)
 	case *ast.PipeExpression:
 		result, err := e.evalPipe(node)
 		return result, err
@@ -82,10 +102,14 @@ func (e *Evaluator) evalExpressionValue(expr ast.Expression) (Value, error) {
 		return e.resolvePath(node.Value), nil
 	case *ast.StringLiteral:
 		return node.Value, nil
+	case *ast.FlagLiteral:
+		return node.Value, nil
 	case *ast.IntegerLiteral:
 		return node.Value, nil
 	case *ast.VariableReference:
 		return e.expandVariable(node.Name.Value), nil
+	case *ast.PrefixExpression:
+		return e.evalPrefixExpression(node)
 	case *ast.InfixExpression:
 		return e.evalInfixExpression(node)
 	case *ast.CallExpression:
@@ -94,6 +118,8 @@ func (e *Evaluator) evalExpressionValue(expr ast.Expression) (Value, error) {
 		return e.evalArrayLiteral(node)
 	case *ast.IndexExpression:
 		return e.evalIndexExpression(node)
+	case *ast.SliceExpression:
+		return e.evalSliceExpression(node)
 	}
 	return nil, fmt.Errorf("unknown expression type: %T", expr)
 }
@@ -121,7 +147,15 @@ func (e *Evaluator) valueToString(val Value) string {
 			return "true"
 		}
 		return "false"
+	case *Record:
+		return e.formatRecord(v)
+	case *ShellError:
+		return v.Message
 	case []Value:
+		// A non-empty array of records is a table
+		if rows, ok := asTable(v); ok && len(rows) > 0 {
+			return strings.TrimSuffix(e.renderTable(rows), "\n")
+		}
 		strs := make([]string, len(v))
 		for i, elem := range v {
 			strs[i] = e.valueToString(elem)
@@ -167,20 +201,43 @@ func (e *Evaluator) valueToBool(val Value) bool {
 }
 
 func (e *Evaluator) evalCommand(cmd *ast.Command) (string, error) {
-	// Evaluate arguments
-	args := make([]string, len(cmd.Arguments))
-	for i, arg := range cmd.Arguments {
-		val, err := e.evalExpression(arg)
-		if err != nil {
-			return "", err
-		}
-		args[i] = val
+	// Commands in the arguments are used for their values
+	discard := e.discard
+	e.discard = false
+
+	if cmd.Type == ast.CMD_EXTERNAL {
+		return e.execExternal(cmd)
+	}
+
+	spec, ok := builtinSpecs[cmd.Type]
+	if !ok {
+		return "", fmt.Errorf("unknown command: %s", cmd.Name)
+	}
+
+	if wantsHelp(cmd.Arguments) {
+		help := spec.helpText()
+		fmt.Fprint(e.stdout, help)
+		return help, nil
 	}
 
+	if isTableStage(cmd.Type) {
+		return e.execTableStage(cmd)
+	}
+
+	// Evaluate arguments, separating options from operands
+	flags, args, err := e.parseCommandArgs(spec, cmd.Arguments)
+	if err != nil {
+		return "", err
+	}
+
+	// The text filters check this in newFilterOutput
+	e.discard = discard
+	defer func() { e.discard = false }()
+
 	// Execute command based on type
 	switch cmd.Type {
 	case ast.CMD_LIST:
-		return e.execList(args)
+		return e.execList(flags, args)
 	case ast.CMD_CHANGEDIR:
 		return e.execChangeDir(args)
 	case ast.CMD_CURRENTDIR:
@@ -190,7 +247,7 @@ func (e *Evaluator) evalCommand(cmd *ast.Command) (string, error) {
 	case ast.CMD_REMOVEDIR:
 		return e.execRemoveDir(args)
 	case ast.CMD_REMOVE:
-		return e.execRemove(args)
+		return e.execRemove(flags, args)
 	case ast.CMD_MAKEFILE:
 		return e.execMakeFile(args)
 	case ast.CMD_WHOAMI:
@@ -205,34 +262,125 @@ func (e *Evaluator) evalCommand(cmd *ast.Command) (string, error) {
 		return e.execClear()
 	case ast.CMD_TILDE:
 		return e.execHome()
+	case ast.CMD_SET:
+		return e.execSet(flags, args)
+	case ast.CMD_BIND:
+		return e.execBind(flags, args)
+	case ast.CMD_TRASH:
+		return e.execTrash(args)
+	case ast.CMD_UNDO:
+		return e.execUndo()
+	case ast.CMD_COPY:
+		return e.execCopy(flags, args)
+	case ast.CMD_MOVE:
+		return e.execMove(flags, args)
+	case ast.CMD_LINK:
+		return e.execLink(flags, args)
+	case ast.CMD_TOUCH:
+		return e.execTouch(flags, args)
+	case ast.CMD_GREP:
+		return e.execGrep(flags, args)
+	case ast.CMD_HEAD:
+		return e.execHead(flags, args)
+	case ast.CMD_TAIL:
+		return e.execTail(flags, args)
+	case ast.CMD_WC:
+		return e.execWordCount(flags, args)
+	case ast.CMD_SORT:
+		return e.execSort(flags, args)
+	case ast.CMD_UNIQ:
+		return e.execUniq(flags, args)
+	case ast.CMD_FIND:
+		return e.execFind(flags, args)
+	case ast.CMD_FROMJSON:
+		return e.execFromJSON(flags, args)
+	case ast.CMD_TOJSON:
+		return e.execToJSON()
+	case ast.CMD_FROMCSV, ast.CMD_FROMTSV:
+		return e.execFromCSV(cmd.Type, flags, args)
+	case ast.CMD_TOCSV, ast.CMD_TOTSV:
+		return e.execToCSV(cmd)
 	default:
 		return "", fmt.Errorf("unknown command: %s", cmd.Name)
 	}
 }
 
 func (e *Evaluator) evalPipe(pipe *ast.PipeExpression) (string, error) {
-	// Capture output from left command
-	var leftOutput bytes.Buffer
-	oldStdout := e.stdout
-	e.stdout = &leftOutput
+	discard := e.discard
+	e.discard = false
+
+	// to-json, to-csv and to-tsv turn the value on their left into text
+	if stage, ok := pipe.Right.(*ast.Command); ok && !wantsHelp(stage.Arguments) {
+		switch stage.Type {
+		case ast.CMD_TOJSON:
+			return e.pipeToJSON(pipe.Left, stage)
+		case ast.CMD_TOCSV, ast.CMD_TOTSV:
+			return e.pipeToCSV(pipe.Left, stage)
+		}
+	}
 
-	_, err := e.evalExpression(pipe.Left)
-	e.stdout = oldStdout
+	// Pipelines ending in a table stage or a from- command pass values, not text
+	if val, ok, err := e.evalStructured(pipe); ok {
+		if err != nil {
+			return "", err
+		}
+		return e.printValue(val), nil
+	}
+
+	// Capture output from left command
+	leftOutput, err := e.captureOutput(e.commandOrVariable(pipe.Left))
 	if err != nil {
 		return "", err
 	}
 
 	// Use left output as input for right command
 	oldStdin := e.stdin
-	e.stdin = &leftOutput
+	e.stdin = leftOutput
 
-	result, err := e.evalExpression(pipe.Right)
+	right := e.commandOrVariable(pipe.Right)
+	result, err := e.evalCommandOutput(right, discard)
 	e.stdin = oldStdin
 
 	return result, err
 }
 
+// captureOutput evaluates expr and returns what it wrote to stdout
+func (e *Evaluator) captureOutput(expr ast.Expression) (*bytes.Buffer, error) {
+	var output bytes.Buffer
+	oldStdout := e.stdout
+	e.stdout = &output
+
+	_, err := e.evalDiscarding(expr)
+	e.stdout = oldStdout
+	return &output, err
+}
+
+// evalCommandOutput evaluates expr, with evalDiscarding when its value is
+// not used
+func (e *Evaluator) evalCommandOutput(expr ast.Expression, discard bool) (string, error) {
+	if discard {
+		return e.evalDiscarding(expr)
+	}
+	return e.evalExpression(expr)
+}
+
+// evalDiscarding evaluates expr for what it writes alone. A command, or
+// the last command of a pipeline or redirection, is told its value is not
+// used, so a text filter need not keep a copy of everything it prints.
+func (e *Evaluator) evalDiscarding(expr ast.Expression) (string, error) {
+	switch expr.(type) {
+	case *ast.Command, *ast.PipeExpression, *ast.RedirectionExpression:
+		e.discard = true
+	}
+	result, err := e.evalExpression(expr)
+	e.discard = false
+	return result, err
+}
+
 func (e *Evaluator) evalRedirection(redir *ast.RedirectionExpression) (string, error) {
+	discard := e.discard
+	e.discard = false
+
 	// Get target filename
 	target, err := e.evalExpression(redir.Target)
 	if err != nil {
@@ -241,10 +389,25 @@ func (e *Evaluator) evalRedirection(redir *ast.RedirectionExpression) (string, e
 
 	// Resolve path
 	targetPath := e.resolvePath(target)
+	command := e.commandOrVariable(redir.Command)
 
 	switch redir.Type {
 	case ast.REDIR_OUTPUT:
-		// Overwrite file
+		// Overwrite file. Its old contents go to the trash, and undo
+		// brings them back; a device such as /dev/null is written as is.
+		op := fileOp{command: "> " + target}
+		defer func() { e.record(op) }()
+		info, err := os.Lstat(targetPath)
+		switch {
+		case err == nil && info.Mode().IsRegular() && info.Size() > 0:
+			if _, err := clearDestination(targetPath, false, &op); err != nil {
+				return "", fmt.Errorf("cannot replace file %s: %v", target, unwrapPathError(err))
+			}
+			op.created = append(op.created, targetPath)
+		case os.IsNotExist(err):
+			op.created = append(op.created, targetPath)
+		}
+
 		file, err := os.Create(targetPath)
 		if err != nil {
 			return "", fmt.Errorf("cannot create file %s: %v", target, err)
@@ -253,7 +416,7 @@ func (e *Evaluator) evalRedirection(redir *ast.RedirectionExpression) (string, e
 
 		oldStdout := e.stdout
 		e.stdout = file
-		result, err := e.evalExpression(redir.Command)
+		result, err := e.evalCommandOutput(command, discard)
 		e.stdout = oldStdout
 		return result, err
 
@@ -267,7 +430,7 @@ func (e *Evaluator) evalRedirection(redir *ast.RedirectionExpression) (string, e
 
 		oldStdout := e.stdout
 		e.stdout = file
-		result, err := e.evalExpression(redir.Command)
+		result, err := e.evalCommandOutput(command, discard)
 		e.stdout = oldStdout
 		return result, err
 
@@ -281,7 +444,7 @@ func (e *Evaluator) evalRedirection(redir *ast.RedirectionExpression) (string, e
 
 		oldStdin := e.stdin
 		e.stdin = file
-		result, err := e.evalExpression(redir.Command)
+		result, err := e.evalCommandOutput(command, discard)
 		e.stdin = oldStdin
 		return result, err
 
@@ -295,31 +458,6 @@ func (e *Evaluator) evalRedirection(redir *ast.RedirectionExpression) (string, e
 
 // Command implementations
 
-func (e *Evaluator) execList(args []string) (string, error) {
-	dir := e.cwd
-	if len(args) > 0 {
-		dir = e.resolvePath(args[0])
-	}
-
-	entries, err := os.ReadDir(dir)
-	if err != nil {
-		return "", fmt.Errorf("ls: %v", err)
-	}
-
-	var output bytes.Buffer
-	for _, entry := range entries {
-		name := entry.Name()
-		if entry.IsDir() {
-			name += "/"
-		}
-		output.WriteString(name + "\n")
-	}
-
-	result := output.String()
-	fmt.Fprint(e.stdout, result)
-	return result, nil
-}
-
 func (e *Evaluator) execChangeDir(args []string) (string, error) {
 	if len(args) == 0 {
 		// Change to home directory
@@ -354,11 +492,18 @@ func (e *Evaluator) execMakeDir(args []string) (string, error) {
 		return "", fmt.Errorf("mkdir: missing operand")
 	}
 
+	op := fileOp{command: "mkdir"}
+	defer func() { e.record(op) }()
+
 	for _, arg := range args {
 		path := e.resolvePath(arg)
+		created := topmostMissing(path)
 		if err := os.MkdirAll(path, 0755); err != nil {
 			return "", fmt.Errorf("mkdir: %v", err)
 		}
+		if created != "" {
+			op.created = append(op.created, created)
+		}
 	}
 	return "", nil
 }
@@ -368,25 +513,23 @@ func (e *Evaluator) execRemoveDir(args []string) (string, error) {
 		return "", fmt.Errorf("rmdir: missing operand")
 	}
 
+	op := fileOp{command: "rmdir"}
+	defer func() { e.record(op) }()
+
 	for _, arg := range args {
 		path := e.resolvePath(arg)
-		if err := os.Remove(path); err != nil {
+		entries, err := os.ReadDir(path)
+		if err != nil {
 			return "", fmt.Errorf("rmdir: %v", err)
 		}
-	}
-	return "", nil
-}
-
-func (e *Evaluator) execRemove(args []string) (string, error) {
-	if len(args) == 0 {
-		return "", fmt.Errorf("rm: missing operand")
-	}
-
-	for _, arg := range args {
-		path := e.resolvePath(arg)
-		if err := os.RemoveAll(path); err != nil {
-			return "", fmt.Errorf("rm: %v", err)
+		if len(entries) > 0 {
+			return "", fmt.Errorf("rmdir: failed to remove '%s': directory not empty", arg)
 		}
+		item, err := moveToTrash(path)
+		if err != nil {
+			return "", fmt.Errorf("rmdir: %v", err)
+		}
+		op.trashed = append(op.trashed, item)
 	}
 	return "", nil
 }
@@ -396,8 +539,26 @@ func (e *Evaluator) execMakeFile(args []string) (string, error) {
 		return "", fmt.Errorf("mkfile: missing operand")
 	}
 
+	op := fileOp{command: "mkfile"}
+	defer func() { e.record(op) }()
+
 	for _, arg := range args {
 		path := e.resolvePath(arg)
+
+		// mkfile empties existing files; keep the old contents in the trash
+		info, err := os.Stat(path)
+		switch {
+		case err == nil && info.Mode().IsRegular() && info.Size() > 0:
+			item, err := moveToTrash(path)
+			if err != nil {
+				return "", fmt.Errorf("mkfile: %v", err)
+			}
+			op.trashed = append(op.trashed, item)
+			op.created = append(op.created, path)
+		case os.IsNotExist(err):
+			op.created = append(op.created, path)
+		}
+
 		file, err := os.Create(path)
 		if err != nil {
 			return "", fmt.Errorf("mkfile: %v", err)
@@ -408,15 +569,11 @@ func (e *Evaluator) execMakeFile(args []string) (string, error) {
 }
 
 func (e *Evaluator) execWhoami() (string, error) {
-	username := os.Getenv("USER")
-	if username == "" {
-		username = os.Getenv("USERNAME") // Windows
-	}
+	username := userName()
 	fmt.Fprintln(e.stdout, username)
 	return username, nil
 }
 
-
 func (e *Evaluator) execHome() (string, error) {
 	home, err := os.UserHomeDir()
 	if err != nil {
@@ -506,6 +663,41 @@ func (e *Evaluator) resolvePath(path string) string {
 	return filepath.Clean(filepath.Join(e.cwd, path))
 }
 
+// confirm asks a yes/no question on stderr and reads the answer from the
+// terminal, so prompts still work while stdout or stdin are redirected
+func (e *Evaluator) confirm(question string) bool {
+	fmt.Fprint(e.stderr, question+" [y/N] ")
+
+	// Read one byte at a time so no input past the answer is consumed
+	var answer []byte
+	buf := make([]byte, 1)
+	for {
+		n, err := e.ttyIn.Read(buf)
+		if n == 0 || err != nil || buf[0] == '\n' {
+			break
+		}
+		answer = append(answer, buf[0])
+	}
+
+	reply := strings.ToLower(strings.TrimSpace(string(answer)))
+	return reply == "y" || reply == "yes"
+}
+
+// topmostMissing returns the outermost directory that creating path would
+// add, or "" if path already exists
+func topmostMissing(path string) string {
+	missing := ""
+	for p := path; ; p = filepath.Dir(p) {
+		if _, err := os.Lstat(p); err == nil {
+			return missing
+		}
+		missing = p
+		if filepath.Dir(p) == p {
+			return missing
+		}
+	}
+}
+
 func (e *Evaluator) expandVariable(name string) string {
 	// First check local env
 	if val, ok := e.env[name]; ok {
@@ -525,19 +717,56 @@ func (e *Evaluator) SetEnv(name, value string) {
 	e.env[name] = value
 }
 
-// evalAssignment handles variable assignment: x = value
+// evalAssignment handles assignment: x = value or arr[i] = value
 func (e *Evaluator) evalAssignment(stmt *ast.AssignmentStatement) error {
-	val, err := e.evalExpressionValue(stmt.Value)
+	val, err := e.evalCommandValue(stmt.Value)
 	if err != nil {
 		return err
 	}
-	e.vars[stmt.Name.Value] = val
-	return nil
+	if id, ok := stmt.Target.(*ast.Identifier); ok {
+		return e.setVar(id.Value, val, e.arrayType(stmt.Value))
+	}
+	return e.assign(stmt.Target, val)
+}
+
+// evalCommandValue evaluates an expression whose result is kept, as in
+// x = find src, for f in find src { ... } or len(find src), and for the
+// operands of other expressions. Commands that produce a list,
+// like find, return it as an array instead of printing it; from-json and
+// pipelines ending in a table stage return their value. Everything else is
+// evaluated as usual.
+func (e *Evaluator) evalCommandValue(expr ast.Expression) (Value, error) {
+	switch node := expr.(type) {
+	case *ast.PipeExpression:
+		if val, ok, err := e.evalStructured(node); ok {
+			return val, err
+		}
+	case *ast.Command:
+		if isDecoder(node.Type) {
+			if val, ok, err := e.evalStructured(node); ok {
+				return val, err
+			}
+		}
+		// A program's output becomes the value instead of being printed:
+		// branch = git branch --show-current
+		if node.Type == ast.CMD_EXTERNAL {
+			out, err := e.captureOutput(node)
+			return strings.TrimSuffix(out.String(), "\n"), err
+		}
+		if node.Type == ast.CMD_FIND && !wantsHelp(node.Arguments) {
+			flags, args, err := e.parseCommandArgs(builtinSpecs[node.Type], node.Arguments)
+			if err != nil {
+				return nil, err
+			}
+			return e.findValues(flags, args)
+		}
+	}
+	return e.evalExpressionValue(expr)
 }
 
 // evalForStatement handles for loops: for i in range(n) { ... }
 func (e *Evaluator) evalForStatement(stmt *ast.ForStatement) error {
-	iterable, err := e.evalExpressionValue(stmt.Iterable)
+	iterable, err := e.evalCommandValue(stmt.Iterable)
 	if err != nil {
 		return err
 	}
@@ -593,18 +822,50 @@ func (e *Evaluator) evalBlockStatement(block *ast.BlockStatement) error {
 	return nil
 }
 
+// evalPrefixExpression handles -x, +x and !x. - and + take a number, or
+// text holding one; ! negates any value's truth.
+func (e *Evaluator) evalPrefixExpression(node *ast.PrefixExpression) (Value, error) {
+	right, err := e.evalCommandValue(node.Right)
+	if err != nil {
+		return nil, err
+	}
+
+	if node.Operator == "!" {
+		return !e.valueToBool(right), nil
+	}
+
+	if f, ok := right.(float64); ok {
+		if node.Operator == "-" {
+			return -f, nil
+		}
+		return f, nil
+	}
+	n, err := e.valueToInt64(right)
+	if err != nil {
+		return nil, fmt.Errorf("unary %s needs a number, got %s", node.Operator, typeName(right))
+	}
+	if node.Operator == "-" {
+		return -n, nil
+	}
+	return n, nil
+}
+
 // evalInfixExpression handles binary operations: left op right
 func (e *Evaluator) evalInfixExpression(node *ast.InfixExpression) (Value, error) {
-	left, err := e.evalExpressionValue(node.Left)
+	left, err := e.evalCommandValue(node.Left)
 	if err != nil {
 		return nil, err
 	}
 
-	right, err := e.evalExpressionValue(node.Right)
+	right, err := e.evalCommandValue(node.Right)
 	if err != nil {
 		return nil, err
 	}
 
+	if node.Operator == "=~" {
+		return e.evalMatch(left, right)
+	}
+
 	// String concatenation
 	if node.Operator == "+" {
 		// Check if either is a string
@@ -668,90 +929,65 @@ func (e *Evaluator) evalInfixExpression(node *ast.InfixExpression) (Value, error
 	return nil, fmt.Errorf("unknown operator: %s", node.Operator)
 }
 
-// evalCallExpression handles function calls: range(n), append(arr, val)
+// evalCallExpression handles function calls: range(n), len(s), ...
+// The function is looked up in the builtins registry, and its arguments are
+// evaluated and counted before it runs.
 func (e *Evaluator) evalCallExpression(node *ast.CallExpression) (Value, error) {
-	switch node.Function {
-	case "range":
-		return e.builtinRange(node.Arguments)
-	case "append":
-		return e.builtinAppend(node.Arguments)
-	default:
+	fn, ok := builtins[node.Function]
+	if !ok {
 		return nil, fmt.Errorf("unknown function: %s", node.Function)
 	}
-}
-
-// builtinRange implements range(n) - returns [0, 1, 2, ..., n-1]
-func (e *Evaluator) builtinRange(args []ast.Expression) (Value, error) {
-	if len(args) != 1 {
-		return nil, fmt.Errorf("range() takes exactly 1 argument")
-	}
-
-	val, err := e.evalExpressionValue(args[0])
-	if err != nil {
+	if err := fn.checkArity(node.Function, len(node.Arguments)); err != nil {
 		return nil, err
 	}
 
-	n, err := e.valueToInt64(val)
-	if err != nil {
-		return nil, fmt.Errorf("range() argument must be an integer")
-	}
-
-	result := make([]Value, n)
-	for i := int64(0); i < n; i++ {
-		result[i] = i
-	}
-	return result, nil
-}
-
-// builtinAppend implements append(arr, val) - returns new array with val appended
-func (e *Evaluator) builtinAppend(args []ast.Expression) (Value, error) {
-	if len(args) != 2 {
-		return nil, fmt.Errorf("append() takes exactly 2 arguments")
-	}
-
-	arrVal, err := e.evalExpressionValue(args[0])
-	if err != nil {
-		return nil, err
-	}
-
-	arr, ok := arrVal.([]Value)
-	if !ok {
-		return nil, fmt.Errorf("append() first argument must be an array")
+	args := make([]Value, len(node.Arguments))
+	for i, arg := range node.Arguments {
+		val, err := e.evalCommandValue(arg)
+		if err != nil {
+			return nil, err
+		}
+		args[i] = val
 	}
-
-	val, err := e.evalExpressionValue(args[1])
-	if err != nil {
-		return nil, err
+	if node.Function == "type" {
+		if t := e.arrayType(node.Arguments[0]); t != "" {
+			return "[]" + t, nil
+		}
 	}
-
-	// Create a new array with the value appended
-	result := make([]Value, len(arr)+1)
-	copy(result, arr)
-	result[len(arr)] = val
-	return result, nil
+	result, err := fn.fn(e, node.Function, args)
+	return result, commandError(node.Function, err)
 }
 
-// evalArrayLiteral handles array literals: [1, 2, 3] or []string
+// evalArrayLiteral handles array literals: [1, 2, 3], []string or
+// []int[1, 2, 3]. The elements of a typed literal are converted to its type.
 func (e *Evaluator) evalArrayLiteral(node *ast.ArrayLiteral) (Value, error) {
-	// Empty array with type hint
 	if node.TypeHint != "" {
-		return []Value{}, nil
+		if err := checkElementType(node.TypeHint); err != nil {
+			return nil, err
+		}
 	}
 
 	elements := make([]Value, len(node.Elements))
 	for i, elem := range node.Elements {
-		val, err := e.evalExpressionValue(elem)
+		val, err := e.evalCommandValue(elem)
 		if err != nil {
 			return nil, err
 		}
 		elements[i] = val
 	}
+	if node.TypeHint != "" {
+		converted, err := e.convertElements(elements, node.TypeHint)
+		if err != nil {
+			return nil, fmt.Errorf("[]%s literal: %v", node.TypeHint, err)
+		}
+		return converted, nil
+	}
 	return elements, nil
 }
 
 // evalIndexExpression handles array indexing: arr[0]
 func (e *Evaluator) evalIndexExpression(node *ast.IndexExpression) (Value, error) {
-	left, err := e.evalExpressionValue(node.Left)
+	left, err := e.evalCommandValue(node.Left)
 	if err != nil {
 		return nil, err
 	}
@@ -761,9 +997,17 @@ func (e *Evaluator) evalIndexExpression(node *ast.IndexExpression) (Value, error
 		return nil, err
 	}
 
-	arr, ok := left.([]Value)
-	if !ok {
-		return nil, fmt.Errorf("index operator not supported on %T", left)
+	// Records are indexed by field name: row["size"], and so are errors
+	if shellErr, ok := left.(*ShellError); ok {
+		left = shellErr.record()
+	}
+	if rec, ok := left.(*Record); ok {
+		key := e.valueToString(index)
+		val, ok := rec.Get(key)
+		if !ok {
+			return nil, fmt.Errorf("record has no field '%s'", key)
+		}
+		return val, nil
 	}
 
 	idx, err := e.valueToInt64(index)
@@ -771,9 +1015,21 @@ func (e *Evaluator) evalIndexExpression(node *ast.IndexExpression) (Value, error
 		return nil, fmt.Errorf("array index must be an integer")
 	}
 
-	if idx < 0 || idx >= int64(len(arr)) {
-		return nil, fmt.Errorf("array index out of bounds: %d", idx)
+	// Strings are indexed by character; a negative index counts from the end
+	switch v := left.(type) {
+	case []Value:
+		i, ok := normalizeIndex(idx, len(v))
+		if !ok {
+			return nil, fmt.Errorf("array index out of bounds: %d", idx)
+		}
+		return v[i], nil
+	case string:
+		runes := []rune(v)
+		i, ok := normalizeIndex(idx, len(runes))
+		if !ok {
+			return nil, fmt.Errorf("string index out of bounds: %d", idx)
+		}
+		return string(runes[i]), nil
 	}
-
-	return arr[idx], nil
+	return nil, fmt.Errorf("index operator not supported on %s", typeName(left))
 }
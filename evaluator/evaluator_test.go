@@ -0,0 +1,167 @@
+package evaluator
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"ravenshell/lexer"
+	"ravenshell/parser"
+	"strings"
+	"testing"
+)
+
+// runInput parses and evaluates input with dir as the working directory,
+// returning everything written to stdout
+func runInput(t *testing.T, dir, input string) (string, error) {
+	t.Helper()
+	e, out := newTestEvaluator(t, dir)
+	err := evalInput(t, e, input)
+	return out.String(), err
+}
+
+// newTestEvaluator returns an evaluator working in dir whose stdout is
+// captured, whose prompts are discarded and whose trash is private to the test
+func newTestEvaluator(t *testing.T, dir string) (*Evaluator, *bytes.Buffer) {
+	t.Helper()
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+
+	var out bytes.Buffer
+	e := New()
+	e.cwd = dir
+	e.stdout = &out
+	e.stderr = io.Discard
+	e.ttyIn = strings.NewReader("")
+	return e, &out
+}
+
+// evalInput parses and evaluates input with an existing evaluator
+func evalInput(t *testing.T, e *Evaluator, input string) error {
+	t.Helper()
+	l := lexer.NewLexer(input)
+	p := parser.New(l)
+	program := p.ParseProgram()
+	if len(p.Errors()) > 0 {
+		t.Fatalf("parser errors for %q: %v", input, p.Errors())
+	}
+	return e.Eval(program)
+}
+
+func TestCommandOptions(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		wantErr string
+	}{
+		{"unknown short option", "ls -z", "ls: invalid option -- 'z'\nusage: ls [options] [path...]"},
+		{"unknown long option", "ls --color", "ls: unrecognized option '--color'\nusage: ls [options] [path...]"},
+		{"value on boolean option", "ls --all=yes", "ls: option '--all' doesn't allow an argument\nusage: ls [options] [path...]"},
+		{"option on command without options", "cwd -x", "cwd: invalid option -- 'x'\nusage: cwd"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := runInput(t, t.TempDir(), tt.input)
+			if err == nil {
+				t.Fatalf("expected error for %q", tt.input)
+			}
+			if err.Error() != tt.wantErr {
+				t.Errorf("wrong error. expected=%q, got=%q", tt.wantErr, err.Error())
+			}
+		})
+	}
+}
+
+func TestCommandHelp(t *testing.T) {
+	out, err := runInput(t, t.TempDir(), "ls --help")
+	if err != nil {
+		t.Fatalf("ls --help returned error: %v", err)
+	}
+
+	expected := builtinSpecs["ls"].helpText()
+	if out != expected {
+		t.Errorf("wrong help output. expected=%q, got=%q", expected, out)
+	}
+	if !bytes.Contains([]byte(out), []byte("-a, --all")) {
+		t.Errorf("help output missing option table. got=%q", out)
+	}
+}
+
+func TestDashValuesAreOperands(t *testing.T) {
+	out, err := runInput(t, t.TempDir(), `print "-l" -- -x`)
+	if err != nil {
+		t.Fatalf("print returned error: %v", err)
+	}
+	if out != "-l -x\n" {
+		t.Errorf("wrong output. expected=%q, got=%q", "-l -x\n", out)
+	}
+}
+
+func TestCommandNamesAsVariables(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "notes"), []byte("sort me\nleave me\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"head = 3\nprint head + 1", "4\n"},
+		{"find = \"x\"\nprint find", "x\n"},
+		{"where = 2\nn = where * 2\nprint n", "4\n"},
+		{"tail = [1, 2]\nprint tail[1]", "2\n"},
+		{"head = 3\nn = head\nprint n", "3\n"},
+		{"for sort in [\"a\", \"b\"] { print sort }", "a\nb\n"},
+		{"grep sort notes", "sort me\n"},
+		{"head = 3\nhead -n 1 notes", "sort me\n"},
+		{"x = head -n 1 notes", "sort me\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := runInput(t, dir, tt.input)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("wrong output. expected=%q, got=%q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestPrefixOperators(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"x = -5\nprint x", "-5\n"},
+		{"print 0 - -1", "1\n"},
+		{"x = 3\ny = -x-1\nprint y", "-4\n"},
+		{"y = 3\nz = 1\nw = y -z\nprint w", "2\n"},
+		{"xs = [4, 5]\ny = -xs[1] * 2\nprint y", "-10\n"},
+		{"s = \"abc\"\nn = -len(s)\nprint n", "-3\n"},
+		{"print +\"7\" + 1", "8\n"},
+		{"x = 2\nprint abs(-x)", "2\n"},
+		{"empty = \"\"\nprint !empty", "true\n"},
+		{"print !(1 < 2)", "false\n"},
+		{"f = from-json('-1.5')\ng = -f\nprint g", "1.5\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := runInput(t, t.TempDir(), tt.input)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("wrong output. expected=%q, got=%q", tt.expected, got)
+			}
+		})
+	}
+
+	if _, err := runInput(t, t.TempDir(), `x = -"abc"`); err == nil || err.Error() != "unary - needs a number, got string" {
+		t.Errorf("expected an error negating text, got %v", err)
+	}
+}
@@ -0,0 +1,82 @@
+package evaluator
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"ravenshell/ast"
+)
+
+// commandOrVariable returns expr, or a command running the program it names
+// when expr is a lone name that isn't a variable: make, or less in
+// ls | less. The parser can't tell these from variables, as in rows | to-csv.
+// Likewise a program run with an option such as -z is a subtraction when
+// its name is a variable: y -z.
+func (e *Evaluator) commandOrVariable(expr ast.Expression) ast.Expression {
+	if cmd, ok := expr.(*ast.Command); ok && cmd.Expression != nil {
+		if _, defined := e.vars[cmd.Name]; defined {
+			return cmd.Expression
+		}
+		return expr
+	}
+	ident, ok := expr.(*ast.Identifier)
+	if !ok {
+		return expr
+	}
+	if _, defined := e.vars[ident.Value]; defined {
+		return expr
+	}
+	return &ast.Command{Token: ident.Token, Name: ident.Value, Type: ast.CMD_EXTERNAL}
+}
+
+// execExternal runs a program found on $PATH with the shell's stdin,
+// stdout and stderr, in the current directory. Its exit status is kept in
+// the status variable. A non-zero status is only an error with the errexit
+// option on, like set -e in other shells; a program that can't be found or
+// started is always an error.
+func (e *Evaluator) execExternal(cmd *ast.Command) (string, error) {
+	args := make([]string, len(cmd.Arguments))
+	for i, arg := range cmd.Arguments {
+		val, err := e.evalExpression(arg)
+		if err != nil {
+			return "", err
+		}
+		args[i] = val
+	}
+
+	path, err := exec.LookPath(cmd.Name)
+	if err != nil {
+		e.vars["status"] = int64(127)
+		return "", &ShellError{Message: "unknown command: " + cmd.Name, Command: cmd.Name, Status: 127}
+	}
+
+	c := exec.Command(path, args...)
+	c.Args[0] = cmd.Name
+	c.Dir = e.cwd
+	c.Stdin, c.Stdout, c.Stderr = e.stdin, e.stdout, e.stderr
+	c.Env = os.Environ()
+	for name, val := range e.env {
+		c.Env = append(c.Env, name+"="+val)
+	}
+
+	err = c.Run()
+	var exitErr *exec.ExitError
+	if err != nil && !errors.As(err, &exitErr) {
+		e.vars["status"] = int64(126)
+		return "", &ShellError{Message: fmt.Sprintf("%s: %v", cmd.Name, err), Command: cmd.Name, Status: 126}
+	}
+
+	status := int64(0)
+	if exitErr != nil {
+		// A program killed by a signal has no exit code
+		if status = int64(exitErr.ExitCode()); status < 0 {
+			status = 1
+		}
+	}
+	e.vars["status"] = status
+	if status != 0 && e.boolOption("errexit") {
+		return "", &ShellError{Message: fmt.Sprintf("%s: %v", cmd.Name, exitErr), Command: cmd.Name, Status: status}
+	}
+	return "", nil
+}
@@ -0,0 +1,288 @@
+package evaluator
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"ravenshell/ast"
+	"strings"
+	"time"
+)
+
+// transfer is a source and where it goes, as resolved paths and as the
+// user wrote them, which messages show
+type transfer struct {
+	from, to       string
+	fromArg, toArg string
+}
+
+// destinations pairs each source with where it should go: inside dst when
+// dst is a directory, otherwise dst itself. Several sources require dst to
+// be an existing directory.
+func (e *Evaluator) destinations(cmd string, args []string) ([]transfer, error) {
+	if len(args) == 0 {
+		return nil, fmt.Errorf("%s: missing file operand", cmd)
+	}
+	if len(args) == 1 {
+		return nil, fmt.Errorf("%s: missing destination file operand after '%s'", cmd, args[0])
+	}
+
+	sources, target := args[:len(args)-1], args[len(args)-1]
+	dst := e.resolvePath(target)
+	info, err := os.Stat(dst)
+	intoDir := err == nil && info.IsDir()
+	if len(sources) > 1 && !intoDir {
+		return nil, fmt.Errorf("%s: target '%s' is not a directory", cmd, target)
+	}
+
+	pairs := make([]transfer, len(sources))
+	for i, src := range sources {
+		pair := transfer{from: e.resolvePath(src), to: dst, fromArg: src, toArg: target}
+		if intoDir {
+			pair.to = filepath.Join(dst, filepath.Base(pair.from))
+			pair.toArg = filepath.Join(target, filepath.Base(pair.from))
+		}
+		pairs[i] = pair
+	}
+	return pairs, nil
+}
+
+// clearDestination makes room for a new file at path. Anything already
+// there is moved to the trash and recorded in op; with noClobber it is left
+// alone and clearDestination reports false.
+func clearDestination(path string, noClobber bool, op *fileOp) (bool, error) {
+	if _, err := os.Lstat(path); err != nil {
+		return true, nil
+	}
+	if noClobber {
+		return false, nil
+	}
+	item, err := moveToTrash(path)
+	if err != nil {
+		return false, err
+	}
+	op.trashed = append(op.trashed, item)
+	return true, nil
+}
+
+// sameFile reports whether a and b both exist and, following symbolic
+// links, are the same file
+func sameFile(a, b string) bool {
+	aInfo, err := os.Stat(a)
+	if err != nil {
+		return false
+	}
+	bInfo, err := os.Stat(b)
+	return err == nil && os.SameFile(aInfo, bInfo)
+}
+
+func (e *Evaluator) execCopy(flags flagSet, args []string) (string, error) {
+	pairs, err := e.destinations("cp", args)
+	if err != nil {
+		return "", err
+	}
+
+	op := fileOp{command: "cp"}
+	defer func() { e.record(op) }()
+
+	for _, pair := range pairs {
+		from, to := pair.from, pair.to
+		// A symbolic link named as a source is followed, unless -P asks
+		// for the link itself; links inside a copied tree stay links
+		stat := os.Stat
+		if flags.has("no-dereference") {
+			stat = os.Lstat
+		}
+		info, err := stat(from)
+		if err != nil {
+			return "", fmt.Errorf("cp: cannot stat '%s': %v", pair.fromArg, unwrapPathError(err))
+		}
+		if !flags.has("no-dereference") {
+			if from, err = filepath.EvalSymlinks(from); err != nil {
+				return "", fmt.Errorf("cp: cannot stat '%s': %v", pair.fromArg, unwrapPathError(err))
+			}
+		}
+		if info.IsDir() {
+			if !flags.has("recursive") {
+				return "", fmt.Errorf("cp: -r not specified; omitting directory '%s'", pair.fromArg)
+			}
+			if to == from || isWithin(to, from) {
+				return "", fmt.Errorf("cp: cannot copy '%s' into itself", pair.fromArg)
+			}
+		}
+		if to == from || sameFile(pair.from, to) {
+			return "", fmt.Errorf("cp: '%s' and '%s' are the same file", pair.fromArg, pair.toArg)
+		}
+
+		ok, err := clearDestination(to, flags.has("no-clobber"), &op)
+		if err != nil {
+			return "", fmt.Errorf("cp: cannot overwrite '%s': %v", pair.toArg, unwrapPathError(err))
+		}
+		if !ok {
+			continue
+		}
+		if err := copyTree(from, to, flags.has("preserve")); err != nil {
+			return "", fmt.Errorf("cp: cannot copy '%s' to '%s': %v", pair.fromArg, pair.toArg, unwrapPathError(err))
+		}
+		op.created = append(op.created, to)
+	}
+	return "", nil
+}
+
+func (e *Evaluator) execMove(flags flagSet, args []string) (string, error) {
+	pairs, err := e.destinations("mv", args)
+	if err != nil {
+		return "", err
+	}
+
+	op := fileOp{command: "mv"}
+	defer func() { e.record(op) }()
+
+	for _, pair := range pairs {
+		from, to := pair.from, pair.to
+		if _, err := os.Lstat(from); err != nil {
+			return "", fmt.Errorf("mv: cannot stat '%s': %v", pair.fromArg, unwrapPathError(err))
+		}
+		if protected, ok := e.protectedBy(from); ok {
+			return "", fmt.Errorf("mv: refusing to move '%s': it contains protected path '%s'", pair.fromArg, protected)
+		}
+		if to == from {
+			return "", fmt.Errorf("mv: '%s' and '%s' are the same file", pair.fromArg, pair.toArg)
+		}
+		if isWithin(to, from) {
+			return "", fmt.Errorf("mv: cannot move '%s' into itself", pair.fromArg)
+		}
+
+		ok, err := clearDestination(to, flags.has("no-clobber"), &op)
+		if err != nil {
+			return "", fmt.Errorf("mv: cannot overwrite '%s': %v", pair.toArg, unwrapPathError(err))
+		}
+		if !ok {
+			continue
+		}
+		if err := movePath(from, to); err != nil {
+			return "", fmt.Errorf("mv: cannot move '%s' to '%s': %v", pair.fromArg, pair.toArg, unwrapPathError(err))
+		}
+		op.moved = append(op.moved, [2]string{from, to})
+	}
+	return "", nil
+}
+
+func (e *Evaluator) execLink(flags flagSet, args []string) (string, error) {
+	if len(args) == 0 {
+		return "", fmt.Errorf("ln: missing file operand")
+	}
+	// With a single operand the link is made in the current directory
+	if len(args) == 1 {
+		args = append(args, ".")
+	}
+	pairs, err := e.destinations("ln", args)
+	if err != nil {
+		return "", err
+	}
+
+	op := fileOp{command: "ln"}
+	defer func() { e.record(op) }()
+
+	for _, pair := range pairs {
+		target, link := pair.from, pair.to
+		if !flags.has("symbolic") {
+			if _, err := os.Stat(target); err != nil {
+				return "", fmt.Errorf("ln: failed to access '%s': %v", pair.fromArg, unwrapPathError(err))
+			}
+		}
+
+		if _, err := os.Lstat(link); err == nil && !flags.has("force") {
+			return "", fmt.Errorf("ln: failed to create link '%s': file exists", pair.toArg)
+		}
+		if _, err := clearDestination(link, false, &op); err != nil {
+			return "", fmt.Errorf("ln: cannot replace '%s': %v", pair.toArg, unwrapPathError(err))
+		}
+
+		if flags.has("symbolic") {
+			err = os.Symlink(symlinkTarget(pair), link)
+		} else {
+			err = os.Link(target, link)
+		}
+		if err != nil {
+			return "", fmt.Errorf("ln: failed to create link '%s': %v", pair.toArg, unwrapPathError(err))
+		}
+		op.created = append(op.created, link)
+	}
+	return "", nil
+}
+
+// symlinkTarget is what a symbolic link points to: the target as typed, so
+// a relative link stays relative to the link's directory as with ln(1).
+// Only ~ is expanded, since the link can't.
+func symlinkTarget(pair transfer) string {
+	if pair.fromArg == "~" || strings.HasPrefix(pair.fromArg, "~/") {
+		return pair.from
+	}
+	return pair.fromArg
+}
+
+// touchTimeFormats are the layouts accepted by touch --date
+var touchTimeFormats = []string{
+	time.RFC3339,
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+func (e *Evaluator) execTouch(flags flagSet, args []string) (string, error) {
+	if len(args) == 0 {
+		return "", fmt.Errorf("touch: missing file operand")
+	}
+
+	when := time.Now()
+	if date := flags.value("date", ""); date != "" {
+		parsed, err := parseTouchTime(date)
+		if err != nil {
+			return "", builtinSpecs[ast.CMD_TOUCH].usageError("invalid date format '%s'", date)
+		}
+		when = parsed
+	}
+
+	op := fileOp{command: "touch"}
+	defer func() { e.record(op) }()
+
+	for _, arg := range args {
+		path := e.resolvePath(arg)
+		if _, err := os.Lstat(path); os.IsNotExist(err) {
+			if flags.has("no-create") {
+				continue
+			}
+			// O_CREATE without O_TRUNC never empties a file created meanwhile
+			file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE, 0644)
+			if err != nil {
+				return "", fmt.Errorf("touch: cannot touch '%s': %v", arg, unwrapPathError(err))
+			}
+			file.Close()
+			op.created = append(op.created, path)
+		} else if _, err := os.Stat(path); os.IsNotExist(err) {
+			// A symbolic link to a missing file. Creating the file it
+			// points to would put it where undo doesn't look.
+			if flags.has("no-create") {
+				continue
+			}
+			return "", fmt.Errorf("touch: cannot touch '%s': symbolic link to a missing file", arg)
+		}
+		if err := os.Chtimes(path, when, when); err != nil {
+			return "", fmt.Errorf("touch: cannot touch '%s': %v", arg, unwrapPathError(err))
+		}
+	}
+	return "", nil
+}
+
+// parseTouchTime parses a --date value in local time
+func parseTouchTime(value string) (time.Time, error) {
+	var err error
+	for _, layout := range touchTimeFormats {
+		var t time.Time
+		if t, err = time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, err
+}
@@ -0,0 +1,193 @@
+package evaluator
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCopy(t *testing.T) {
+	dir := t.TempDir()
+	makeTree(t, dir)
+	old := time.Date(2020, 1, 2, 3, 4, 5, 0, time.Local)
+	if err := os.Chtimes(filepath.Join(dir, "tree", "a.txt"), old, old); err != nil {
+		t.Fatal(err)
+	}
+	e, _ := newTestEvaluator(t, dir)
+
+	if err := evalInput(t, e, "cp tree copy"); err == nil {
+		t.Error("expected error copying a directory without -r")
+	}
+	if err := evalInput(t, e, "cp -rp tree copy"); err != nil {
+		t.Fatalf("cp -rp returned error: %v", err)
+	}
+	if !exists(filepath.Join(dir, "copy", "sub", "b.txt")) {
+		t.Error("cp -r did not copy the tree contents")
+	}
+	info, err := os.Stat(filepath.Join(dir, "copy", "a.txt"))
+	if err != nil || !info.ModTime().Equal(old) {
+		t.Errorf("cp -p did not keep the modification time. got=%v, err=%v", info, err)
+	}
+
+	// A directory destination receives the source under its own name
+	if err := evalInput(t, e, "cp tree/a.txt copy/sub"); err != nil {
+		t.Fatalf("cp into directory returned error: %v", err)
+	}
+	if !exists(filepath.Join(dir, "copy", "sub", "a.txt")) {
+		t.Error("cp did not copy into the directory")
+	}
+
+	if err := evalInput(t, e, "cp -r tree tree/sub"); err == nil {
+		t.Error("expected error copying a directory into itself")
+	}
+
+	// A symbolic link source is copied as the file it points to, or as a
+	// link with -P
+	if err := os.Symlink("tree/a.txt", filepath.Join(dir, "link")); err != nil {
+		t.Fatal(err)
+	}
+	if err := evalInput(t, e, "cp link plain\ncp -P link kept"); err != nil {
+		t.Fatalf("cp of a symlink returned error: %v", err)
+	}
+	if info, err := os.Lstat(filepath.Join(dir, "plain")); err != nil || !info.Mode().IsRegular() {
+		t.Errorf("cp did not follow the symlink. got=%v, err=%v", info, err)
+	}
+	if target, err := os.Readlink(filepath.Join(dir, "kept")); err != nil || target != "tree/a.txt" {
+		t.Errorf("cp -P did not copy the symlink. got=%q, err=%v", target, err)
+	}
+	err = evalInput(t, e, "cp link tree/a.txt")
+	if err == nil || !strings.Contains(err.Error(), "are the same file") {
+		t.Errorf("expected error copying a symlink onto its target, got %v", err)
+	}
+	if !exists(filepath.Join(dir, "tree", "a.txt")) {
+		t.Error("the symlink's target was moved to the trash")
+	}
+}
+
+func TestMoveAndUndo(t *testing.T) {
+	dir := t.TempDir()
+	makeTree(t, dir)
+	if err := os.WriteFile(filepath.Join(dir, "target.txt"), []byte("old"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	e, _ := newTestEvaluator(t, dir)
+
+	if err := evalInput(t, e, "mv tree/a.txt target.txt"); err != nil {
+		t.Fatalf("mv returned error: %v", err)
+	}
+	if exists(filepath.Join(dir, "tree", "a.txt")) {
+		t.Error("mv left the source behind")
+	}
+
+	if err := evalInput(t, e, "undo"); err != nil {
+		t.Fatalf("undo returned error: %v", err)
+	}
+	if !exists(filepath.Join(dir, "tree", "a.txt")) {
+		t.Error("undo did not move the file back")
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "target.txt"))
+	if err != nil || string(data) != "old" {
+		t.Errorf("undo did not restore the overwritten file. got=%q, err=%v", data, err)
+	}
+
+	if err := evalInput(t, e, "mv -n tree/a.txt target.txt"); err != nil {
+		t.Fatalf("mv -n returned error: %v", err)
+	}
+	if !exists(filepath.Join(dir, "tree", "a.txt")) {
+		t.Error("mv -n replaced an existing destination")
+	}
+}
+
+func TestLink(t *testing.T) {
+	dir := t.TempDir()
+	makeTree(t, dir)
+	e, _ := newTestEvaluator(t, dir)
+
+	if err := evalInput(t, e, "ln -s tree/a.txt soft"); err != nil {
+		t.Fatalf("ln -s returned error: %v", err)
+	}
+	// The target is kept as typed, relative to the link like ln(1)
+	target, err := os.Readlink(filepath.Join(dir, "soft"))
+	if err != nil || target != "tree/a.txt" {
+		t.Errorf("wrong symlink target. got=%q, err=%v", target, err)
+	}
+	if err := evalInput(t, e, "ln -s a.txt tree/rel"); err != nil {
+		t.Fatalf("ln -s returned error: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "tree", "rel")); err != nil {
+		t.Errorf("relative symlink doesn't resolve from its directory. err=%v", err)
+	}
+
+	if err := evalInput(t, e, "ln tree/a.txt hard"); err != nil {
+		t.Fatalf("ln returned error: %v", err)
+	}
+	a, _ := os.Stat(filepath.Join(dir, "tree", "a.txt"))
+	b, err := os.Stat(filepath.Join(dir, "hard"))
+	if err != nil || !os.SameFile(a, b) {
+		t.Errorf("ln did not make a hard link. err=%v", err)
+	}
+
+	// Messages show paths as typed, as rm and mv do
+	err = evalInput(t, e, "ln tree/sub/b.txt hard")
+	if err == nil || err.Error() != "ln: failed to create link 'hard': file exists" {
+		t.Errorf("expected error replacing an existing link without -f, got %v", err)
+	}
+	err = evalInput(t, e, "ln missing.txt other")
+	if err == nil || !strings.HasPrefix(err.Error(), "ln: failed to access 'missing.txt'") {
+		t.Errorf("expected error naming missing.txt as typed, got %v", err)
+	}
+	if err := evalInput(t, e, "ln -f tree/sub/b.txt hard"); err != nil {
+		t.Errorf("ln -f returned error: %v", err)
+	}
+}
+
+func TestTouch(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "notes.txt")
+	if err := os.WriteFile(path, []byte("keep me"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	e, _ := newTestEvaluator(t, dir)
+
+	if err := evalInput(t, e, `touch -d "2021-06-01 12:00" notes.txt new.txt`); err != nil {
+		t.Fatalf("touch returned error: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil || string(data) != "keep me" {
+		t.Errorf("touch changed the file contents. got=%q, err=%v", data, err)
+	}
+	want := time.Date(2021, 6, 1, 12, 0, 0, 0, time.Local)
+	for _, name := range []string{"notes.txt", "new.txt"} {
+		info, err := os.Stat(filepath.Join(dir, name))
+		if err != nil || !info.ModTime().Equal(want) {
+			t.Errorf("%s: wrong modification time. got=%v, err=%v", name, info, err)
+		}
+	}
+
+	if err := evalInput(t, e, "touch -c missing.txt"); err != nil {
+		t.Fatalf("touch -c returned error: %v", err)
+	}
+	if exists(filepath.Join(dir, "missing.txt")) {
+		t.Error("touch -c created a file")
+	}
+	if err := evalInput(t, e, "touch -d yesterday notes.txt"); err == nil {
+		t.Error("expected error for an invalid date")
+	}
+
+	// A symbolic link to a missing file is not taken as missing itself
+	if err := os.Symlink("gone.txt", filepath.Join(dir, "dangling")); err != nil {
+		t.Fatal(err)
+	}
+	err = evalInput(t, e, "touch dangling")
+	if err == nil || !strings.Contains(err.Error(), "symbolic link to a missing file") {
+		t.Errorf("expected error touching a dangling symlink, got %v", err)
+	}
+	if err := evalInput(t, e, "touch -c dangling"); err != nil {
+		t.Errorf("touch -c of a dangling symlink returned error: %v", err)
+	}
+	if exists(filepath.Join(dir, "gone.txt")) {
+		t.Error("touch created the symlink's target")
+	}
+}
@@ -0,0 +1,102 @@
+package evaluator
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+// movePath renames src to dst, falling back to copy and delete when they
+// are on different filesystems
+func movePath(src, dst string) error {
+	err := os.Rename(src, dst)
+	if err == nil || !errors.Is(err, syscall.EXDEV) {
+		return err
+	}
+
+	if err := copyTree(src, dst, true); err != nil {
+		os.RemoveAll(dst)
+		return err
+	}
+	return os.RemoveAll(src)
+}
+
+// copyTree copies a file, symlink or directory tree from src to dst,
+// keeping permissions and, when preserveTimes is set, modification times
+func copyTree(src, dst string, preserveTimes bool) error {
+	info, err := os.Lstat(src)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case info.Mode()&os.ModeSymlink != 0:
+		target, err := os.Readlink(src)
+		if err != nil {
+			return err
+		}
+		return os.Symlink(target, dst)
+
+	case info.IsDir():
+		if err := os.Mkdir(dst, info.Mode().Perm()|0700); err != nil {
+			return err
+		}
+		entries, err := os.ReadDir(src)
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			if err := copyTree(filepath.Join(src, entry.Name()), filepath.Join(dst, entry.Name()), preserveTimes); err != nil {
+				return err
+			}
+		}
+		// Set the final mode and time after the contents, since adding
+		// entries updates the directory's mtime
+		if err := os.Chmod(dst, info.Mode().Perm()); err != nil {
+			return err
+		}
+		if !preserveTimes {
+			return nil
+		}
+		return os.Chtimes(dst, info.ModTime(), info.ModTime())
+
+	case info.Mode().IsRegular():
+		return copyFile(src, dst, info, preserveTimes)
+
+	default:
+		return fmt.Errorf("%s: cannot copy special file", src)
+	}
+}
+
+// copyFile copies a regular file's contents and mode, and its modification
+// time when preserveTimes is set
+func copyFile(src, dst string, info fs.FileInfo, preserveTimes bool) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(dst, info.Mode().Perm()); err != nil {
+		return err
+	}
+	if !preserveTimes {
+		return nil
+	}
+	return os.Chtimes(dst, info.ModTime(), info.ModTime())
+}
@@ -0,0 +1,263 @@
+package evaluator
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"ravenshell/ast"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// findOptions holds the predicates accepted by find
+type findOptions struct {
+	name     string   // --name: glob the base name must match
+	kind     string   // --type: f, d or l
+	size     *bound   // --size: compared in bytes
+	mtime    *bound   // --mtime: age of the last modification, in whole units
+	maxDepth int      // --max-depth: -1 for no limit
+	prune    []string // --prune: colon-separated globs of directories to skip
+}
+
+// bound is a numeric test written N (exactly), +N (more than) or -N (less
+// than), with N counted in unit
+type bound struct {
+	cmp   int // -1, 0 or 1
+	value int64
+	unit  int64
+}
+
+// matches compares n with the bound scaled to the same measure, as sizes
+// are compared in bytes
+func (b *bound) matches(n int64) bool {
+	return b.compare(n, b.value*b.unit)
+}
+
+// matchesWhole compares n counted in whole units, dropping any part of
+// one, the way find(1) counts ages: a file changed 3 days and 5 hours ago
+// matches 3 but not +3
+func (b *bound) matchesWhole(n int64) bool {
+	return b.compare(n/b.unit, b.value)
+}
+
+func (b *bound) compare(n, value int64) bool {
+	switch b.cmp {
+	case 1:
+		return n > value
+	case -1:
+		return n < value
+	}
+	return n == value
+}
+
+// sizeUnits are the suffixes accepted by --size
+var sizeUnits = map[byte]int64{'c': 1, 'k': 1 << 10, 'K': 1 << 10, 'M': 1 << 20, 'G': 1 << 30}
+
+// ageUnits are the suffixes accepted by --mtime; a bare number means days
+var ageUnits = map[byte]int64{'s': 1, 'm': 60, 'h': 3600, 'd': 86400, 'w': 7 * 86400}
+
+// parseBound reads [+|-]N[unit], N counted in the unit's multiplier
+func parseBound(text string, units map[byte]int64, defUnit int64) (*bound, error) {
+	b := &bound{}
+	switch {
+	case strings.HasPrefix(text, "+"):
+		b.cmp, text = 1, text[1:]
+	case strings.HasPrefix(text, "-"):
+		b.cmp, text = -1, text[1:]
+	}
+	mult := defUnit
+	if text != "" {
+		if m, ok := units[text[len(text)-1]]; ok {
+			mult, text = m, text[:len(text)-1]
+		}
+	}
+	n, err := strconv.ParseInt(text, 10, 64)
+	if err != nil || n < 0 {
+		return nil, fmt.Errorf("invalid value")
+	}
+	b.value, b.unit = n, mult
+	return b, nil
+}
+
+// newFindOptions reads find predicates from the parsed flags
+func newFindOptions(flags flagSet) (findOptions, error) {
+	spec := builtinSpecs[ast.CMD_FIND]
+	opts := findOptions{name: flags.value("name", ""), maxDepth: -1}
+
+	if kind := flags.value("type", ""); kind != "" {
+		if kind != "f" && kind != "d" && kind != "l" {
+			return opts, spec.usageError("invalid type '%s' (use f, d or l)", kind)
+		}
+		opts.kind = kind
+	}
+	if opts.name != "" {
+		if _, err := filepath.Match(opts.name, ""); err != nil {
+			return opts, spec.usageError("invalid pattern '%s'", opts.name)
+		}
+	}
+	if text := flags.value("size", ""); text != "" {
+		b, err := parseBound(text, sizeUnits, 1)
+		if err != nil {
+			return opts, spec.usageError("invalid size '%s'", text)
+		}
+		opts.size = b
+	}
+	if text := flags.value("mtime", ""); text != "" {
+		b, err := parseBound(text, ageUnits, ageUnits['d'])
+		if err != nil {
+			return opts, spec.usageError("invalid age '%s'", text)
+		}
+		opts.mtime = b
+	}
+	depth, err := flags.intValue(spec, "max-depth", -1)
+	if err != nil {
+		return opts, err
+	}
+	opts.maxDepth = int(depth)
+	for _, glob := range strings.Split(flags.value("prune", ""), ":") {
+		if glob != "" {
+			opts.prune = append(opts.prune, glob)
+		}
+	}
+	return opts, nil
+}
+
+// matches reports whether an entry satisfies every predicate
+func (o findOptions) matches(d fs.DirEntry, now time.Time) (bool, error) {
+	if o.name != "" {
+		if ok, _ := filepath.Match(o.name, d.Name()); !ok {
+			return false, nil
+		}
+	}
+	switch o.kind {
+	case "f":
+		if !d.Type().IsRegular() {
+			return false, nil
+		}
+	case "d":
+		if !d.IsDir() {
+			return false, nil
+		}
+	case "l":
+		if d.Type()&fs.ModeSymlink == 0 {
+			return false, nil
+		}
+	}
+	if o.size == nil && o.mtime == nil {
+		return true, nil
+	}
+
+	info, err := d.Info()
+	if err != nil {
+		return false, err
+	}
+	if o.size != nil && !o.size.matches(info.Size()) {
+		return false, nil
+	}
+	if o.mtime != nil && !o.mtime.matchesWhole(int64(now.Sub(info.ModTime()).Seconds())) {
+		return false, nil
+	}
+	return true, nil
+}
+
+// pruned reports whether find should skip a directory and everything in it
+func (o findOptions) pruned(d fs.DirEntry) bool {
+	for _, glob := range o.prune {
+		if ok, _ := filepath.Match(glob, d.Name()); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// walkFind walks each root (the current directory if none is given) and
+// calls fn with every matching path. Paths inside the current directory are
+// passed relative to it. Unreadable directories are skipped and the first
+// such error is returned once the walk is done.
+func (e *Evaluator) walkFind(flags flagSet, roots []string, fn func(path string)) error {
+	opts, err := newFindOptions(flags)
+	if err != nil {
+		return err
+	}
+	if len(roots) == 0 {
+		roots = []string{"."}
+	}
+
+	now := time.Now()
+	var firstErr error
+	for _, root := range roots {
+		start := e.resolvePath(root)
+		if _, err := os.Lstat(start); err != nil {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("find: '%s': %v", root, unwrapPathError(err))
+			}
+			continue
+		}
+
+		filepath.WalkDir(start, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				if firstErr == nil {
+					firstErr = fmt.Errorf("find: '%s': %v", e.displayPath(path), unwrapPathError(err))
+				}
+				return nil
+			}
+
+			depth := 0
+			if path != start {
+				depth = strings.Count(strings.TrimPrefix(path, start), string(filepath.Separator))
+				if strings.HasSuffix(start, string(filepath.Separator)) {
+					depth++
+				}
+			}
+			if d.IsDir() && path != start && opts.pruned(d) {
+				return filepath.SkipDir
+			}
+
+			ok, err := opts.matches(d, now)
+			if err != nil {
+				// Entry vanished between reading the directory and stat
+				return nil
+			}
+			if ok {
+				fn(e.displayPath(path))
+			}
+			if d.IsDir() && opts.maxDepth >= 0 && depth >= opts.maxDepth {
+				return filepath.SkipDir
+			}
+			return nil
+		})
+	}
+	return firstErr
+}
+
+// displayPath shows path relative to the current directory when it is
+// inside it, and absolute otherwise
+func (e *Evaluator) displayPath(path string) string {
+	if path == e.cwd {
+		return "."
+	}
+	if isWithin(path, e.cwd) {
+		if rel, err := filepath.Rel(e.cwd, path); err == nil {
+			return rel
+		}
+	}
+	return path
+}
+
+func (e *Evaluator) execFind(flags flagSet, args []string) (string, error) {
+	out := e.newFilterOutput()
+	err := e.walkFind(flags, args, out.line)
+	return out.result.String(), err
+}
+
+// findValues runs find for use as a value and returns the matching paths as
+// an array instead of printing them
+func (e *Evaluator) findValues(flags flagSet, args []string) (Value, error) {
+	paths := []Value{}
+	err := e.walkFind(flags, args, func(path string) {
+		paths = append(paths, path)
+	})
+	return paths, err
+}
@@ -0,0 +1,122 @@
+package evaluator
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFind(t *testing.T) {
+	dir := t.TempDir()
+	makeTree(t, dir)
+	if err := os.MkdirAll(filepath.Join(dir, "tree", "cache"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "tree", "cache", "c.txt"), make([]byte, 2048), 0644); err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-48 * time.Hour)
+	if err := os.Chtimes(filepath.Join(dir, "tree", "a.txt"), old, old); err != nil {
+		t.Fatal(err)
+	}
+	// Three days and a few hours: the part of a day is dropped, as find(1) does
+	older := time.Now().Add(-(3*24 + 5) * time.Hour)
+	if err := os.Chtimes(filepath.Join(dir, "tree", "sub", "b.txt"), older, older); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"find tree --type d", "tree\ntree/cache\ntree/sub\n"},
+		{`find --name "*.txt"`, "tree/a.txt\ntree/cache/c.txt\ntree/sub/b.txt\n"},
+		{`find tree --name "*.txt" --prune=cache`, "tree/a.txt\ntree/sub/b.txt\n"},
+		{"find tree -d 1", "tree\ntree/a.txt\ntree/cache\ntree/sub\n"},
+		{"find tree --type f --size=+1k", "tree/cache/c.txt\n"},
+		{"find tree --type f --mtime=+1", "tree/a.txt\ntree/sub/b.txt\n"},
+		{"find tree --type f --mtime=3", "tree/sub/b.txt\n"},
+		{"find tree --type f --mtime=2", "tree/a.txt\n"},
+		{"find tree --type f --mtime=+3", ""},
+		{"find tree --type f --mtime=-3", "tree/a.txt\ntree/cache/c.txt\n"},
+		{"find tree --type f --mtime=-1h", "tree/cache/c.txt\n"},
+		{"find tree --type f --size=2k", "tree/cache/c.txt\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := runInput(t, dir, tt.input)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("wrong output.\nexpected=%q\ngot=%q", tt.expected, got)
+			}
+		})
+	}
+
+	if _, err := runInput(t, dir, "find --type x"); err == nil {
+		t.Error("expected error for an invalid type")
+	}
+	if _, err := runInput(t, dir, "find missing"); err == nil {
+		t.Error("expected error for a missing root")
+	}
+
+	// A signed value after a space reads as arithmetic, so it must be attached
+	for _, input := range []string{"find tree --size +1k", "find tree -m -1"} {
+		_, err := runInput(t, dir, input)
+		if err == nil || !strings.Contains(err.Error(), "attached") {
+			t.Errorf("%s: expected a usage error, got %v", input, err)
+		}
+	}
+}
+
+func TestFindAsValue(t *testing.T) {
+	dir := t.TempDir()
+	makeTree(t, dir)
+	e, out := newTestEvaluator(t, dir)
+
+	if err := evalInput(t, e, "files = find tree --type f"); err != nil {
+		t.Fatalf("assignment returned error: %v", err)
+	}
+	if out.Len() != 0 {
+		t.Errorf("find printed while used as a value: %q", out.String())
+	}
+	expected := []Value{"tree/a.txt", "tree/sub/b.txt"}
+	if !reflect.DeepEqual(e.vars["files"], expected) {
+		t.Errorf("wrong value. expected=%v, got=%v", expected, e.vars["files"])
+	}
+}
+
+func TestFindInExpression(t *testing.T) {
+	dir := t.TempDir()
+	makeTree(t, dir)
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`print len(find tree --type f)`, "2\n"},
+		{`n = len(find tree --name "*.txt") + 1
+print n`, "3\n"},
+		{`second = (find tree --type f)[1]
+print second`, "tree/sub/b.txt\n"},
+		{`print join(find tree --type f, ",")`, "tree/a.txt,tree/sub/b.txt\n"},
+		{`print len([find tree --type d])`, "1\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := runInput(t, dir, tt.input)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("wrong output.\nexpected=%q\ngot=%q", tt.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,284 @@
+package evaluator
+
+import (
+	"bytes"
+	"fmt"
+	"ravenshell/ast"
+	"strconv"
+	"strings"
+)
+
+// flagSpec describes one option accepted by a built-in command
+type flagSpec struct {
+	short byte   // single-letter form (-l), 0 if there is none
+	long  string // long form without dashes (--all), empty if there is none
+	arg   string // placeholder for the option's value; empty for boolean options
+	help  string // one-line description shown by --help
+}
+
+// name returns the key the option is stored under in a flagSet
+func (f flagSpec) name() string {
+	if f.long != "" {
+		return f.long
+	}
+	return string(f.short)
+}
+
+// commandSpec describes a built-in's options and usage
+type commandSpec struct {
+	name    string     // command name as typed
+	usage   string     // synopsis after the name, e.g. "[options] [path...]"
+	summary string     // one-line description shown by --help
+	flags   []flagSpec // accepted options
+	// Path operands reach the command as typed, for its messages and for
+	// ln -s; the command resolves them itself
+	typedPaths bool
+}
+
+// flagSet holds the options given to a command, keyed by flagSpec.name
+type flagSet struct {
+	values map[string]string
+}
+
+// has reports whether the option was given
+func (f flagSet) has(name string) bool {
+	_, ok := f.values[name]
+	return ok
+}
+
+// value returns the option's value, or def if it was not given
+func (f flagSet) value(name, def string) string {
+	if v, ok := f.values[name]; ok {
+		return v
+	}
+	return def
+}
+
+// intValue returns the option's value as an integer, or def if it was not given
+func (f flagSet) intValue(spec *commandSpec, name string, def int64) (int64, error) {
+	v, ok := f.values[name]
+	if !ok {
+		return def, nil
+	}
+	n, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		return 0, spec.usageError("invalid number '%s' for %s", v, flagDisplay(name))
+	}
+	return n, nil
+}
+
+// flagDisplay formats an option name the way it is typed: -n or --lines
+func flagDisplay(name string) string {
+	if len(name) == 1 {
+		return "-" + name
+	}
+	return "--" + name
+}
+
+// usageError builds an error naming the command followed by its usage line
+func (s *commandSpec) usageError(format string, a ...interface{}) error {
+	return fmt.Errorf("%s: %s\n%s", s.name, fmt.Sprintf(format, a...), s.synopsis())
+}
+
+// synopsis returns the usage line: "usage: ls [options] [path...]"
+func (s *commandSpec) synopsis() string {
+	return strings.TrimSpace("usage: " + s.name + " " + s.usage)
+}
+
+// lookupShort finds the option for a single-letter flag
+func (s *commandSpec) lookupShort(ch byte) (flagSpec, bool) {
+	for _, f := range s.flags {
+		if f.short != 0 && f.short == ch {
+			return f, true
+		}
+	}
+	return flagSpec{}, false
+}
+
+// lookupLong finds the option for a --name flag
+func (s *commandSpec) lookupLong(name string) (flagSpec, bool) {
+	for _, f := range s.flags {
+		if f.long != "" && f.long == name {
+			return f, true
+		}
+	}
+	return flagSpec{}, false
+}
+
+// helpText renders the usage and option table shown by --help
+func (s *commandSpec) helpText() string {
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "%s\n", s.synopsis())
+	fmt.Fprintf(&out, "%s\n", s.summary)
+
+	out.WriteString("\noptions:\n")
+	rows := make([][2]string, 0, len(s.flags)+1)
+	for _, f := range s.flags {
+		var form string
+		switch {
+		case f.short != 0 && f.long != "":
+			form = fmt.Sprintf("-%c, --%s", f.short, f.long)
+		case f.short != 0:
+			form = fmt.Sprintf("-%c", f.short)
+		default:
+			form = "    --" + f.long
+		}
+		if f.arg != "" {
+			if f.long != "" {
+				form += "=" + f.arg
+			} else {
+				form += " " + f.arg
+			}
+		}
+		rows = append(rows, [2]string{form, f.help})
+	}
+	rows = append(rows, [2]string{"    --help", "show this help"})
+
+	width := 0
+	for _, row := range rows {
+		if len(row[0]) > width {
+			width = len(row[0])
+		}
+	}
+	for _, row := range rows {
+		fmt.Fprintf(&out, "  %-*s  %s\n", width, row[0], row[1])
+	}
+	return out.String()
+}
+
+// wantsHelp reports whether --help appears among a command's options
+func wantsHelp(args []ast.Expression) bool {
+	for _, arg := range args {
+		if flag, ok := arg.(*ast.FlagLiteral); ok {
+			if flag.Value == "--" {
+				return false
+			}
+			if flag.Value == "--help" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// parseCommandArgs evaluates a command's arguments, separating options from
+// operands. Only option syntax (-l, --all) counts as an option: a string or
+// variable whose value starts with a dash is always an operand, so
+// rm "-file" removes a file named -file.
+func (e *Evaluator) parseCommandArgs(spec *commandSpec, exprs []ast.Expression) (flagSet, []string, error) {
+	flags := flagSet{values: make(map[string]string)}
+	var operands []string
+	optionsDone := false
+
+	for i := 0; i < len(exprs); i++ {
+		if err := signedValueError(spec, exprs[i]); !optionsDone && err != nil {
+			return flags, nil, err
+		}
+		flag, isFlag := exprs[i].(*ast.FlagLiteral)
+		if !isFlag || optionsDone {
+			val, err := e.evalOperand(spec, exprs[i])
+			if err != nil {
+				return flags, nil, err
+			}
+			operands = append(operands, val)
+			continue
+		}
+
+		// nextValue takes the following argument as an option's value. A
+		// value that looks like a path stays as typed: -p a.b is a key path
+		// and --prune .git a glob, not files in the working directory.
+		nextValue := func(display string) (string, error) {
+			if i+1 >= len(exprs) {
+				return "", spec.usageError("option requires an argument -- '%s'", display)
+			}
+			i++
+			if path, ok := exprs[i].(*ast.PathExpression); ok {
+				return path.Value, nil
+			}
+			return e.evalExpression(exprs[i])
+		}
+
+		text := flag.Value
+		switch {
+		case text == "--":
+			optionsDone = true
+
+		case strings.HasPrefix(text, "--"):
+			name, value, hasValue := strings.Cut(text[2:], "=")
+			f, ok := spec.lookupLong(name)
+			if !ok {
+				return flags, nil, spec.usageError("unrecognized option '--%s'", name)
+			}
+			if f.arg == "" {
+				if hasValue {
+					return flags, nil, spec.usageError("option '--%s' doesn't allow an argument", name)
+				}
+				flags.values[f.name()] = ""
+				continue
+			}
+			if !hasValue {
+				v, err := nextValue(name)
+				if err != nil {
+					return flags, nil, err
+				}
+				value = v
+			}
+			flags.values[f.name()] = value
+
+		default:
+			// Short options can be grouped (-la); an option taking a value
+			// uses the rest of the group (-n5) or the next argument (-n 5)
+			letters := text[1:]
+			for j := 0; j < len(letters); j++ {
+				f, ok := spec.lookupShort(letters[j])
+				if !ok {
+					return flags, nil, spec.usageError("invalid option -- '%c'", letters[j])
+				}
+				if f.arg == "" {
+					flags.values[f.name()] = ""
+					continue
+				}
+				value := letters[j+1:]
+				if value == "" {
+					v, err := nextValue(string(letters[j]))
+					if err != nil {
+						return flags, nil, err
+					}
+					value = v
+				}
+				flags.values[f.name()] = value
+				break
+			}
+		}
+	}
+
+	return flags, operands, nil
+}
+
+// evalOperand evaluates a command's operand. A path stays as typed for
+// commands that resolve their paths themselves.
+func (e *Evaluator) evalOperand(spec *commandSpec, expr ast.Expression) (string, error) {
+	if path, ok := expr.(*ast.PathExpression); ok && spec.typedPaths {
+		return path.Value, nil
+	}
+	return e.evalExpression(expr)
+}
+
+// signedValueError rejects an option followed by a value starting with +
+// or -, which reads as arithmetic: --size +10k is --size + 10k. The value
+// has to be attached, as in --size=+10k or -s+10k.
+func signedValueError(spec *commandSpec, expr ast.Expression) error {
+	infix, ok := expr.(*ast.InfixExpression)
+	if !ok || (infix.Operator != "+" && infix.Operator != "-") {
+		return nil
+	}
+	flag, ok := infix.Left.(*ast.FlagLiteral)
+	if !ok {
+		return nil
+	}
+	attached := flag.Value + infix.Operator + "N"
+	if strings.HasPrefix(flag.Value, "--") {
+		attached = flag.Value + "=" + infix.Operator + "N"
+	}
+	return spec.usageError("option '%s' takes a value starting with %s attached, as in %s", flag.Value, infix.Operator, attached)
+}
@@ -0,0 +1,171 @@
+package evaluator
+
+import (
+	"os/exec"
+	"ravenshell/lexer"
+	"ravenshell/token"
+	"strings"
+)
+
+// Syntax highlighting colors the line being edited in the REPL. The line
+// is run through the lexer, which marks where each token starts and ends;
+// the text between tokens (spaces and comments) is kept as it is, so the
+// colored line takes up exactly the room of the typed one. Each kind of
+// token gets the color of a color_ shell option.
+
+// highlightOptions maps each kind of token to the option holding its color
+var highlightOptions = map[string]string{
+	"keyword":  "color_keyword",
+	"command":  "color_command",
+	"string":   "color_string",
+	"variable": "color_variable",
+	"operator": "color_operator",
+	"error":    "color_error",
+	"comment":  "color_comment",
+}
+
+// Highlight returns line with ANSI colors added. Built-in commands,
+// programs on $PATH and functions are colored as commands, and a name in
+// command position that is none of these or a variable as an error.
+func (e *Evaluator) Highlight(line string) string {
+	if !e.boolOption("highlight") {
+		return line
+	}
+
+	var tokens []token.Token
+	l := lexer.NewLexer(line)
+	for {
+		tok := l.NextToken()
+		if tok.Type == token.EOF {
+			break
+		}
+		tokens = append(tokens, tok)
+	}
+
+	var out strings.Builder
+	prev := 0
+	atCommand := true
+	for i, tok := range tokens {
+		e.writeGap(&out, line[prev:tok.Pos])
+		var next *token.Token
+		if i+1 < len(tokens) {
+			next = &tokens[i+1]
+		}
+		text := line[tok.Pos:tok.End]
+		kind := e.tokenKind(tok, next, atCommand)
+		if i > 0 && tokens[i-1].Type == token.DOLLAR && tokens[i-1].End == tok.Pos {
+			kind = "variable" // the name in $HOME
+		}
+		if kind == "error" && tok.End == len(line) && strings.ContainsAny(text, `"'`) {
+			// A quote that isn't closed yet is a string still being typed
+			kind = "string"
+		}
+		out.WriteString(e.colorize(kind, text))
+		prev = tok.End
+
+		switch tok.Type {
+		case token.PIPE, token.LBRACE, token.RBRACE, token.ASSIGN:
+			atCommand = true
+		case token.FULLSTOP, token.FSLASH, token.TILDE:
+			// ./script and ~/bin/tool keep command position until the
+			// path is over
+		default:
+			atCommand = false
+		}
+	}
+	e.writeGap(&out, line[prev:])
+	return out.String()
+}
+
+// writeGap copies the text between tokens, coloring a comment
+func (e *Evaluator) writeGap(out *strings.Builder, gap string) {
+	if i := strings.IndexByte(gap, '#'); i != -1 {
+		out.WriteString(gap[:i])
+		out.WriteString(e.colorize("comment", gap[i:]))
+		return
+	}
+	out.WriteString(gap)
+}
+
+// tokenKind decides how a token is colored. next is the token after it,
+// if any, and atCommand says whether a command name may stand here.
+func (e *Evaluator) tokenKind(tok token.Token, next *token.Token, atCommand bool) string {
+	switch tok.Type {
+	case token.FOR, token.IN, token.IF, token.ELSE, token.TRY, token.CATCH, token.FINALLY:
+		return "keyword"
+	case token.STRING:
+		return "string"
+	case token.DOLLAR:
+		return "variable"
+	case token.PIPE, token.ASSIGN, token.PLUS, token.MINUS, token.BANG, token.ASTERISK, token.PERCENT,
+		token.EQ, token.NOT_EQ, token.MATCH, token.ARROW, token.LT, token.GT, token.LTE, token.GTE,
+		token.INTO, token.OUT:
+		return "operator"
+	case token.ILLEGAL:
+		return "error"
+	case token.RANGE, token.APPEND:
+		return "command"
+	case token.IDENT:
+		if tok.Literal == "match" && atCommand && next != nil &&
+			next.Type != token.ASSIGN && next.Type != token.LBRACE {
+			// match followed by a value starts a match statement
+			return "keyword"
+		}
+		return e.identKind(tok, next, atCommand)
+	}
+
+	if token.ContextualCommands[tok.Type] && next != nil && next.Type == token.ASSIGN {
+		// head = 3 names a variable
+		return e.identKind(tok, next, atCommand)
+	}
+	if _, ok := token.TokenMap[tok.Literal]; ok && atCommand {
+		return "command"
+	}
+	return ""
+}
+
+// identKind colors a name: a function call, a variable, or in command
+// position a program that must exist on $PATH
+func (e *Evaluator) identKind(tok token.Token, next *token.Token, atCommand bool) string {
+	if next != nil && next.Type == token.LPAREN && next.Pos == tok.End {
+		if _, ok := builtins[tok.Literal]; ok {
+			return "command"
+		}
+		return "error"
+	}
+	if _, ok := e.vars[tok.Literal]; ok {
+		return "variable"
+	}
+	if !atCommand || (next != nil && next.Type == token.ASSIGN) {
+		return ""
+	}
+	if tok.Literal == "exit" || tok.Literal == "quit" {
+		return "command" // handled by the REPL
+	}
+	if _, err := exec.LookPath(tok.Literal); err != nil {
+		return "error"
+	}
+	return "command"
+}
+
+// colorize wraps text in the color its kind is themed with. A theme option
+// names one or more styles from colors, such as "bold green"; "none" or
+// an empty value leaves the text as it is.
+func (e *Evaluator) colorize(kind, text string) string {
+	if kind == "" {
+		return text
+	}
+	var codes strings.Builder
+	for _, name := range strings.FieldsFunc(e.option(highlightOptions[kind]), isStyleSeparator) {
+		codes.WriteString(colors[name])
+	}
+	if codes.Len() == 0 {
+		return text
+	}
+	return codes.String() + text + colors["reset"]
+}
+
+// isStyleSeparator splits a theme value such as "bold green" or "bold,green"
+func isStyleSeparator(r rune) bool {
+	return r == ' ' || r == ','
+}
@@ -0,0 +1,88 @@
+package evaluator
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+)
+
+func TestHighlight(t *testing.T) {
+	bin := t.TempDir()
+	if err := os.WriteFile(filepath.Join(bin, "tool"), nil, 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin)
+
+	e, _ := newTestEvaluator(t, t.TempDir())
+	e.vars["count"] = int64(3)
+
+	const (
+		green   = "\033[32m"
+		red     = "\033[31m"
+		yellow  = "\033[33m"
+		blue    = "\033[34m"
+		magenta = "\033[35m"
+		cyan    = "\033[36m"
+		dim     = "\033[2m"
+		reset   = "\033[0m"
+	)
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`ls -l`, green + "ls" + reset + " -l"},
+		{`tool x | grep "a b"`, green + "tool" + reset + " x " + cyan + "|" + reset + " " + green + "grep" + reset + " " + yellow + `"a b"` + reset},
+		{`nosuch x`, red + "nosuch" + reset + " x"},
+		{`count`, blue + "count" + reset},
+		{`print $HOME`, green + "print" + reset + " " + blue + "$" + reset + blue + "HOME" + reset},
+		{`x = len(count)`, "x " + cyan + "=" + reset + " " + green + "len" + reset + "(" + blue + "count" + reset + ")"},
+		{`y = nofunc(1)`, "y " + cyan + "=" + reset + " " + red + "nofunc" + reset + "(1)"},
+		{`if count > 1 { print "big" }`, magenta + "if" + reset + " " + blue + "count" + reset + " " + cyan + ">" + reset + " 1 { " + green + "print" + reset + " " + yellow + `"big"` + reset + " }"},
+		{`print "unfinished`, green + "print" + reset + " " + yellow + `"unfinished` + reset},
+		{`ls # list`, green + "ls" + reset + " " + dim + "# list" + reset},
+		{`exit`, green + "exit" + reset},
+		{`match count {`, magenta + "match" + reset + " " + blue + "count" + reset + " {"},
+		{`match = 1`, "match " + cyan + "=" + reset + " 1"},
+		{`head = 1`, "head " + cyan + "=" + reset + " 1"},
+		{`head -n 1`, green + "head" + reset + " -n 1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got := e.Highlight(tt.input)
+			if got != tt.expected {
+				t.Errorf("wrong highlighting.\nexpected=%q\ngot=%q", tt.expected, got)
+			}
+			if plain := regexp.MustCompile("\033\\[[0-9;]*m").ReplaceAllString(got, ""); plain != tt.input {
+				t.Errorf("highlighting changed the text: %q", plain)
+			}
+		})
+	}
+}
+
+func TestHighlightTheme(t *testing.T) {
+	e, _ := newTestEvaluator(t, t.TempDir())
+
+	if err := evalInput(t, e, `set color_command "bold,white"`); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := e.Highlight("ls"); got != "\033[1m\033[37mls\033[0m" {
+		t.Errorf("theme not applied: %q", got)
+	}
+
+	if err := evalInput(t, e, "set color_command none"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := e.Highlight("ls"); got != "ls" {
+		t.Errorf("expected no color, got %q", got)
+	}
+
+	if err := evalInput(t, e, "set highlight off"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := e.Highlight(`print "x" | nosuch`); got != `print "x" | nosuch` {
+		t.Errorf("expected no highlighting, got %q", got)
+	}
+}
@@ -0,0 +1,136 @@
+package evaluator
+
+import (
+	"fmt"
+	"ravenshell/ast"
+)
+
+// Arrays and strings are indexed from 0, and a negative index counts back
+// from the end: arr[-1] is the last element. Assigning to an element makes
+// a new array or record and stores it back in the variable, so other
+// variables holding the old value don't change.
+
+// normalizeIndex resolves a possibly negative index against length and
+// reports whether it is in range
+func normalizeIndex(idx int64, length int) (int, bool) {
+	if idx < 0 {
+		idx += int64(length)
+	}
+	if idx < 0 || idx >= int64(length) {
+		return 0, false
+	}
+	return int(idx), true
+}
+
+// clampIndex resolves a possibly negative slice bound against length,
+// clamping it to 0..length
+func clampIndex(idx int64, length int) int {
+	if idx < 0 {
+		idx += int64(length)
+	}
+	return int(max(0, min(idx, int64(length))))
+}
+
+// evalSliceExpression handles arr[i:j], arr[:j] and arr[i:] for arrays and
+// strings. Bounds out of range are clamped, as in Python.
+func (e *Evaluator) evalSliceExpression(node *ast.SliceExpression) (Value, error) {
+	left, err := e.evalCommandValue(node.Left)
+	if err != nil {
+		return nil, err
+	}
+
+	var length int
+	switch v := left.(type) {
+	case []Value:
+		length = len(v)
+	case string:
+		length = len([]rune(v))
+	default:
+		return nil, fmt.Errorf("slice operator not supported on %s", typeName(left))
+	}
+
+	bound := func(expr ast.Expression, def int) (int, error) {
+		if expr == nil {
+			return def, nil
+		}
+		val, err := e.evalExpressionValue(expr)
+		if err != nil {
+			return 0, err
+		}
+		idx, err := e.valueToInt64(val)
+		if err != nil {
+			return 0, fmt.Errorf("slice bounds must be integers")
+		}
+		return clampIndex(idx, length), nil
+	}
+	lo, err := bound(node.Start, 0)
+	if err != nil {
+		return nil, err
+	}
+	hi, err := bound(node.End, length)
+	if err != nil {
+		return nil, err
+	}
+	hi = max(lo, hi)
+
+	if s, ok := left.(string); ok {
+		return string([]rune(s)[lo:hi]), nil
+	}
+	return append([]Value{}, left.([]Value)[lo:hi]...), nil
+}
+
+// assign stores val in target: a variable, or an element of one as in
+// arr[0] = val or rows[1]["name"] = val
+func (e *Evaluator) assign(target ast.Expression, val Value) error {
+	switch t := target.(type) {
+	case *ast.Identifier:
+		return e.setVar(t.Value, val, "")
+
+	case *ast.IndexExpression:
+		if root, ok := t.Left.(*ast.Identifier); ok {
+			if _, defined := e.vars[root.Value]; !defined {
+				return fmt.Errorf("undefined variable: %s", root.Value)
+			}
+		}
+		container, err := e.evalExpressionValue(t.Left)
+		if err != nil {
+			return err
+		}
+		index, err := e.evalExpressionValue(t.Index)
+		if err != nil {
+			return err
+		}
+		updated, err := e.withElement(container, index, val)
+		if err != nil {
+			return err
+		}
+		return e.assign(t.Left, updated)
+	}
+	return fmt.Errorf("cannot assign to %s", target.String())
+}
+
+// withElement returns a copy of container with the element at index set
+// to val
+func (e *Evaluator) withElement(container, index, val Value) (Value, error) {
+	switch v := container.(type) {
+	case []Value:
+		idx, err := e.valueToInt64(index)
+		if err != nil {
+			return nil, fmt.Errorf("array index must be an integer")
+		}
+		i, ok := normalizeIndex(idx, len(v))
+		if !ok {
+			return nil, fmt.Errorf("array index out of bounds: %d", idx)
+		}
+		updated := append([]Value{}, v...)
+		updated[i] = val
+		return updated, nil
+	case *Record:
+		updated := v.clone()
+		updated.Set(e.valueToString(index), val)
+		return updated, nil
+	case string:
+		return nil, fmt.Errorf("cannot assign to a character of a string")
+	}
+	return nil, fmt.Errorf("index assignment not supported on %s", typeName(container))
+}
@@ -0,0 +1,94 @@
+package evaluator
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestIndexingAndSlicing(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected Value
+	}{
+		{"arr[1:3]", []Value{int64(20), int64(30)}},
+		{"arr[:2]", []Value{int64(10), int64(20)}},
+		{"arr[2:]", []Value{int64(30), int64(40)}},
+		{"arr[last]", int64(40)},
+		{"arr[-2]", int64(30)},
+		{"arr[-3:-1]", []Value{int64(20), int64(30)}},
+		{"arr[last - 1:]", []Value{int64(30), int64(40)}},
+		{"arr[1:100]", []Value{int64(20), int64(30), int64(40)}},
+		{"arr[3:1]", []Value{}},
+		{"word[1]", "é"},
+		{"word[1:3]", "él"},
+		{"word[last]", "o"},
+		{"word[:]", "héllo"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			e, _ := newTestEvaluator(t, t.TempDir())
+			input := "arr = [10, 20, 30, 40]\nword = \"héllo\"\nlast = -1\nresult = " + tt.input
+			if err := evalInput(t, e, input); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(e.vars["result"], tt.expected) {
+				t.Errorf("wrong result. expected=%#v, got=%#v", tt.expected, e.vars["result"])
+			}
+		})
+	}
+}
+
+func TestElementAssignment(t *testing.T) {
+	e, _ := newTestEvaluator(t, t.TempDir())
+
+	input := `arr = [1, 2, 3]
+copy = arr
+arr[0] = 9
+last = -1
+arr[last] = 7
+cfg = from-json('{"server": {"ports": [80, 443]}}')
+cfg["server"]["ports"][1] = 8443
+cfg["name"] = "web"`
+	if err := evalInput(t, e, input); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if expected := []Value{int64(9), int64(2), int64(7)}; !reflect.DeepEqual(e.vars["arr"], expected) {
+		t.Errorf("wrong array. expected=%v, got=%v", expected, e.vars["arr"])
+	}
+	if expected := []Value{int64(1), int64(2), int64(3)}; !reflect.DeepEqual(e.vars["copy"], expected) {
+		t.Errorf("assignment changed a copy of the array: %v", e.vars["copy"])
+	}
+	out, err := e.builtinToJSON("to-json", []Value{e.vars["cfg"]})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := `{"server":{"ports":[80,8443]},"name":"web"}`; out != expected {
+		t.Errorf("wrong record. expected=%s, got=%s", expected, out)
+	}
+}
+
+func TestIndexErrors(t *testing.T) {
+	tests := []struct {
+		input   string
+		wantErr string
+	}{
+		{"arr = [1]\nx = arr[5]", "array index out of bounds: 5"},
+		{"arr = [1]\narr[1] = 2", "array index out of bounds: 1"},
+		{"s = \"ab\"\ns[0] = \"x\"", "cannot assign to a character of a string"},
+		{"missing[0] = 1", "undefined variable: missing"},
+		{"n = 5\nx = n[0]", "index operator not supported on integer"},
+		{"n = 5\nx = n[1:]", "slice operator not supported on integer"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			_, err := runInput(t, t.TempDir(), tt.input)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
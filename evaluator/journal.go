@@ -0,0 +1,98 @@
+package evaluator
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+)
+
+// maxJournal bounds how many file operations undo can reach back through
+const maxJournal = 100
+
+// fileOp is a file operation recorded so undo can reverse it
+type fileOp struct {
+	command  string      // command that made the change, for messages
+	created  []string    // paths that did not exist before the command
+	moved    [][2]string // renames made by the command, as {from, to}
+	trashed  []trashItem // content the command moved to the trash
+	restored []trashItem // items the command brought back from the trash
+}
+
+// record adds an operation to the journal; operations that changed nothing
+// are ignored
+func (e *Evaluator) record(op fileOp) {
+	if len(op.created) == 0 && len(op.moved) == 0 && len(op.trashed) == 0 && len(op.restored) == 0 {
+		return
+	}
+	e.journal = append(e.journal, op)
+	if len(e.journal) > maxJournal {
+		e.journal = e.journal[len(e.journal)-maxJournal:]
+	}
+}
+
+// execUndo reverses the most recent journaled file operation. Paths the
+// operation created are moved to the trash rather than deleted, so undo
+// never destroys data either. Each step is dropped from the entry once it
+// is done; if one fails, the rest stay in the journal so undo can be run
+// again once the problem is fixed.
+func (e *Evaluator) execUndo() (string, error) {
+	if len(e.journal) == 0 {
+		return "", fmt.Errorf("undo: nothing to undo")
+	}
+	op := &e.journal[len(e.journal)-1]
+
+	var output bytes.Buffer
+	err := e.undoSteps(op, &output)
+	result := output.String()
+	fmt.Fprint(e.stdout, result)
+	if err != nil {
+		return result, err
+	}
+	e.journal = e.journal[:len(e.journal)-1]
+	return result, nil
+}
+
+// undoSteps reverses op's steps in turn, writing what each did to output
+func (e *Evaluator) undoSteps(op *fileOp, output *bytes.Buffer) error {
+	for n := len(op.created); n > 0; n = len(op.created) {
+		path := op.created[n-1]
+		if _, err := os.Lstat(path); err == nil {
+			if _, err := moveToTrash(path); err != nil {
+				return fmt.Errorf("undo: cannot remove '%s': %v", path, unwrapPathError(err))
+			}
+			fmt.Fprintf(output, "undo %s: removed %s\n", op.command, path)
+		}
+		op.created = op.created[:n-1]
+	}
+
+	for n := len(op.moved); n > 0; n = len(op.moved) {
+		from, to := op.moved[n-1][0], op.moved[n-1][1]
+		if _, err := os.Lstat(from); err == nil {
+			return fmt.Errorf("undo: cannot move '%s' back: '%s' already exists", to, from)
+		}
+		if err := movePath(to, from); err != nil {
+			return fmt.Errorf("undo: cannot move '%s' back: %v", to, unwrapPathError(err))
+		}
+		fmt.Fprintf(output, "undo %s: moved %s back to %s\n", op.command, to, from)
+		op.moved = op.moved[:n-1]
+	}
+
+	for len(op.restored) > 0 {
+		item := op.restored[0]
+		if _, err := moveToTrash(item.original); err != nil {
+			return fmt.Errorf("undo: cannot trash '%s': %v", item.original, unwrapPathError(err))
+		}
+		fmt.Fprintf(output, "undo %s: trashed %s\n", op.command, item.original)
+		op.restored = op.restored[1:]
+	}
+
+	for n := len(op.trashed); n > 0; n = len(op.trashed) {
+		item := op.trashed[n-1]
+		if err := restoreFromTrash(item); err != nil {
+			return fmt.Errorf("undo: cannot restore '%s': %v", item.original, unwrapPathError(err))
+		}
+		fmt.Fprintf(output, "undo %s: restored %s\n", op.command, item.original)
+		op.trashed = op.trashed[:n-1]
+	}
+	return nil
+}
@@ -0,0 +1,333 @@
+package evaluator
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"ravenshell/ast"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// JSON maps onto shell values as follows: objects become records (keeping
+// their key order), arrays become arrays, whole numbers become integers,
+// other numbers float64, and null becomes nil.
+
+// decodeJSON reads exactly one JSON value from r
+func decodeJSON(r io.Reader) (Value, error) {
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+	val, err := decodeJSONValue(dec)
+	if err != nil {
+		return nil, jsonSyntaxError(err)
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, fmt.Errorf("unexpected data after the JSON value")
+	}
+	return val, nil
+}
+
+// decodeJSONValue reads the next value from dec token by token, so object
+// keys keep the order they appear in
+func decodeJSONValue(dec *json.Decoder) (Value, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	switch t := tok.(type) {
+	case json.Delim:
+		if t == '[' {
+			arr := []Value{}
+			for dec.More() {
+				elem, err := decodeJSONValue(dec)
+				if err != nil {
+					return nil, err
+				}
+				arr = append(arr, elem)
+			}
+			_, err := dec.Token() // ]
+			return arr, err
+		}
+		rec := NewRecord()
+		for dec.More() {
+			key, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			val, err := decodeJSONValue(dec)
+			if err != nil {
+				return nil, err
+			}
+			rec.Set(key.(string), val)
+		}
+		_, err := dec.Token() // }
+		return rec, err
+	case json.Number:
+		if n, err := t.Int64(); err == nil {
+			return n, nil
+		}
+		return t.Float64()
+	case string, bool:
+		return t, nil
+	}
+	return nil, nil
+}
+
+// jsonSyntaxError rewords decoder errors without Go type names
+func jsonSyntaxError(err error) error {
+	if err == io.EOF || errors.Is(err, io.ErrUnexpectedEOF) {
+		return fmt.Errorf("unexpected end of JSON input")
+	}
+	var syntax *json.SyntaxError
+	if errors.As(err, &syntax) {
+		return fmt.Errorf("invalid JSON at byte %d: %v", syntax.Offset, err)
+	}
+	return err
+}
+
+// encodeJSON writes val as JSON. With a non-empty indent each element goes
+// on its own line, nested by indent per level.
+func (e *Evaluator) encodeJSON(out *bytes.Buffer, val Value, indent string, depth int) error {
+	newline := func(level int) {
+		if indent != "" {
+			out.WriteString("\n" + strings.Repeat(indent, level))
+		}
+	}
+
+	switch v := val.(type) {
+	case nil:
+		out.WriteString("null")
+	case bool:
+		out.WriteString(strconv.FormatBool(v))
+	case int64:
+		out.WriteString(strconv.FormatInt(v, 10))
+	case int:
+		out.WriteString(strconv.Itoa(v))
+	case float64:
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return fmt.Errorf("cannot convert %v to JSON", v)
+		}
+		out.WriteString(strconv.FormatFloat(v, 'g', -1, 64))
+	case string:
+		out.WriteString(jsonString(v))
+	case time.Time:
+		out.WriteString(jsonString(v.Format(time.RFC3339)))
+	case []Value:
+		if len(v) == 0 {
+			out.WriteString("[]")
+			return nil
+		}
+		out.WriteString("[")
+		for i, elem := range v {
+			if i > 0 {
+				out.WriteString(",")
+			}
+			newline(depth + 1)
+			if err := e.encodeJSON(out, elem, indent, depth+1); err != nil {
+				return err
+			}
+		}
+		newline(depth)
+		out.WriteString("]")
+	case *ShellError:
+		return e.encodeJSON(out, v.record(), indent, depth)
+	case *Record:
+		if len(v.Keys()) == 0 {
+			out.WriteString("{}")
+			return nil
+		}
+		out.WriteString("{")
+		for i, key := range v.Keys() {
+			if i > 0 {
+				out.WriteString(",")
+			}
+			newline(depth + 1)
+			out.WriteString(jsonString(key) + ":")
+			if indent != "" {
+				out.WriteString(" ")
+			}
+			field, _ := v.Get(key)
+			if err := e.encodeJSON(out, field, indent, depth+1); err != nil {
+				return err
+			}
+		}
+		newline(depth)
+		out.WriteString("}")
+	default:
+		return fmt.Errorf("cannot convert %T to JSON", val)
+	}
+	return nil
+}
+
+// jsonString quotes s as a JSON string, leaving <, > and & readable
+func jsonString(s string) string {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.Encode(s)
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+// lookupPath follows a path like servers.0.host or servers[0].host into
+// nested records and arrays
+func lookupPath(val Value, path string) (Value, error) {
+	path = strings.NewReplacer("[", ".", "]", "").Replace(path)
+	walked := ""
+	for _, part := range strings.Split(path, ".") {
+		if part == "" {
+			continue
+		}
+		switch v := val.(type) {
+		case *Record:
+			field, ok := v.Get(part)
+			if !ok {
+				return nil, fmt.Errorf("path '%s': %s has no field '%s'", path, describePath(walked), part)
+			}
+			val = field
+		case []Value:
+			i, err := strconv.Atoi(part)
+			if err != nil || i < 0 || i >= len(v) {
+				return nil, fmt.Errorf("path '%s': %s has no element '%s'", path, describePath(walked), part)
+			}
+			val = v[i]
+		default:
+			return nil, fmt.Errorf("path '%s': %s is not an object or array", path, describePath(walked))
+		}
+		if walked != "" {
+			walked += "."
+		}
+		walked += part
+	}
+	return val, nil
+}
+
+// describePath names the part of a path walked so far in error messages
+func describePath(walked string) string {
+	if walked == "" {
+		return "the top-level value"
+	}
+	return "'" + walked + "'"
+}
+
+// readJSON decodes the file named in args, or stdin, applying --path
+func (e *Evaluator) readJSON(flags flagSet, args []string) (Value, error) {
+	if len(args) > 1 {
+		return nil, builtinSpecs[ast.CMD_FROMJSON].usageError("too many files")
+	}
+
+	var val Value
+	err := e.eachInput("from-json", args, func(in textInput) error {
+		v, err := decodeJSON(in.reader)
+		if err != nil {
+			return fmt.Errorf("from-json: %v", err)
+		}
+		val = v
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if path := flags.value("path", ""); path != "" {
+		if val, err = lookupPath(val, path); err != nil {
+			return nil, fmt.Errorf("from-json: %v", err)
+		}
+	}
+	return val, nil
+}
+
+// jsonIndent returns the indent to-json's options ask for: none by default,
+// two spaces for --pretty and N spaces for --indent=N
+func jsonIndent(flags flagSet) (string, error) {
+	n, err := flags.intValue(builtinSpecs[ast.CMD_TOJSON], "indent", 0)
+	if err != nil {
+		return "", err
+	}
+	if n == 0 && flags.has("pretty") {
+		n = 2
+	}
+	if n < 0 || n > 16 {
+		return "", builtinSpecs[ast.CMD_TOJSON].usageError("indent must be between 0 and 16")
+	}
+	return strings.Repeat(" ", int(n)), nil
+}
+
+func (e *Evaluator) execFromJSON(flags flagSet, args []string) (string, error) {
+	val, err := e.readJSON(flags, args)
+	if err != nil {
+		return "", err
+	}
+	return e.printValue(val), nil
+}
+
+// execToJSON reports to-json used without a value to convert
+func (e *Evaluator) execToJSON() (string, error) {
+	return "", builtinSpecs[ast.CMD_TOJSON].usageError("pipe a value in, e.g. data | to-json, or call to-json(data)")
+}
+
+// pipeToJSON converts the value produced by left to JSON text. Text from an
+// ordinary command becomes a JSON string.
+func (e *Evaluator) pipeToJSON(left ast.Expression, stage *ast.Command) (string, error) {
+	spec := builtinSpecs[ast.CMD_TOJSON]
+	flags, args, err := e.parseCommandArgs(spec, stage.Arguments)
+	if err != nil {
+		return "", err
+	}
+	if len(args) > 0 {
+		return "", spec.usageError("unexpected argument '%s'", args[0])
+	}
+	indent, err := jsonIndent(flags)
+	if err != nil {
+		return "", err
+	}
+
+	val, err := e.pipedValue(left)
+	if err != nil {
+		return "", err
+	}
+	var out bytes.Buffer
+	if err := e.encodeJSON(&out, val, indent, 0); err != nil {
+		return "", fmt.Errorf("to-json: %v", err)
+	}
+	out.WriteString("\n")
+	result := out.String()
+	fmt.Fprint(e.stdout, result)
+	return result, nil
+}
+
+// builtinFromJSON implements from-json(text) and from-json(text, path)
+func (e *Evaluator) builtinFromJSON(name string, args []Value) (Value, error) {
+	val, err := decodeJSON(strings.NewReader(e.valueToString(args[0])))
+	if err != nil {
+		return nil, fmt.Errorf("from-json(): %v", err)
+	}
+	if len(args) == 2 {
+		if val, err = lookupPath(val, e.valueToString(args[1])); err != nil {
+			return nil, fmt.Errorf("from-json(): %v", err)
+		}
+	}
+	return val, nil
+}
+
+// builtinToJSON implements to-json(value) and to-json(value, indent)
+func (e *Evaluator) builtinToJSON(name string, args []Value) (Value, error) {
+	indent := ""
+	if len(args) == 2 {
+		width, err := e.valueToInt64(args[1])
+		if err != nil || width < 0 || width > 16 {
+			return nil, fmt.Errorf("to-json() indent must be a number between 0 and 16")
+		}
+		indent = strings.Repeat(" ", int(width))
+	}
+
+	var out bytes.Buffer
+	if err := e.encodeJSON(&out, args[0], indent, 0); err != nil {
+		return nil, fmt.Errorf("to-json(): %v", err)
+	}
+	return out.String(), nil
+}
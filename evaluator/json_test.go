@@ -0,0 +1,126 @@
+package evaluator
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testConfig = `{"name": "app", "servers": [{"host": "a.example", "port": 80}, {"host": "b.example", "port": 8080}], "debug": false, "ratio": 0.5, "none": null}`
+
+func TestJSONCommands(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "config"), []byte(testConfig), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "settings"), []byte(`{"db": {"host": "localhost"}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"from-json --path=servers.1.host config", "b.example\n"},
+		{"from-json -p db.host settings", "localhost\n"},
+		{"from-json --path db.host settings", "localhost\n"},
+		{`from-json -p "servers[0].port" config`, "80\n"},
+		{`show config | from-json -p servers | where port > 100 | select host`, "host\nb.example\n"},
+		{"from-json -p servers config | to-json", `[{"host":"a.example","port":80},{"host":"b.example","port":8080}]` + "\n"},
+		{`from-json -p "servers.0" config | to-json --pretty`, "{\n  \"host\": \"a.example\",\n  \"port\": 80\n}\n"},
+		{"from-json config | to-json -i 1 | head -n 3", "{\n \"name\": \"app\",\n \"servers\": [\n"},
+		{"from-json config | to-json | grep -o ratio", ""},
+		{"show config | to-json | from-json | from-json -p name", "app\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := runInput(t, dir, tt.input)
+			if tt.expected == "" {
+				if err == nil {
+					t.Fatalf("expected an error, got output %q", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("wrong output.\nexpected=%q\ngot=%q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestJSONRoundTrip(t *testing.T) {
+	val, err := decodeJSON(strings.NewReader(testConfig))
+	if err != nil {
+		t.Fatalf("decode returned error: %v", err)
+	}
+	rec, ok := val.(*Record)
+	if !ok {
+		t.Fatalf("expected a record, got %T", val)
+	}
+	if got := strings.Join(rec.Keys(), " "); got != "name servers debug ratio none" {
+		t.Errorf("keys out of order: %s", got)
+	}
+	if port, _ := lookupPath(val, "servers.1.port"); port != int64(8080) {
+		t.Errorf("expected int64 8080, got %#v", port)
+	}
+	if ratio, _ := rec.Get("ratio"); ratio != 0.5 {
+		t.Errorf("expected float 0.5, got %#v", ratio)
+	}
+
+	e, _ := newTestEvaluator(t, t.TempDir())
+	var out bytes.Buffer
+	if err := e.encodeJSON(&out, val, "", 0); err != nil {
+		t.Fatalf("encode returned error: %v", err)
+	}
+	expected := strings.ReplaceAll(strings.ReplaceAll(testConfig, ": ", ":"), ", ", ",")
+	if out.String() != expected {
+		t.Errorf("round trip changed the JSON.\nexpected=%s\ngot=%s", expected, out.String())
+	}
+}
+
+func TestJSONFunctions(t *testing.T) {
+	e, out := newTestEvaluator(t, t.TempDir())
+
+	input := `cfg = from-json('{"a": [1, 2], "b": "x<y"}')
+print cfg["a"][1]
+print from-json('{"a": [1, 2]}', "a[0]")
+print to-json(cfg)
+print to-json(cfg["a"], 2)`
+	if err := evalInput(t, e, input); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := "2\n1\n{\"a\":[1,2],\"b\":\"x<y\"}\n[\n  1,\n  2\n]\n"
+	if out.String() != expected {
+		t.Errorf("wrong output.\nexpected=%q\ngot=%q", expected, out.String())
+	}
+}
+
+func TestJSONErrors(t *testing.T) {
+	tests := []struct {
+		input   string
+		wantErr string
+	}{
+		{`x = from-json('{"a": 1')`, "unexpected end of JSON input"},
+		{`x = from-json('{"a": 1} 2')`, "unexpected data after the JSON value"},
+		{`x = from-json('{"a": 1}', "b")`, "path 'b': the top-level value has no field 'b'"},
+		{`x = from-json('{"a": [1]}', "a.3")`, "path 'a.3': 'a' has no element '3'"},
+		{`x = from-json('{"a": 1}', "a.b")`, "path 'a.b': 'a' is not an object or array"},
+		{"to-json", "to-json: pipe a value in"},
+		{`x = to-json(1, 2, 3)`, "to-json() takes 1 or 2 arguments"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			_, err := runInput(t, t.TempDir(), tt.input)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,335 @@
+package evaluator
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"golang.org/x/term"
+)
+
+// listOptions holds the flags accepted by ls
+type listOptions struct {
+	long      bool // -l: mode, owner, size, mtime and link target
+	all       bool // -a: include entries starting with .
+	byTime    bool // -t: newest first
+	bySize    bool // -S: largest first
+	reverse   bool // -r: reverse the sort order
+	human     bool // -h: sizes like 1.5K, 20M
+	recursive bool // -R: descend into subdirectories
+}
+
+// listEntry is a single file shown by ls
+type listEntry struct {
+	name   string
+	info   os.FileInfo
+	target string // symlink target, only filled in for long listings
+}
+
+func (e *Evaluator) execList(flags flagSet, paths []string) (string, error) {
+	opts := newListOptions(flags)
+	if len(paths) == 0 {
+		paths = []string{"."}
+	}
+
+	var output bytes.Buffer
+	var firstErr error
+	var files []listEntry
+	var dirs []string
+
+	// Like ls, files named on the command line are listed first, then each directory
+	for _, p := range paths {
+		info, err := os.Lstat(e.resolvePath(p))
+		if err != nil {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("ls: %v", err)
+			}
+			continue
+		}
+		if info.IsDir() {
+			dirs = append(dirs, p)
+			continue
+		}
+		files = append(files, e.newListEntry(p, e.resolvePath(p), info, opts))
+	}
+
+	if len(files) > 0 {
+		e.writeListEntries(&output, files, opts)
+	}
+
+	showHeaders := len(paths) > 1 || opts.recursive
+	for i, dir := range dirs {
+		if i > 0 || len(files) > 0 {
+			output.WriteString("\n")
+		}
+		if err := e.listDir(&output, dir, opts, showHeaders); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	result := output.String()
+	fmt.Fprint(e.stdout, result)
+	return result, firstErr
+}
+
+// newListOptions reads ls options from the parsed flags
+func newListOptions(flags flagSet) listOptions {
+	return listOptions{
+		long:      flags.has("l"),
+		all:       flags.has("all"),
+		byTime:    flags.has("t"),
+		bySize:    flags.has("S"),
+		reverse:   flags.has("reverse"),
+		human:     flags.has("human-readable"),
+		recursive: flags.has("recursive"),
+	}
+}
+
+// listDir writes the contents of a single directory, recursing if -R was given
+func (e *Evaluator) listDir(out *bytes.Buffer, dir string, opts listOptions, header bool) error {
+	path := e.resolvePath(dir)
+	dirEntries, err := os.ReadDir(path)
+	if err != nil {
+		return fmt.Errorf("ls: %v", err)
+	}
+
+	var entries []listEntry
+	for _, de := range dirEntries {
+		if !opts.all && strings.HasPrefix(de.Name(), ".") {
+			continue
+		}
+		info, err := de.Info()
+		if err != nil {
+			// Entry vanished between ReadDir and Info
+			continue
+		}
+		entries = append(entries, e.newListEntry(de.Name(), filepath.Join(path, de.Name()), info, opts))
+	}
+
+	if header {
+		out.WriteString(dir + ":\n")
+	}
+	e.writeListEntries(out, entries, opts)
+
+	if !opts.recursive {
+		return nil
+	}
+
+	var firstErr error
+	for _, entry := range entries {
+		if !entry.info.IsDir() {
+			continue
+		}
+		out.WriteString("\n")
+		if err := e.listDir(out, filepath.Join(dir, entry.name), opts, true); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// newListEntry builds a listEntry, reading the link target for long listings
+func (e *Evaluator) newListEntry(name, path string, info os.FileInfo, opts listOptions) listEntry {
+	entry := listEntry{name: name, info: info}
+	if opts.long && info.Mode()&os.ModeSymlink != 0 {
+		if target, err := os.Readlink(path); err == nil {
+			entry.target = target
+		}
+	}
+	return entry
+}
+
+// writeListEntries sorts entries and writes them in long, column or line format
+func (e *Evaluator) writeListEntries(out *bytes.Buffer, entries []listEntry, opts listOptions) {
+	sortListEntries(entries, opts)
+
+	if opts.long {
+		writeLongListing(out, entries, opts)
+		return
+	}
+
+	names := make([]string, len(entries))
+	for i, entry := range entries {
+		names[i] = entry.name
+		if entry.info.IsDir() {
+			names[i] += "/"
+		}
+	}
+
+	// Columns only make sense for a person looking at a terminal; pipes and
+	// redirections get one name per line so they stay easy to process
+	if width, ok := e.terminalWidth(); ok {
+		writeColumns(out, names, width)
+		return
+	}
+	for _, name := range names {
+		out.WriteString(name + "\n")
+	}
+}
+
+// sortListEntries orders entries by name, mtime or size
+func sortListEntries(entries []listEntry, opts listOptions) {
+	less := func(i, j int) bool { return entries[i].name < entries[j].name }
+	switch {
+	case opts.bySize:
+		less = func(i, j int) bool {
+			if entries[i].info.Size() != entries[j].info.Size() {
+				return entries[i].info.Size() > entries[j].info.Size()
+			}
+			return entries[i].name < entries[j].name
+		}
+	case opts.byTime:
+		less = func(i, j int) bool {
+			ti, tj := entries[i].info.ModTime(), entries[j].info.ModTime()
+			if !ti.Equal(tj) {
+				return ti.After(tj)
+			}
+			return entries[i].name < entries[j].name
+		}
+	}
+
+	if opts.reverse {
+		sort.SliceStable(entries, func(i, j int) bool { return less(j, i) })
+	} else {
+		sort.SliceStable(entries, less)
+	}
+}
+
+// writeLongListing writes one aligned line per entry: mode, owner, group, size, mtime, name
+func writeLongListing(out *bytes.Buffer, entries []listEntry, opts listOptions) {
+	rows := make([][5]string, len(entries))
+	var widths [5]int
+
+	for i, entry := range entries {
+		owner, group := fileOwner(entry.info)
+		size := fmt.Sprintf("%d", entry.info.Size())
+		if opts.human {
+			size = humanSize(entry.info.Size())
+		}
+		rows[i] = [5]string{formatMode(entry.info.Mode()), owner, group, size, formatModTime(entry.info.ModTime())}
+		for col, field := range rows[i] {
+			if len(field) > widths[col] {
+				widths[col] = len(field)
+			}
+		}
+	}
+
+	for i, entry := range entries {
+		row := rows[i]
+		fmt.Fprintf(out, "%s %-*s %-*s %*s %s %s",
+			row[0], widths[1], row[1], widths[2], row[2], widths[3], row[3], row[4], entry.name)
+		if entry.target != "" {
+			out.WriteString(" -> " + entry.target)
+		} else if entry.info.IsDir() {
+			out.WriteString("/")
+		}
+		out.WriteString("\n")
+	}
+}
+
+// writeColumns lays names out top-to-bottom in as many columns as fit in width
+func writeColumns(out *bytes.Buffer, names []string, width int) {
+	if len(names) == 0 {
+		return
+	}
+
+	const gap = 2
+	rows, colWidths := len(names), []int{0}
+	for cols := len(names); cols > 1; cols-- {
+		r := (len(names) + cols - 1) / cols
+		widths := make([]int, (len(names)+r-1)/r)
+		total := 0
+		for i, name := range names {
+			if len(name)+gap > widths[i/r] {
+				widths[i/r] = len(name) + gap
+			}
+		}
+		for _, w := range widths {
+			total += w
+		}
+		if total <= width {
+			rows, colWidths = r, widths
+			break
+		}
+	}
+
+	for row := 0; row < rows; row++ {
+		var line strings.Builder
+		for col := range colWidths {
+			i := col*rows + row
+			if i >= len(names) {
+				break
+			}
+			if col == len(colWidths)-1 || i+rows >= len(names) {
+				line.WriteString(names[i])
+			} else {
+				line.WriteString(fmt.Sprintf("%-*s", colWidths[col], names[i]))
+			}
+		}
+		out.WriteString(line.String() + "\n")
+	}
+}
+
+// terminalWidth returns the width of stdout when it is a terminal
+func (e *Evaluator) terminalWidth() (int, bool) {
+	file, ok := e.stdout.(*os.File)
+	if !ok || !term.IsTerminal(int(file.Fd())) {
+		return 0, false
+	}
+	width, _, err := term.GetSize(int(file.Fd()))
+	if err != nil || width <= 0 {
+		return 80, true
+	}
+	return width, true
+}
+
+// formatMode renders a file mode the way ls does: drwxr-xr-x, lrwxrwxrwx
+func formatMode(mode os.FileMode) string {
+	kind := "-"
+	switch {
+	case mode.IsDir():
+		kind = "d"
+	case mode&os.ModeSymlink != 0:
+		kind = "l"
+	case mode&os.ModeNamedPipe != 0:
+		kind = "p"
+	case mode&os.ModeSocket != 0:
+		kind = "s"
+	case mode&os.ModeCharDevice != 0:
+		kind = "c"
+	case mode&os.ModeDevice != 0:
+		kind = "b"
+	}
+	return kind + mode.Perm().String()[1:]
+}
+
+// formatModTime shows the time of day for recent files and the year for older ones
+func formatModTime(t time.Time) string {
+	if time.Since(t) > 180*24*time.Hour || t.After(time.Now().Add(time.Hour)) {
+		return t.Format("Jan _2  2006")
+	}
+	return t.Format("Jan _2 15:04")
+}
+
+// humanSize formats a byte count using K, M, G, ... suffixes
+func humanSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d", size)
+	}
+	value := float64(size)
+	suffixes := "KMGTPE"
+	i := -1
+	for value >= unit && i < len(suffixes)-1 {
+		value /= unit
+		i++
+	}
+	if value < 10 {
+		return fmt.Sprintf("%.1f%c", value, suffixes[i])
+	}
+	return fmt.Sprintf("%.0f%c", value, suffixes[i])
+}
@@ -0,0 +1,97 @@
+package evaluator
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestListHidesDotfilesUnlessAll(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"a.txt", ".hidden"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Mkdir(filepath.Join(dir, "sub"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"default", "ls", "a.txt\nsub/\n"},
+		{"all", "ls -a", ".hidden\na.txt\nsub/\n"},
+		{"long option", "ls --all", ".hidden\na.txt\nsub/\n"},
+		{"reverse", "ls -r", "sub/\na.txt\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := runInput(t, dir, tt.input)
+			if err != nil {
+				t.Fatalf("%s returned error: %v", tt.input, err)
+			}
+			if result != tt.expected {
+				t.Errorf("wrong listing. expected=%q, got=%q", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestListLongShowsSymlinkTarget(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "target"), []byte("data"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("target", filepath.Join(dir, "link")); err != nil {
+		t.Skipf("symlinks not supported: %v", err)
+	}
+
+	result, err := runInput(t, dir, "ls -l")
+	if err != nil {
+		t.Fatalf("ls -l returned error: %v", err)
+	}
+	if !strings.Contains(result, "link -> target") {
+		t.Errorf("long listing missing symlink target. got=%q", result)
+	}
+}
+
+func TestListInvalidOption(t *testing.T) {
+	if _, err := runInput(t, t.TempDir(), "ls -z"); err == nil {
+		t.Error("expected error for invalid option")
+	}
+}
+
+func TestHumanSize(t *testing.T) {
+	tests := []struct {
+		size     int64
+		expected string
+	}{
+		{0, "0"},
+		{1023, "1023"},
+		{1024, "1.0K"},
+		{1536, "1.5K"},
+		{20 * 1024 * 1024, "20M"},
+	}
+
+	for _, tt := range tests {
+		if got := humanSize(tt.size); got != tt.expected {
+			t.Errorf("humanSize(%d) wrong. expected=%q, got=%q", tt.size, tt.expected, got)
+		}
+	}
+}
+
+func TestWriteColumns(t *testing.T) {
+	var out bytes.Buffer
+	writeColumns(&out, []string{"a", "bb", "ccc", "d"}, 12)
+
+	expected := "a   ccc\nbb  d\n"
+	if out.String() != expected {
+		t.Errorf("wrong column layout. expected=%q, got=%q", expected, out.String())
+	}
+}
@@ -0,0 +1,105 @@
+package evaluator
+
+import (
+	"ravenshell/ast"
+	"regexp"
+	"strings"
+)
+
+// evalMatchStatement runs the first arm with a pattern matching the value.
+// When no arm matches, nothing runs.
+func (e *Evaluator) evalMatchStatement(stmt *ast.MatchStatement) error {
+	value, err := e.evalExpressionValue(stmt.Value)
+	if err != nil {
+		return err
+	}
+
+	for _, arm := range stmt.Arms {
+		for _, pattern := range arm.Patterns {
+			matched, err := e.matchPattern(pattern, value)
+			if err != nil {
+				return err
+			}
+			if matched {
+				return e.evalBlockStatement(arm.Body)
+			}
+		}
+	}
+	return nil
+}
+
+// matchPattern reports whether value matches one pattern. A regex sets
+// captures the way =~ does; a glob or a plain value must match the whole
+// value, and numbers compare as numbers, as with ==.
+func (e *Evaluator) matchPattern(pattern *ast.MatchPattern, value Value) (bool, error) {
+	if pattern.Value == nil {
+		return true, nil
+	}
+
+	want, err := e.evalExpressionValue(pattern.Value)
+	if err != nil {
+		return false, err
+	}
+
+	if pattern.Regex {
+		matched, err := e.evalMatch(value, want)
+		if err != nil {
+			return false, err
+		}
+		return matched.(bool), nil
+	}
+	if lit, ok := pattern.Value.(*ast.StringLiteral); ok && isGlob(lit.Value) {
+		re, err := e.compilePattern(globToRegexp(lit.Value))
+		if err != nil {
+			return false, err
+		}
+		return re.MatchString(e.valueToString(value)), nil
+	}
+	return e.valuesEqual(value, want), nil
+}
+
+// valuesEqual compares two values the way == does
+func (e *Evaluator) valuesEqual(left, right Value) bool {
+	leftNum, leftErr := e.valueToInt64(left)
+	rightNum, rightErr := e.valueToInt64(right)
+	if leftErr == nil && rightErr == nil {
+		return leftNum == rightNum
+	}
+	return e.valueToString(left) == e.valueToString(right)
+}
+
+// isGlob reports whether a pattern uses glob wildcards
+func isGlob(pattern string) bool {
+	return strings.ContainsAny(pattern, "*?[")
+}
+
+// globToRegexp translates a glob into an anchored regular expression.
+// Unlike file name globs, * also matches /, as in a shell's case.
+func globToRegexp(glob string) string {
+	var re strings.Builder
+	re.WriteString(`(?s)^`)
+	for i := 0; i < len(glob); i++ {
+		switch c := glob[i]; c {
+		case '*':
+			re.WriteString(`.*`)
+		case '?':
+			re.WriteString(`.`)
+		case '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end <= 0 {
+				re.WriteString(`\[`)
+				continue
+			}
+			class := glob[i+1 : i+1+end]
+			if class[0] == '!' {
+				class = "^" + class[1:]
+			}
+			re.WriteString("[" + class + "]")
+			i += end + 1
+		default:
+			re.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	re.WriteString(`$`)
+	return re.String()
+}
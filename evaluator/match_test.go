@@ -0,0 +1,128 @@
+package evaluator
+
+import (
+	"testing"
+)
+
+func TestMatchStatement(t *testing.T) {
+	input := `for f in ["a.txt", "notes.md", "v12", 2, "feature/login", "c", "b"] {
+    match f {
+        "a.txt" => { print f "exact" }
+        "*.md" | "*.rst" => { print f "doc" }
+        =~ "^v(\d+)" => { print f "version" captures[1] }
+        1 | 2 => { print f "number" }
+        "feature/*" | "[!ab]" => { print f "glob" }
+        _ => { print f "other" }
+    }
+}`
+	expected := "a.txt exact\nnotes.md doc\nv12 version 12\n2 number\nfeature/login glob\nc glob\nb other\n"
+
+	got, err := runInput(t, t.TempDir(), input)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != expected {
+		t.Errorf("wrong output.\nexpected=%q\ngot=%q", expected, got)
+	}
+}
+
+func TestMatchFirstArmWins(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`match "10" { 10 => { print "number" }, "10" => { print "text" } }`, "number\n"},
+		{`match "x" { "a" => { print "a" } }
+print "none"`, "none\n"},
+		{`n = 3
+match n { n => { print "same" }, _ => { print "other" } }`, "same\n"},
+		{`match "*" { "[*]" => { print "star" } }`, "star\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := runInput(t, t.TempDir(), tt.input)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("wrong output.\nexpected=%q\ngot=%q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestMatchAsVariable(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"match = \"found\"\nprint match", "found\n"},
+		{"switch = 2\nprint switch + 1", "3\n"},
+		{"match = 1\nmatch match { 1 => { print \"one\" } }", "one\n"},
+		{"for match in [\"a\", \"b\"] { print match }", "a\nb\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := runInput(t, t.TempDir(), tt.input)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("wrong output.\nexpected=%q\ngot=%q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestElseIf(t *testing.T) {
+	for _, tt := range []struct {
+		x        string
+		expected string
+	}{{"12", "large\n"}, {"7", "medium\n"}, {"1", "small\n"}} {
+		input := "x = " + tt.x + `
+if x > 10 {
+    print "large"
+} else if x > 5 {
+    print "medium"
+} else {
+    print "small"
+}`
+		got, err := runInput(t, t.TempDir(), input)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got != tt.expected {
+			t.Errorf("x = %s: expected=%q, got=%q", tt.x, tt.expected, got)
+		}
+	}
+}
+
+func TestGlobToRegexp(t *testing.T) {
+	tests := []struct {
+		glob, text string
+		want       bool
+	}{
+		{"*.txt", "a.txt", true},
+		{"*.txt", "a.txt.bak", false},
+		{"release*", "release/1.0", true},
+		{"?b", "ab", true},
+		{"?b", "abb", false},
+		{"[abc]x", "bx", true},
+		{"[!abc]x", "bx", false},
+		{"a[", "a[", true},
+		{"a.c", "abc", false},
+	}
+
+	for _, tt := range tests {
+		e, _ := newTestEvaluator(t, t.TempDir())
+		re, err := e.compilePattern(globToRegexp(tt.glob))
+		if err != nil {
+			t.Fatalf("%q: %v", tt.glob, err)
+		}
+		if got := re.MatchString(tt.text); got != tt.want {
+			t.Errorf("%q against %q: expected %v, got %v", tt.glob, tt.text, tt.want, got)
+		}
+	}
+}
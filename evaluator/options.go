@@ -0,0 +1,141 @@
+package evaluator
+
+import (
+	"bytes"
+	"fmt"
+	"ravenshell/ast"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// shellOption describes a setting changed with the set command
+type shellOption struct {
+	def  string // value used until the option is set
+	help string // description shown by set with no arguments
+}
+
+// shellOptions lists every option understood by set
+var shellOptions = map[string]shellOption{
+	"color_command": {
+		def:  "green",
+		help: "highlighting color of commands, programs and functions",
+	},
+	"color_comment": {
+		def:  "dim",
+		help: "highlighting color of comments",
+	},
+	"color_error": {
+		def:  "red",
+		help: "highlighting color of unknown commands and bad input",
+	},
+	"color_keyword": {
+		def:  "magenta",
+		help: "highlighting color of keywords such as if and for",
+	},
+	"color_operator": {
+		def:  "cyan",
+		help: "highlighting color of operators, pipes and redirections",
+	},
+	"color_string": {
+		def:  "yellow",
+		help: "highlighting color of quoted strings",
+	},
+	"color_variable": {
+		def:  "blue",
+		help: "highlighting color of variables and $NAME",
+	},
+	"edit_mode": {
+		def:  "emacs",
+		help: "keys for editing the line in the REPL: emacs or vi",
+	},
+	"errexit": {
+		def:  "off",
+		help: "treat a program that exits with a non-zero status as an error, like set -e",
+	},
+	"highlight": {
+		def:  "on",
+		help: "color the line being typed; the color_ options set the theme",
+	},
+	"rm_confirm_threshold": {
+		def:  "0",
+		help: "ask before rm -r removes more than this many entries (0 never asks)",
+	},
+	"rm_protected": {
+		def:  "",
+		help: "colon-separated paths that rm refuses to remove, in addition to / and ~",
+	},
+	"vi_insert_indicator": {
+		def:  "[I] ",
+		help: "shown before the prompt in vi insert mode; takes the escapes of PROMPT",
+	},
+	"vi_normal_indicator": {
+		def:  "[N] ",
+		help: "shown before the prompt in vi normal mode; takes the escapes of PROMPT",
+	},
+}
+
+// option returns the current value of a shell option
+func (e *Evaluator) option(name string) string {
+	if val, ok := e.options[name]; ok {
+		return val
+	}
+	return shellOptions[name].def
+}
+
+// intOption returns a shell option as an integer, treating bad values as 0
+func (e *Evaluator) intOption(name string) int64 {
+	n, err := strconv.ParseInt(e.option(name), 10, 64)
+	if err != nil {
+		return 0
+	}
+	return n
+}
+
+// boolOption reports whether a shell option is turned on
+func (e *Evaluator) boolOption(name string) bool {
+	switch strings.ToLower(e.option(name)) {
+	case "on", "true", "yes", "1":
+		return true
+	}
+	return false
+}
+
+// execSet lists, sets or resets shell options
+func (e *Evaluator) execSet(flags flagSet, args []string) (string, error) {
+	if len(args) == 0 {
+		names := make([]string, 0, len(shellOptions))
+		for name := range shellOptions {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		var output bytes.Buffer
+		for _, name := range names {
+			fmt.Fprintf(&output, "%s = %q  # %s\n", name, e.option(name), shellOptions[name].help)
+		}
+		result := output.String()
+		fmt.Fprint(e.stdout, result)
+		return result, nil
+	}
+
+	name := args[0]
+	if _, ok := shellOptions[name]; !ok {
+		return "", fmt.Errorf("set: unknown option '%s'", name)
+	}
+
+	switch {
+	case flags.has("unset"):
+		if len(args) > 1 {
+			return "", builtinSpecs[ast.CMD_SET].usageError("--unset takes only an option name")
+		}
+		delete(e.options, name)
+	case len(args) == 1:
+		e.options[name] = "on"
+	case len(args) == 2:
+		e.options[name] = args[1]
+	default:
+		return "", builtinSpecs[ast.CMD_SET].usageError("too many arguments")
+	}
+	return "", nil
+}
@@ -0,0 +1,10 @@
+//go:build !unix
+
+package evaluator
+
+import "os"
+
+// fileOwner returns placeholders on platforms without Unix ownership
+func fileOwner(info os.FileInfo) (string, string) {
+	return "-", "-"
+}
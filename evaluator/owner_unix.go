@@ -0,0 +1,32 @@
+//go:build unix
+
+package evaluator
+
+import (
+	"os"
+	"os/user"
+	"strconv"
+	"syscall"
+)
+
+// fileOwner returns the user and group names that own a file,
+// falling back to numeric ids when they cannot be looked up
+func fileOwner(info os.FileInfo) (string, string) {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return "-", "-"
+	}
+
+	uid := strconv.FormatUint(uint64(stat.Uid), 10)
+	gid := strconv.FormatUint(uint64(stat.Gid), 10)
+
+	owner := uid
+	if u, err := user.LookupId(uid); err == nil {
+		owner = u.Username
+	}
+	group := gid
+	if g, err := user.LookupGroupId(gid); err == nil {
+		group = g.Name
+	}
+	return owner, group
+}
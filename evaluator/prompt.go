@@ -0,0 +1,201 @@
+package evaluator
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// defaultPrompt is shown when PROMPT isn't set
+const defaultPrompt = "# "
+
+// colors are the styles a prompt can name with \[name], and that the
+// highlighting theme options are written in
+var colors = map[string]string{
+	"reset":   "\033[0m",
+	"bold":    "\033[1m",
+	"dim":     "\033[2m",
+	"black":   "\033[30m",
+	"red":     "\033[31m",
+	"green":   "\033[32m",
+	"yellow":  "\033[33m",
+	"blue":    "\033[34m",
+	"magenta": "\033[35m",
+	"cyan":    "\033[36m",
+	"white":   "\033[37m",
+}
+
+// Prompt returns the interactive prompt and the prompt shown at the right
+// edge of the terminal, expanded from the PROMPT and RPROMPT variables.
+// The REPL calls it before reading each line, so the escapes in them show
+// the state at that moment.
+func (e *Evaluator) Prompt() (left, right string) {
+	left = defaultPrompt
+	if val, ok := e.vars["PROMPT"]; ok {
+		left = e.expandPrompt(e.valueToString(val))
+	}
+	if val, ok := e.vars["RPROMPT"]; ok {
+		right = e.expandPrompt(e.valueToString(val))
+	}
+	return left, right
+}
+
+// EditMode returns the keys the edit_mode option selects for the REPL's
+// line editor: "vi", or "emacs" for any other value
+func (e *Evaluator) EditMode() string {
+	if strings.ToLower(e.option("edit_mode")) == "vi" {
+		return "vi"
+	}
+	return "emacs"
+}
+
+// ModeIndicators returns what vi mode shows before the prompt in insert
+// and normal mode, expanded like PROMPT
+func (e *Evaluator) ModeIndicators() (insert, normal string) {
+	return e.expandPrompt(e.option("vi_insert_indicator")), e.expandPrompt(e.option("vi_normal_indicator"))
+}
+
+// SetStatus records the exit status of a line the REPL ran, so the status
+// variable and the \? prompt escape show it
+func (e *Evaluator) SetStatus(status int64) {
+	e.vars["status"] = status
+}
+
+// expandPrompt replaces the escapes in a prompt template:
+//
+//	\w  current directory, with the home directory shown as ~
+//	\W  last element of the current directory
+//	\u  user name
+//	\h  host name up to the first dot
+//	\?  the status variable
+//	\t  time as 15:04:05, \A as 15:04
+//	\b  git branch, or the short commit when HEAD is detached
+//	\e  escape character, for raw ANSI sequences
+//	\[name]  a color or style from colors
+//	\\  a backslash
+//
+// Anything else is kept as written.
+func (e *Evaluator) expandPrompt(template string) string {
+	var out strings.Builder
+	for i := 0; i < len(template); i++ {
+		if template[i] != '\\' || i+1 == len(template) {
+			out.WriteByte(template[i])
+			continue
+		}
+		i++
+		switch c := template[i]; c {
+		case 'w':
+			out.WriteString(e.shortCwd())
+		case 'W':
+			out.WriteString(filepath.Base(e.cwd))
+		case 'u':
+			out.WriteString(userName())
+		case 'h':
+			host, _ := os.Hostname()
+			host, _, _ = strings.Cut(host, ".")
+			out.WriteString(host)
+		case '?':
+			status, ok := e.vars["status"]
+			if !ok {
+				status = int64(0)
+			}
+			out.WriteString(e.valueToString(status))
+		case 't':
+			out.WriteString(time.Now().Format("15:04:05"))
+		case 'A':
+			out.WriteString(time.Now().Format("15:04"))
+		case 'b':
+			out.WriteString(gitBranch(e.cwd))
+		case 'e':
+			out.WriteByte('\033')
+		case '\\':
+			out.WriteByte('\\')
+		case '[':
+			name, _, closed := strings.Cut(template[i+1:], "]")
+			if code, ok := colors[name]; closed && ok {
+				out.WriteString(code)
+				i += len(name) + 1
+			} else {
+				out.WriteString(`\[`)
+			}
+		default:
+			out.WriteByte('\\')
+			out.WriteByte(c)
+		}
+	}
+	return out.String()
+}
+
+// shortCwd returns the current directory with the home directory written ~
+func (e *Evaluator) shortCwd() string {
+	home, err := os.UserHomeDir()
+	if err != nil || home == "" {
+		return e.cwd
+	}
+	if e.cwd == home {
+		return "~"
+	}
+	if rel, ok := strings.CutPrefix(e.cwd, home+string(filepath.Separator)); ok {
+		return "~" + string(filepath.Separator) + rel
+	}
+	return e.cwd
+}
+
+// userName returns the login name, the way whoami reports it
+func userName() string {
+	if name := os.Getenv("USER"); name != "" {
+		return name
+	}
+	return os.Getenv("USERNAME") // Windows
+}
+
+// gitBranch reads the branch checked out in the repository containing dir
+// from its HEAD file, without running git. It returns the first 7
+// characters of the commit when HEAD is detached, and "" outside a
+// repository.
+func gitBranch(dir string) string {
+	for {
+		gitDir := filepath.Join(dir, ".git")
+		if info, err := os.Stat(gitDir); err == nil {
+			if !info.IsDir() {
+				// A worktree or submodule: .git holds "gitdir: <path>"
+				content, err := os.ReadFile(gitDir)
+				if err != nil {
+					return ""
+				}
+				path, ok := strings.CutPrefix(strings.TrimSpace(string(content)), "gitdir: ")
+				if !ok {
+					return ""
+				}
+				if !filepath.IsAbs(path) {
+					path = filepath.Join(dir, path)
+				}
+				gitDir = path
+			}
+			return readHead(filepath.Join(gitDir, "HEAD"))
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// readHead returns the branch a HEAD file refers to, or its short commit
+func readHead(path string) string {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	head := strings.TrimSpace(string(content))
+	if ref, ok := strings.CutPrefix(head, "ref: "); ok {
+		return strings.TrimPrefix(ref, "refs/heads/")
+	}
+	if len(head) > 7 {
+		return head[:7]
+	}
+	return head
+}
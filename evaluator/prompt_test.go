@@ -0,0 +1,117 @@
+package evaluator
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPrompt(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USER", "raven")
+	project := filepath.Join(home, "src", "project")
+	if err := os.MkdirAll(filepath.Join(project, ".git"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(project, ".git", "HEAD"), []byte("ref: refs/heads/feature/login\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		prompt   string
+		cwd      string
+		expected string
+	}{
+		{`\w $ `, project, "~/src/project $ "},
+		{`\w`, home, "~"},
+		{`\w`, "/", "/"},
+		{`\W> `, project, "project> "},
+		{`\u [\?] `, home, "raven [0] "},
+		{`(\b) `, project, "(feature/login) "},
+		{`(\b) `, home, "() "},
+		{`\[green]\W\[reset] `, project, "\033[32mproject\033[0m "},
+		{`\e[1m\\\x\[nope]`, home, "\033[1m\\\\x\\[nope]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.prompt, func(t *testing.T) {
+			e, _ := newTestEvaluator(t, tt.cwd)
+			e.vars["PROMPT"] = tt.prompt
+			if got, _ := e.Prompt(); got != tt.expected {
+				t.Errorf("expected=%q, got=%q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestPromptDefaultsAndStatus(t *testing.T) {
+	e, _ := newTestEvaluator(t, t.TempDir())
+	if left, right := e.Prompt(); left != defaultPrompt || right != "" {
+		t.Errorf("expected the default prompt, got %q and %q", left, right)
+	}
+
+	e.SetStatus(127)
+	e.vars["PROMPT"] = `\? > `
+	e.vars["RPROMPT"] = `[\?]`
+	left, right := e.Prompt()
+	if left != "127 > " || right != "[127]" {
+		t.Errorf("wrong prompts for status 127: %q and %q", left, right)
+	}
+}
+
+func TestEditMode(t *testing.T) {
+	e, _ := newTestEvaluator(t, t.TempDir())
+	if mode := e.EditMode(); mode != "emacs" {
+		t.Errorf("expected emacs by default, got %q", mode)
+	}
+	if insert, normal := e.ModeIndicators(); insert != "[I] " || normal != "[N] " {
+		t.Errorf("wrong default indicators: %q and %q", insert, normal)
+	}
+
+	if err := evalInput(t, e, `set edit_mode vi
+set vi_normal_indicator "\[bold]cmd\[reset] "`); err != nil {
+		t.Fatal(err)
+	}
+	if mode := e.EditMode(); mode != "vi" {
+		t.Errorf("expected vi after set edit_mode vi, got %q", mode)
+	}
+	if _, normal := e.ModeIndicators(); normal != "\033[1mcmd\033[0m " {
+		t.Errorf("normal indicator not expanded: %q", normal)
+	}
+}
+
+func TestGitBranch(t *testing.T) {
+	dir := t.TempDir()
+	repo := filepath.Join(dir, "repo")
+	worktree := filepath.Join(dir, "worktree")
+	for _, d := range []string{filepath.Join(repo, ".git", "worktrees", "wt"), filepath.Join(repo, "sub"), worktree} {
+		if err := os.MkdirAll(d, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	files := map[string]string{
+		filepath.Join(repo, ".git", "HEAD"):                    "ref: refs/heads/main\n",
+		filepath.Join(repo, ".git", "worktrees", "wt", "HEAD"): "3594521f0c2e8d5b7a1e4c6d9f8b2a0e1c3d5f7a\n",
+		filepath.Join(worktree, ".git"):                        "gitdir: ../repo/.git/worktrees/wt\n",
+	}
+	for path, content := range files {
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		dir, expected string
+	}{
+		{repo, "main"},
+		{filepath.Join(repo, "sub"), "main"},
+		{worktree, "3594521"},
+		{dir, ""},
+	}
+	for _, tt := range tests {
+		if got := gitBranch(tt.dir); got != tt.expected {
+			t.Errorf("gitBranch(%s): expected=%q, got=%q", tt.dir, tt.expected, got)
+		}
+	}
+}
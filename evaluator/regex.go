@@ -0,0 +1,125 @@
+package evaluator
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"regexp/syntax"
+	"strings"
+)
+
+// maxPatterns bounds the compiled-pattern cache, so a script that builds
+// patterns in a loop doesn't grow it without limit
+const maxPatterns = 256
+
+// compilePattern returns the compiled form of a regular expression, reusing
+// it if the pattern has been seen before
+func (e *Evaluator) compilePattern(pattern string) (*regexp.Regexp, error) {
+	if re, ok := e.patterns[pattern]; ok {
+		return re, nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern '%s': %v", pattern, unwrapSyntaxError(err))
+	}
+	if len(e.patterns) >= maxPatterns {
+		clear(e.patterns)
+	}
+	e.patterns[pattern] = re
+	return re, nil
+}
+
+// unwrapSyntaxError drops the pattern that regexp repeats in its errors
+func unwrapSyntaxError(err error) error {
+	var parseErr *syntax.Error
+	if errors.As(err, &parseErr) {
+		return fmt.Errorf("%s", parseErr.Code)
+	}
+	return err
+}
+
+// evalMatch implements text =~ pattern. On a match, captures holds the
+// whole match followed by each group; otherwise it is empty.
+func (e *Evaluator) evalMatch(left, right Value) (Value, error) {
+	re, err := e.compilePattern(e.valueToString(right))
+	if err != nil {
+		return nil, err
+	}
+	groups := re.FindStringSubmatch(e.valueToString(left))
+	e.vars["captures"] = stringsToValues(groups)
+	return groups != nil, nil
+}
+
+// patternArgs returns the text and compiled pattern arguments shared by the
+// regex built-ins
+func (e *Evaluator) patternArgs(name string, args []Value) (string, *regexp.Regexp, error) {
+	text, err := e.stringArg(name, args, 0)
+	if err != nil {
+		return "", nil, err
+	}
+	pattern, err := e.stringArg(name, args, 1)
+	if err != nil {
+		return "", nil, err
+	}
+	re, err := e.compilePattern(pattern)
+	if err != nil {
+		return "", nil, fmt.Errorf("%s(): %v", name, err)
+	}
+	return text, re, nil
+}
+
+// builtinReplace implements replace(text, pattern, replacement). The
+// replacement can refer to groups as $1 or ${name}.
+func (e *Evaluator) builtinReplace(name string, args []Value) (Value, error) {
+	text, re, err := e.patternArgs(name, args)
+	if err != nil {
+		return nil, err
+	}
+	replacement, err := e.stringArg(name, args, 2)
+	if err != nil {
+		return nil, err
+	}
+	return re.ReplaceAllString(text, replacement), nil
+}
+
+// builtinSplit implements split(text, pattern), returning the pieces of
+// text between matches. split(text) splits on runs of whitespace.
+func (e *Evaluator) builtinSplit(name string, args []Value) (Value, error) {
+	var pieces []string
+	if len(args) == 1 {
+		text, err := e.stringArg(name, args, 0)
+		if err != nil {
+			return nil, err
+		}
+		pieces = strings.Fields(text)
+	} else {
+		text, re, err := e.patternArgs(name, args)
+		if err != nil {
+			return nil, err
+		}
+		pieces = re.Split(text, -1)
+	}
+	return stringsToValues(pieces), nil
+}
+
+// builtinFindAll implements find_all(text, pattern). Each match is the
+// matched text if the pattern has no groups, the group's text if it has
+// one, and an array of the groups if it has more.
+func (e *Evaluator) builtinFindAll(name string, args []Value) (Value, error) {
+	text, re, err := e.patternArgs(name, args)
+	if err != nil {
+		return nil, err
+	}
+	result := []Value{}
+	for _, groups := range re.FindAllStringSubmatch(text, -1) {
+		switch len(groups) {
+		case 1:
+			result = append(result, groups[0])
+		case 2:
+			result = append(result, groups[1])
+		default:
+			result = append(result, stringsToValues(groups[1:]))
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,95 @@
+package evaluator
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMatchOperator(t *testing.T) {
+	e, out := newTestEvaluator(t, t.TempDir())
+
+	input := `line = "2024-05-01 ERROR disk full"
+if line =~ "^(\d+)-(\d+)-(\d+) (\w+)" {
+    print captures[4] captures[1]
+}`
+	if err := evalInput(t, e, input); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out.String() != "ERROR 2024\n" {
+		t.Errorf("wrong output. got=%q", out.String())
+	}
+	expected := []Value{"2024-05-01 ERROR", "2024", "05", "01", "ERROR"}
+	if !reflect.DeepEqual(e.vars["captures"], expected) {
+		t.Errorf("wrong captures. expected=%v, got=%v", expected, e.vars["captures"])
+	}
+
+	if err := evalInput(t, e, `ok = line =~ "WARN"`); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if e.vars["ok"] != false {
+		t.Errorf("expected no match, got %v", e.vars["ok"])
+	}
+	if captures := e.vars["captures"].([]Value); len(captures) != 0 {
+		t.Errorf("captures not cleared after a failed match: %v", captures)
+	}
+}
+
+func TestRegexFunctions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected Value
+	}{
+		{`replace("v1.2.3", "(\d+)\.(\d+)\.\d+", "$1.$2.x")`, "v1.2.x"},
+		{`replace("a-b_c", "[-_]", "")`, "abc"},
+		{`split("a, b,c", ",\s*")`, []Value{"a", "b", "c"}},
+		{`find_all("aXbXc", "X")`, []Value{"X", "X"}},
+		{`find_all("id=4 id=17", "id=(\d+)")`, []Value{"4", "17"}},
+		{`find_all("a=1 b=2", "(\w)=(\d)")`, []Value{[]Value{"a", "1"}, []Value{"b", "2"}}},
+		{`find_all("abc", "\d")`, []Value{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			e, _ := newTestEvaluator(t, t.TempDir())
+			if err := evalInput(t, e, "result = "+tt.input); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(e.vars["result"], tt.expected) {
+				t.Errorf("wrong result. expected=%#v, got=%#v", tt.expected, e.vars["result"])
+			}
+		})
+	}
+}
+
+func TestRegexErrors(t *testing.T) {
+	tests := []struct {
+		input   string
+		wantErr string
+	}{
+		{`x = replace("a", "(", "b")`, "replace(): invalid pattern '(': missing closing )"},
+		{`x = split()`, "split() takes 1 or 2 arguments"},
+		{`x = "a" =~ "[a"`, "invalid pattern '[a'"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			_, err := runInput(t, t.TempDir(), tt.input)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestPatternCache(t *testing.T) {
+	e, _ := newTestEvaluator(t, t.TempDir())
+	first, err := e.compilePattern("a+")
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, _ := e.compilePattern("a+")
+	if first != second {
+		t.Error("pattern was compiled twice")
+	}
+}
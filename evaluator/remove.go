@@ -0,0 +1,202 @@
+package evaluator
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// removeOptions holds the flags accepted by rm
+type removeOptions struct {
+	recursive   bool // -r: remove directories and their contents
+	force       bool // -f: ignore missing paths and never prompt
+	interactive bool // -i: ask before every removal
+	dryRun      bool // --dry-run: only print what would be removed
+	permanent   bool // --permanent: delete instead of moving to the trash
+}
+
+func (e *Evaluator) execRemove(flags flagSet, args []string) (string, error) {
+	if len(args) == 0 {
+		return "", fmt.Errorf("rm: missing operand")
+	}
+
+	opts := removeOptions{
+		recursive:   flags.has("recursive"),
+		force:       flags.has("force"),
+		interactive: flags.has("interactive"),
+		dryRun:      flags.has("dry-run"),
+		permanent:   flags.has("permanent"),
+	}
+
+	var output bytes.Buffer
+	var firstErr error
+	op := fileOp{command: "rm"}
+	for _, arg := range args {
+		if err := e.removeOne(&output, arg, opts, &op); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	e.record(op)
+
+	result := output.String()
+	fmt.Fprint(e.stdout, result)
+	return result, firstErr
+}
+
+// removeOne checks and removes a single rm operand, adding anything moved
+// to the trash to op
+func (e *Evaluator) removeOne(out *bytes.Buffer, arg string, opts removeOptions, op *fileOp) error {
+	base := filepath.Base(arg)
+	if base == "." || base == ".." {
+		return fmt.Errorf("rm: refusing to remove '.' or '..' directory: '%s'", arg)
+	}
+
+	path := e.resolvePath(arg)
+	if protected, ok := e.protectedBy(path); ok {
+		if protected == path {
+			return fmt.Errorf("rm: refusing to remove protected path '%s'", arg)
+		}
+		return fmt.Errorf("rm: refusing to remove '%s': it contains protected path '%s'", arg, protected)
+	}
+	if path == e.cwd || isWithin(e.cwd, path) {
+		return fmt.Errorf("rm: refusing to remove '%s': it contains the current directory", arg)
+	}
+
+	info, err := os.Lstat(path)
+	if err != nil {
+		if opts.force && os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("rm: cannot remove '%s': %v", arg, unwrapPathError(err))
+	}
+
+	isDir := info.IsDir()
+	if isDir && !opts.recursive {
+		return fmt.Errorf("rm: cannot remove '%s': is a directory (use -r)", arg)
+	}
+
+	if opts.dryRun {
+		return e.describeRemoval(out, path, arg)
+	}
+
+	if !opts.force {
+		question := ""
+		if opts.interactive {
+			question = fmt.Sprintf("rm: remove file '%s'?", arg)
+		}
+		if isDir {
+			count, err := countEntries(path)
+			if err != nil {
+				return fmt.Errorf("rm: cannot remove '%s': %v", arg, unwrapPathError(err))
+			}
+			threshold := e.intOption("rm_confirm_threshold")
+			if opts.interactive || (threshold > 0 && int64(count) > threshold) {
+				question = fmt.Sprintf("rm: remove directory '%s' and its %d entries?", arg, count)
+			}
+		}
+		if question != "" && !e.confirm(question) {
+			return nil
+		}
+	}
+
+	// Anything already in the trash is deleted for real
+	if opts.permanent || isInTrash(path) {
+		if isDir {
+			err = os.RemoveAll(path)
+		} else {
+			err = os.Remove(path)
+		}
+		if err != nil {
+			return fmt.Errorf("rm: cannot remove '%s': %v", arg, unwrapPathError(err))
+		}
+		return nil
+	}
+
+	item, err := moveToTrash(path)
+	if err != nil {
+		return fmt.Errorf("rm: cannot remove '%s': %v", arg, unwrapPathError(err))
+	}
+	op.trashed = append(op.trashed, item)
+	return nil
+}
+
+// describeRemoval writes every path that removing path would delete,
+// contents before the directory that holds them
+func (e *Evaluator) describeRemoval(out *bytes.Buffer, path, arg string) error {
+	var paths []string
+	err := filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		paths = append(paths, filepath.Join(arg, strings.TrimPrefix(p, path)))
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("rm: cannot remove '%s': %v", arg, unwrapPathError(err))
+	}
+
+	for i := len(paths) - 1; i >= 0; i-- {
+		out.WriteString("would remove " + paths[i] + "\n")
+	}
+	return nil
+}
+
+// protectedBy reports the protected path that removing path would destroy:
+// the path itself or anything beneath it. / and the home directory are always
+// protected; the rm_protected option adds more.
+func (e *Evaluator) protectedBy(path string) (string, bool) {
+	protected := []string{string(filepath.Separator)}
+	if home, err := os.UserHomeDir(); err == nil {
+		protected = append(protected, home)
+	}
+	for _, p := range filepath.SplitList(e.option("rm_protected")) {
+		if p != "" {
+			protected = append(protected, e.resolvePath(p))
+		}
+	}
+
+	for _, p := range protected {
+		p = filepath.Clean(p)
+		if p == path || isWithin(p, path) {
+			return p, true
+		}
+	}
+	return "", false
+}
+
+// isWithin reports whether path lies inside dir
+func isWithin(path, dir string) bool {
+	rel, err := filepath.Rel(dir, path)
+	if err != nil {
+		return false
+	}
+	return rel != "." && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// countEntries returns the number of files and directories below path
+func countEntries(path string) (int, error) {
+	count := -1 // don't count path itself
+	err := filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		count++
+		return nil
+	})
+	return count, err
+}
+
+// unwrapPathError drops the operation and paths from an *fs.PathError or
+// *os.LinkError, since the commands' own messages already name the paths
+func unwrapPathError(err error) error {
+	switch e := err.(type) {
+	case *fs.PathError:
+		return e.Err
+	case *os.LinkError:
+		return e.Err
+	}
+	return err
+}
@@ -0,0 +1,134 @@
+package evaluator
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// makeTree creates dir/tree with two files and a nested directory
+func makeTree(t *testing.T, dir string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Join(dir, "tree", "sub"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"tree/a.txt", "tree/sub/b.txt"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func exists(path string) bool {
+	_, err := os.Lstat(path)
+	return err == nil
+}
+
+func TestRemoveRefusesProtectedPaths(t *testing.T) {
+	dir := t.TempDir()
+	makeTree(t, dir)
+	home, err := os.UserHomeDir()
+	if err != nil {
+		t.Skip("no home directory")
+	}
+
+	tests := []struct {
+		name    string
+		input   string
+		wantErr string
+	}{
+		{"root", "rm -r /", "refusing to remove protected path"},
+		{"home", "rm -r " + home, "refusing to remove protected path"},
+		{"tilde", "rm -rf ~", "refusing to remove protected path"},
+		{"current directory", "rm -r .", "contains the current directory"},
+		{"configured", `set rm_protected "` + filepath.Join(dir, "tree", "sub") + `"
+rm -r tree`, "contains protected path"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := runInput(t, dir, tt.input)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+			if !exists(filepath.Join(dir, "tree", "sub", "b.txt")) {
+				t.Error("protected tree was removed")
+			}
+		})
+	}
+}
+
+func TestRemoveDirectoryNeedsRecursive(t *testing.T) {
+	dir := t.TempDir()
+	makeTree(t, dir)
+
+	_, err := runInput(t, dir, "rm tree")
+	if err == nil || !strings.Contains(err.Error(), "is a directory") {
+		t.Fatalf("expected is a directory error, got %v", err)
+	}
+
+	if _, err := runInput(t, dir, "rm tree/a.txt"); err != nil {
+		t.Fatalf("rm file returned error: %v", err)
+	}
+	if _, err := runInput(t, dir, "rm -r tree"); err != nil {
+		t.Fatalf("rm -r returned error: %v", err)
+	}
+	if exists(filepath.Join(dir, "tree")) {
+		t.Error("tree still exists after rm -r")
+	}
+}
+
+func TestRemoveDryRun(t *testing.T) {
+	dir := t.TempDir()
+	makeTree(t, dir)
+
+	out, err := runInput(t, dir, "rm -r --dry-run tree")
+	if err != nil {
+		t.Fatalf("rm --dry-run returned error: %v", err)
+	}
+
+	expected := strings.Join([]string{
+		"would remove " + filepath.Join("tree", "sub", "b.txt"),
+		"would remove " + filepath.Join("tree", "sub"),
+		"would remove " + filepath.Join("tree", "a.txt"),
+		"would remove tree",
+	}, "\n") + "\n"
+	if out != expected {
+		t.Errorf("wrong dry-run output. expected=%q, got=%q", expected, out)
+	}
+	if !exists(filepath.Join(dir, "tree", "sub", "b.txt")) {
+		t.Error("dry run removed files")
+	}
+}
+
+func TestRemoveConfirmation(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		answer  string
+		removed bool
+	}{
+		{"interactive declined", "rm -ri tree", "n\n", false},
+		{"interactive accepted", "rm -ri tree", "y\n", true},
+		{"over threshold declined", "set rm_confirm_threshold 2\nrm -r tree", "\n", false},
+		{"under threshold", "set rm_confirm_threshold 10\nrm -r tree", "", true},
+		{"force skips prompt", "set rm_confirm_threshold 2\nrm -rf tree", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			makeTree(t, dir)
+
+			e, _ := newTestEvaluator(t, dir)
+			e.ttyIn = strings.NewReader(tt.answer)
+			if err := evalInput(t, e, tt.input); err != nil {
+				t.Fatalf("%q returned error: %v", tt.input, err)
+			}
+			if removed := !exists(filepath.Join(dir, "tree")); removed != tt.removed {
+				t.Errorf("removed=%v, expected %v", removed, tt.removed)
+			}
+		})
+	}
+}
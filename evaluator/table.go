@@ -0,0 +1,446 @@
+package evaluator
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"ravenshell/ast"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Structured pipelines pass values, usually tables (arrays of records),
+// between stages instead of text: ls | where size > 1000 | sort-by mtime.
+// ls only produces a table when its consumer is a table stage, so ls | grep
+// still sees plain text. When a value reaches the terminal, a file or a text
+// command it is rendered as text; a table gets one row per line under a
+// header.
+
+// Record is a value with named fields, such as one row of a table. Fields
+// keep the order they were set in, which is their column order.
+type Record struct {
+	keys   []string
+	fields map[string]Value
+}
+
+// NewRecord returns an empty record
+func NewRecord() *Record {
+	return &Record{fields: make(map[string]Value)}
+}
+
+// Set adds or replaces a field
+func (r *Record) Set(key string, val Value) {
+	if _, ok := r.fields[key]; !ok {
+		r.keys = append(r.keys, key)
+	}
+	r.fields[key] = val
+}
+
+// Get returns a field's value
+func (r *Record) Get(key string) (Value, bool) {
+	val, ok := r.fields[key]
+	return val, ok
+}
+
+// Keys returns the field names in order
+func (r *Record) Keys() []string {
+	return r.keys
+}
+
+// clone returns a copy of r that can be changed without affecting r
+func (r *Record) clone() *Record {
+	c := NewRecord()
+	for _, key := range r.keys {
+		c.Set(key, r.fields[key])
+	}
+	return c
+}
+
+// isTableStage reports whether a command takes a table as its input
+func isTableStage(t ast.CommandType) bool {
+	return t == ast.CMD_WHERE || t == ast.CMD_SORTBY || t == ast.CMD_SELECT
+}
+
+// isDecoder reports whether a command parses text into a value
+func isDecoder(t ast.CommandType) bool {
+	return t == ast.CMD_FROMJSON || t == ast.CMD_FROMCSV || t == ast.CMD_FROMTSV
+}
+
+// asTable returns val as rows of records if it is a table
+func asTable(val Value) ([]Value, bool) {
+	rows, ok := val.([]Value)
+	if !ok {
+		return nil, false
+	}
+	for _, row := range rows {
+		if _, ok := row.(*Record); !ok {
+			return nil, false
+		}
+	}
+	return rows, true
+}
+
+// evalStructured evaluates expr for a stage that takes values rather than
+// text. It reports false when expr only produces text: most commands, and
+// pipelines that don't end in a stage producing a value.
+func (e *Evaluator) evalStructured(expr ast.Expression) (Value, bool, error) {
+	switch node := expr.(type) {
+	case *ast.Identifier:
+		if val, ok := e.vars[node.Value]; ok {
+			return val, true, nil
+		}
+
+	case *ast.Command:
+		if wantsHelp(node.Arguments) {
+			return nil, false, nil
+		}
+		switch node.Type {
+		case ast.CMD_LIST, ast.CMD_FROMJSON, ast.CMD_FROMCSV, ast.CMD_FROMTSV:
+			flags, args, err := e.parseCommandArgs(builtinSpecs[node.Type], node.Arguments)
+			if err != nil {
+				return nil, true, err
+			}
+			switch node.Type {
+			case ast.CMD_LIST:
+				rows, err := e.listRecords(flags, args)
+				return rows, true, err
+			case ast.CMD_FROMJSON:
+				val, err := e.readJSON(flags, args)
+				return val, true, err
+			}
+			val, err := e.readDelimited(node.Type, flags, args)
+			return val, true, err
+		}
+
+	case *ast.PipeExpression:
+		stage, ok := node.Right.(*ast.Command)
+		if !ok || wantsHelp(stage.Arguments) {
+			return nil, false, nil
+		}
+
+		switch {
+		case isTableStage(stage.Type):
+			input, ok, err := e.evalStructured(node.Left)
+			if err != nil {
+				return nil, true, err
+			}
+			rows, isTable := asTable(input)
+			if !ok || !isTable {
+				return nil, true, fmt.Errorf("%s: input is not a table (pipe in ls or another table)", stage.Name)
+			}
+			val, err := e.runTableStage(stage, rows)
+			return val, true, err
+
+		case isDecoder(stage.Type):
+			// Text flows in from the left and a value comes out
+			input, err := e.captureOutput(node.Left)
+			if err != nil {
+				return nil, true, err
+			}
+			oldStdin := e.stdin
+			e.stdin = input
+			val, _, err := e.evalStructured(stage)
+			e.stdin = oldStdin
+			return val, true, err
+		}
+	}
+	return nil, false, nil
+}
+
+// pipedValue returns what left passes to a stage that converts values, such
+// as to-json: the value of a structured pipeline, or otherwise the text left
+// prints, without its final newline
+func (e *Evaluator) pipedValue(left ast.Expression) (Value, error) {
+	val, ok, err := e.evalStructured(left)
+	if ok || err != nil {
+		return val, err
+	}
+	text, err := e.captureOutput(left)
+	if err != nil {
+		return nil, err
+	}
+	return strings.TrimSuffix(text.String(), "\n"), nil
+}
+
+// runTableStage applies where, sort-by or select to rows
+func (e *Evaluator) runTableStage(stage *ast.Command, rows []Value) (Value, error) {
+	spec := builtinSpecs[stage.Type]
+
+	// The where condition must be evaluated per row, not up front
+	if stage.Type == ast.CMD_WHERE {
+		if len(stage.Arguments) != 1 {
+			return nil, spec.usageError("missing condition")
+		}
+		return e.filterRows(rows, stage.Arguments[0])
+	}
+
+	flags, args, err := e.parseCommandArgs(spec, stage.Arguments)
+	if err != nil {
+		return nil, err
+	}
+	if len(args) == 0 {
+		return nil, spec.usageError("missing field name")
+	}
+
+	if stage.Type == ast.CMD_SORTBY {
+		sorted := append([]Value{}, rows...)
+		sort.SliceStable(sorted, func(i, j int) bool {
+			a, b := sorted[i].(*Record), sorted[j].(*Record)
+			if flags.has("reverse") {
+				a, b = b, a
+			}
+			for _, key := range args {
+				av, _ := a.Get(key)
+				bv, _ := b.Get(key)
+				if c := e.compareValues(av, bv); c != 0 {
+					return c < 0
+				}
+			}
+			return false
+		})
+		return sorted, nil
+	}
+
+	selected := make([]Value, len(rows))
+	for i, row := range rows {
+		rec := NewRecord()
+		for _, key := range args {
+			val, _ := row.(*Record).Get(key)
+			rec.Set(key, val)
+		}
+		selected[i] = rec
+	}
+	return selected, nil
+}
+
+// filterRows keeps the rows for which cond is true. Each row's fields are
+// visible as variables while cond is evaluated.
+func (e *Evaluator) filterRows(rows []Value, cond ast.Expression) (Value, error) {
+	kept := []Value{}
+	for _, row := range rows {
+		rec := row.(*Record)
+		saved := make(map[string]Value)
+		for _, key := range rec.Keys() {
+			if old, ok := e.vars[key]; ok {
+				saved[key] = old
+			}
+			e.vars[key], _ = rec.Get(key)
+		}
+
+		val, err := e.evalExpressionValue(cond)
+
+		for _, key := range rec.Keys() {
+			if old, ok := saved[key]; ok {
+				e.vars[key] = old
+			} else {
+				delete(e.vars, key)
+			}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("where: %v", err)
+		}
+		if e.valueToBool(val) {
+			kept = append(kept, row)
+		}
+	}
+	return kept, nil
+}
+
+// compareValues orders two field values: numbers and times by value,
+// anything else as text
+func (e *Evaluator) compareValues(a, b Value) int {
+	if ta, ok := a.(time.Time); ok {
+		if tb, ok := b.(time.Time); ok {
+			return ta.Compare(tb)
+		}
+	}
+	na, errA := e.valueToInt64(a)
+	nb, errB := e.valueToInt64(b)
+	if errA == nil && errB == nil {
+		switch {
+		case na < nb:
+			return -1
+		case na > nb:
+			return 1
+		}
+		return 0
+	}
+	return strings.Compare(e.valueToString(a), e.valueToString(b))
+}
+
+// listRecords returns what ls would list as a table with name, type, size
+// and mtime fields. ls's sorting and -a options apply; -l and -h only
+// change how text is shown, so they are ignored.
+func (e *Evaluator) listRecords(flags flagSet, paths []string) ([]Value, error) {
+	opts := newListOptions(flags)
+	if opts.recursive {
+		return nil, fmt.Errorf("ls: -R can't be used in a structured pipeline")
+	}
+	if len(paths) == 0 {
+		paths = []string{"."}
+	}
+
+	var entries []listEntry
+	for _, p := range paths {
+		path := e.resolvePath(p)
+		info, err := os.Lstat(path)
+		if err != nil {
+			return nil, fmt.Errorf("ls: %v", err)
+		}
+		if !info.IsDir() {
+			entries = append(entries, listEntry{name: p, info: info})
+			continue
+		}
+		dirEntries, err := os.ReadDir(path)
+		if err != nil {
+			return nil, fmt.Errorf("ls: %v", err)
+		}
+		for _, de := range dirEntries {
+			if !opts.all && strings.HasPrefix(de.Name(), ".") {
+				continue
+			}
+			info, err := de.Info()
+			if err != nil {
+				continue
+			}
+			name := de.Name()
+			if len(paths) > 1 {
+				name = filepath.Join(p, name)
+			}
+			entries = append(entries, listEntry{name: name, info: info})
+		}
+	}
+	sortListEntries(entries, opts)
+
+	rows := make([]Value, len(entries))
+	for i, entry := range entries {
+		rec := NewRecord()
+		rec.Set("name", entry.name)
+		rec.Set("type", fileType(entry.info.Mode()))
+		rec.Set("size", entry.info.Size())
+		rec.Set("mtime", entry.info.ModTime())
+		rows[i] = rec
+	}
+	return rows, nil
+}
+
+// fileType names the kind of file a mode describes
+func fileType(mode os.FileMode) string {
+	switch {
+	case mode.IsDir():
+		return "dir"
+	case mode&os.ModeSymlink != 0:
+		return "symlink"
+	case mode&os.ModeNamedPipe != 0:
+		return "pipe"
+	case mode&os.ModeSocket != 0:
+		return "socket"
+	case mode&os.ModeDevice != 0:
+		return "device"
+	}
+	return "file"
+}
+
+// renderTable lays rows out in aligned columns under a header naming each
+// field. Numbers are right-aligned; times use ls's format.
+func (e *Evaluator) renderTable(rows []Value) string {
+	columns := tableColumns(rows)
+	if len(columns) == 0 {
+		return ""
+	}
+
+	widths := make([]int, len(columns))
+	numeric := make([]bool, len(columns))
+	cells := make([][]string, len(rows))
+	for i, col := range columns {
+		widths[i] = len(col)
+		numeric[i] = true
+	}
+	for r, row := range rows {
+		cells[r] = make([]string, len(columns))
+		for i, col := range columns {
+			val, _ := row.(*Record).Get(col)
+			cells[r][i] = e.formatCell(val)
+			switch val.(type) {
+			case int64, int, nil:
+			default:
+				numeric[i] = false
+			}
+			if len(cells[r][i]) > widths[i] {
+				widths[i] = len(cells[r][i])
+			}
+		}
+	}
+
+	var out bytes.Buffer
+	writeRow := func(fields []string) {
+		var line strings.Builder
+		for i, field := range fields {
+			if i > 0 {
+				line.WriteString("  ")
+			}
+			if numeric[i] {
+				fmt.Fprintf(&line, "%*s", widths[i], field)
+			} else {
+				fmt.Fprintf(&line, "%-*s", widths[i], field)
+			}
+		}
+		out.WriteString(strings.TrimRight(line.String(), " ") + "\n")
+	}
+	writeRow(columns)
+	for _, row := range cells {
+		writeRow(row)
+	}
+	return out.String()
+}
+
+// tableColumns returns every field name used by rows, in the order they
+// first appear
+func tableColumns(rows []Value) []string {
+	var columns []string
+	seen := make(map[string]bool)
+	for _, row := range rows {
+		for _, key := range row.(*Record).Keys() {
+			if !seen[key] {
+				seen[key] = true
+				columns = append(columns, key)
+			}
+		}
+	}
+	return columns
+}
+
+// formatCell renders a single field for a table
+func (e *Evaluator) formatCell(val Value) string {
+	if t, ok := val.(time.Time); ok {
+		return formatModTime(t)
+	}
+	return e.valueToString(val)
+}
+
+// formatRecord renders a record on one line: {name: a.txt, size: 12}
+func (e *Evaluator) formatRecord(r *Record) string {
+	fields := make([]string, len(r.keys))
+	for i, key := range r.keys {
+		fields[i] = key + ": " + e.formatCell(r.fields[key])
+	}
+	return "{" + strings.Join(fields, ", ") + "}"
+}
+
+// printValue writes a value produced by a structured pipeline to stdout
+func (e *Evaluator) printValue(val Value) string {
+	// An empty table prints nothing, like a grep with no matches
+	if rows, ok := asTable(val); ok && len(rows) == 0 {
+		return ""
+	}
+	result := e.valueToString(val) + "\n"
+	fmt.Fprint(e.stdout, result)
+	return result
+}
+
+// execTableStage reports a table stage used without a table to work on
+func (e *Evaluator) execTableStage(cmd *ast.Command) (string, error) {
+	return "", fmt.Errorf("%s: needs a table piped in, e.g. ls | %s ...", cmd.Name, cmd.Name)
+}
@@ -0,0 +1,103 @@
+package evaluator
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// makeSizedFiles creates files of the given sizes, each a minute newer than
+// the one before
+func makeSizedFiles(t *testing.T, dir string, sizes map[string]int) {
+	t.Helper()
+	base := time.Now().Add(-time.Hour)
+	i := 0
+	for _, name := range []string{"small", "medium", "large"} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, make([]byte, sizes[name]), 0644); err != nil {
+			t.Fatal(err)
+		}
+		mtime := base.Add(time.Duration(i) * time.Minute)
+		if err := os.Chtimes(path, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+		i++
+	}
+}
+
+func TestStructuredPipelines(t *testing.T) {
+	dir := t.TempDir()
+	makeSizedFiles(t, dir, map[string]int{"small": 10, "medium": 2000, "large": 5000})
+	if err := os.Mkdir(filepath.Join(dir, "sub"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`ls | where type == "file" | select name size`,
+			"name    size\nlarge   5000\nmedium  2000\nsmall     10\n"},
+		{`ls | where size > 1000 | where type == "file" | sort-by size | select name`,
+			"name\nmedium\nlarge\n"},
+		{`ls | where type == "file" | sort-by -r mtime | select name`,
+			"name\nlarge\nmedium\nsmall\n"},
+		{`ls | where size > 1000 | select name | grep med`,
+			"medium\n"},
+		{`ls | where size > 100000`, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := runInput(t, dir, tt.input)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("wrong output.\nexpected=%q\ngot=%q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestTableValues(t *testing.T) {
+	dir := t.TempDir()
+	makeSizedFiles(t, dir, map[string]int{"small": 10, "medium": 2000, "large": 5000})
+	e, out := newTestEvaluator(t, dir)
+
+	input := "big = ls | where size > 1000 | sort-by size\nfor f in big { print f[\"name\"] }"
+	if err := evalInput(t, e, input); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out.String() != "medium\nlarge\n" {
+		t.Errorf("wrong output. got=%q", out.String())
+	}
+
+	out.Reset()
+	if err := evalInput(t, e, "big | select name size"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out.String() != "name    size\nmedium  2000\nlarge   5000\n" {
+		t.Errorf("wrong output. got=%q", out.String())
+	}
+}
+
+func TestTableStageErrors(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		input   string
+		wantErr string
+	}{
+		{"sort-by size", "sort-by: needs a table piped in, e.g. ls | sort-by ..."},
+		{"whoami | where size > 1", "where: input is not a table (pipe in ls or another table)"},
+		{"ls | select", "select: missing field name\nusage: select field..."},
+	}
+
+	for _, tt := range tests {
+		_, err := runInput(t, dir, tt.input)
+		if err == nil || err.Error() != tt.wantErr {
+			t.Errorf("for input %q: expected error %q, got %v", tt.input, tt.wantErr, err)
+		}
+	}
+}
@@ -0,0 +1,436 @@
+package evaluator
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"ravenshell/ast"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"golang.org/x/term"
+)
+
+// The text filters read lines from the files named as arguments, or from
+// stdin when there are none, and write each result line as soon as it is
+// known. Only sort holds its whole input; tail keeps its last n lines. A
+// copy of the output is kept as the command's value only when the value is
+// used, as in x = grep TODO notes.txt, so a filter run as a statement or
+// in a pipeline does not hold its output in memory.
+
+// textInput is one source of lines for a text filter
+type textInput struct {
+	name   string // file name as typed, empty for stdin
+	reader io.Reader
+}
+
+// eachInput calls fn for every input of cmd in order: each file argument,
+// or stdin when there are none. A missing file is reported after the other
+// inputs have been processed, like the coreutils versions.
+func (e *Evaluator) eachInput(cmd string, files []string, fn func(in textInput) error) error {
+	if len(files) == 0 {
+		if file, ok := e.stdin.(*os.File); ok && term.IsTerminal(int(file.Fd())) {
+			return fmt.Errorf("%s: no input: pipe text in or name a file", cmd)
+		}
+		return fn(textInput{reader: e.stdin})
+	}
+
+	var firstErr error
+	for _, name := range files {
+		file, err := os.Open(e.resolvePath(name))
+		if err != nil {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("%s: %s: %v", cmd, name, unwrapPathError(err))
+			}
+			continue
+		}
+		err = fn(textInput{name: name, reader: file})
+		file.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return firstErr
+}
+
+// scanLines calls fn for each line of r without its line ending. Lines of
+// any length are accepted.
+func scanLines(r io.Reader, fn func(line string) error) error {
+	reader := bufio.NewReader(r)
+	for {
+		line, err := reader.ReadString('\n')
+		if len(line) > 0 {
+			line = strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r")
+			if ferr := fn(line); ferr != nil {
+				return ferr
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// filterOutput writes a filter's results to stdout as they are produced and,
+// unless the command's value is discarded, keeps a copy to return as the value
+type filterOutput struct {
+	out    io.Writer
+	keep   bool
+	result strings.Builder
+}
+
+func (e *Evaluator) newFilterOutput() *filterOutput {
+	return &filterOutput{out: e.stdout, keep: !e.discard}
+}
+
+func (f *filterOutput) line(s string) {
+	fmt.Fprintln(f.out, s)
+	if f.keep {
+		f.result.WriteString(s + "\n")
+	}
+}
+
+func (e *Evaluator) execGrep(flags flagSet, args []string) (string, error) {
+	spec := builtinSpecs[ast.CMD_GREP]
+	if len(args) == 0 {
+		return "", spec.usageError("missing pattern")
+	}
+
+	pattern := args[0]
+	if flags.has("fixed-strings") {
+		pattern = regexp.QuoteMeta(pattern)
+	}
+	if flags.has("ignore-case") {
+		pattern = "(?i)" + pattern
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return "", fmt.Errorf("grep: invalid pattern '%s': %v", args[0], err)
+	}
+
+	files := args[1:]
+	invert := flags.has("invert-match")
+	prefix := len(files) > 1
+	out := e.newFilterOutput()
+
+	err = e.eachInput("grep", files, func(in textInput) error {
+		count, lineNo := 0, 0
+		err := scanLines(in.reader, func(line string) error {
+			lineNo++
+			if re.MatchString(line) == invert {
+				return nil
+			}
+			count++
+			if flags.has("count") || flags.has("files-with-matches") {
+				return nil
+			}
+			text := line
+			if flags.has("line-number") {
+				text = strconv.Itoa(lineNo) + ":" + text
+			}
+			if prefix {
+				text = in.name + ":" + text
+			}
+			out.line(text)
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("grep: %v", err)
+		}
+
+		switch {
+		case flags.has("files-with-matches"):
+			if count > 0 {
+				out.line(inputName(in))
+			}
+		case flags.has("count"):
+			if prefix {
+				out.line(fmt.Sprintf("%s:%d", in.name, count))
+			} else {
+				out.line(strconv.Itoa(count))
+			}
+		}
+		return nil
+	})
+	return out.result.String(), err
+}
+
+func (e *Evaluator) execHead(flags flagSet, args []string) (string, error) {
+	n, err := flags.intValue(builtinSpecs[ast.CMD_HEAD], "lines", 10)
+	if err != nil {
+		return "", err
+	}
+
+	out := e.newFilterOutput()
+	first := true
+	err = e.eachInput("head", args, func(in textInput) error {
+		writeInputHeader(out, in, len(args) > 1, &first)
+		// Stop reading as soon as enough lines have been written
+		reader := bufio.NewReader(in.reader)
+		for i := int64(0); i < n; i++ {
+			line, err := reader.ReadString('\n')
+			if len(line) > 0 {
+				out.line(strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r"))
+			}
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return fmt.Errorf("head: %v", err)
+			}
+		}
+		return nil
+	})
+	return out.result.String(), err
+}
+
+func (e *Evaluator) execTail(flags flagSet, args []string) (string, error) {
+	n, err := flags.intValue(builtinSpecs[ast.CMD_TAIL], "lines", 10)
+	if err != nil {
+		return "", err
+	}
+
+	out := e.newFilterOutput()
+	first := true
+	err = e.eachInput("tail", args, func(in textInput) error {
+		writeInputHeader(out, in, len(args) > 1, &first)
+		if n <= 0 {
+			return nil
+		}
+		// Keep only the last n lines in a ring, grown as lines arrive so a
+		// large n costs no more than the input
+		var ring []string
+		total := int64(0)
+		err := scanLines(in.reader, func(line string) error {
+			if int64(len(ring)) < n {
+				ring = append(ring, line)
+			} else {
+				ring[total%n] = line
+			}
+			total++
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("tail: %v", err)
+		}
+		start := int64(0)
+		if total > n {
+			start = total - n
+		}
+		for i := start; i < total; i++ {
+			out.line(ring[i%n])
+		}
+		return nil
+	})
+	return out.result.String(), err
+}
+
+// writeInputHeader writes the "==> name <==" line head and tail put before
+// each file when given several
+func writeInputHeader(out *filterOutput, in textInput, several bool, first *bool) {
+	if !several {
+		return
+	}
+	if !*first {
+		out.line("")
+	}
+	*first = false
+	out.line("==> " + inputName(in) + " <==")
+}
+
+// inputName names an input in output: the file name, or "(standard input)"
+func inputName(in textInput) string {
+	if in.name == "" {
+		return "(standard input)"
+	}
+	return in.name
+}
+
+// wordCounts holds the totals reported by wc
+type wordCounts struct {
+	lines, words, bytes int64
+}
+
+func (e *Evaluator) execWordCount(flags flagSet, args []string) (string, error) {
+	showLines, showWords, showBytes := flags.has("lines"), flags.has("words"), flags.has("bytes")
+	if !showLines && !showWords && !showBytes {
+		showLines, showWords, showBytes = true, true, true
+	}
+
+	out := e.newFilterOutput()
+	format := func(c wordCounts, name string) string {
+		var fields []string
+		if showLines {
+			fields = append(fields, fmt.Sprintf("%7d", c.lines))
+		}
+		if showWords {
+			fields = append(fields, fmt.Sprintf("%7d", c.words))
+		}
+		if showBytes {
+			fields = append(fields, fmt.Sprintf("%7d", c.bytes))
+		}
+		if name == "" {
+			// A lone count from piped input is printed bare, ready to assign
+			if len(fields) == 1 {
+				return strings.TrimSpace(fields[0])
+			}
+			return strings.Join(fields, " ")
+		}
+		return strings.Join(append(fields, name), " ")
+	}
+
+	var total wordCounts
+	err := e.eachInput("wc", args, func(in textInput) error {
+		c, err := countWords(in.reader)
+		if err != nil {
+			return fmt.Errorf("wc: %v", err)
+		}
+		total.lines += c.lines
+		total.words += c.words
+		total.bytes += c.bytes
+		out.line(format(c, in.name))
+		return nil
+	})
+	if len(args) > 1 {
+		out.line(format(total, "total"))
+	}
+	return out.result.String(), err
+}
+
+// countWords counts newlines, whitespace-separated words and bytes in r
+func countWords(r io.Reader) (wordCounts, error) {
+	var c wordCounts
+	reader := bufio.NewReader(r)
+	inWord := false
+	for {
+		ch, size, err := reader.ReadRune()
+		if err == io.EOF {
+			return c, nil
+		}
+		if err != nil {
+			return c, err
+		}
+		c.bytes += int64(size)
+		if ch == '\n' {
+			c.lines++
+		}
+		if unicode.IsSpace(ch) {
+			inWord = false
+		} else if !inWord {
+			inWord = true
+			c.words++
+		}
+	}
+}
+
+func (e *Evaluator) execSort(flags flagSet, args []string) (string, error) {
+	var lines []string
+	err := e.eachInput("sort", args, func(in textInput) error {
+		err := scanLines(in.reader, func(line string) error {
+			lines = append(lines, line)
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("sort: %v", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	key := func(s string) string { return s }
+	if flags.has("ignore-case") {
+		key = strings.ToLower
+	}
+	less := func(a, b string) bool { return key(a) < key(b) }
+	if flags.has("numeric") {
+		less = func(a, b string) bool {
+			na, nb := leadingNumber(a), leadingNumber(b)
+			if na != nb {
+				return na < nb
+			}
+			return a < b
+		}
+	}
+	if flags.has("reverse") {
+		sort.SliceStable(lines, func(i, j int) bool { return less(lines[j], lines[i]) })
+	} else {
+		sort.SliceStable(lines, func(i, j int) bool { return less(lines[i], lines[j]) })
+	}
+
+	out := e.newFilterOutput()
+	for i, line := range lines {
+		if flags.has("unique") && i > 0 && !less(lines[i-1], line) && !less(line, lines[i-1]) {
+			continue
+		}
+		out.line(line)
+	}
+	return out.result.String(), nil
+}
+
+// leadingNumber parses the number at the start of s, ignoring leading
+// blanks, the way sort -n does; lines without one sort as zero
+func leadingNumber(s string) float64 {
+	s = strings.TrimLeft(s, " \t")
+	end := 0
+	for end < len(s) && (s[end] >= '0' && s[end] <= '9' || s[end] == '.' || (end == 0 && (s[end] == '-' || s[end] == '+'))) {
+		end++
+	}
+	n, err := strconv.ParseFloat(s[:end], 64)
+	if err != nil {
+		return 0
+	}
+	return n
+}
+
+func (e *Evaluator) execUniq(flags flagSet, args []string) (string, error) {
+	same := func(a, b string) bool { return a == b }
+	if flags.has("ignore-case") {
+		same = strings.EqualFold
+	}
+
+	out := e.newFilterOutput()
+	var prev string
+	count := 0
+	flush := func() {
+		if count == 0 {
+			return
+		}
+		if flags.has("repeated") && count < 2 || flags.has("unique") && count > 1 {
+			return
+		}
+		if flags.has("count") {
+			out.line(fmt.Sprintf("%7d %s", count, prev))
+		} else {
+			out.line(prev)
+		}
+	}
+
+	// Like sort, uniq treats all its inputs as one stream; adjacent
+	// duplicates are collapsed as soon as a different line arrives
+	err := e.eachInput("uniq", args, func(in textInput) error {
+		err := scanLines(in.reader, func(line string) error {
+			if count > 0 && same(prev, line) {
+				count++
+				return nil
+			}
+			flush()
+			prev, count = line, 1
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("uniq: %v", err)
+		}
+		return nil
+	})
+	flush()
+	return out.result.String(), err
+}
@@ -0,0 +1,132 @@
+package evaluator
+
+import (
+	"os"
+	"path/filepath"
+	"ravenshell/ast"
+	"ravenshell/lexer"
+	"ravenshell/parser"
+	"strings"
+	"testing"
+)
+
+func TestTextFilters(t *testing.T) {
+	dir := t.TempDir()
+	lines := "pear 3\napple 10\nApple 2\napple 10\nfig 1\n"
+	if err := os.WriteFile(filepath.Join(dir, "fruit"), []byte(lines), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "more"), []byte("kiwi 4\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"grep apple fruit", "apple 10\napple 10\n"},
+		{"grep -i apple fruit", "apple 10\nApple 2\napple 10\n"},
+		{"grep -vc apple fruit", "3\n"},
+		{"grep -n fig fruit", "5:fig 1\n"},
+		{`grep -F "e 1" fruit`, "apple 10\napple 10\n"},
+		{"grep -l i fruit more", "fruit\nmore\n"},
+		{"head -n 2 fruit", "pear 3\napple 10\n"},
+		{"tail -n2 fruit", "apple 10\nfig 1\n"},
+		{"tail -n 10 more", "kiwi 4\n"},
+		{"show fruit | tail -n 99999999999", lines},
+		{"head -n 1 fruit more", "==> fruit <==\npear 3\n\n==> more <==\nkiwi 4\n"},
+		{"wc -l fruit", "      5 fruit\n"},
+		{"show fruit | wc -w", "10\n"},
+		{"sort fruit", "Apple 2\napple 10\napple 10\nfig 1\npear 3\n"},
+		{"sort -fu fruit", "apple 10\nApple 2\nfig 1\npear 3\n"},
+		{"sort fruit | uniq -c", "      1 Apple 2\n      2 apple 10\n      1 fig 1\n      1 pear 3\n"},
+		{"sort fruit | uniq -d", "apple 10\n"},
+		{"grep -o fruit", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := runInput(t, dir, tt.input)
+			if tt.expected == "" {
+				if err == nil {
+					t.Fatalf("expected an error, got output %q", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("wrong output.\nexpected=%q\ngot=%q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestSortNumeric(t *testing.T) {
+	e, out := newTestEvaluator(t, t.TempDir())
+	e.stdin = strings.NewReader("10\n9\n-1\n100\n")
+
+	if err := evalInput(t, e, "sort -nr"); err != nil {
+		t.Fatalf("sort returned error: %v", err)
+	}
+	if out.String() != "100\n10\n9\n-1\n" {
+		t.Errorf("wrong order. got=%q", out.String())
+	}
+}
+
+func TestTextFilterMissingFile(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "notes"), []byte("one\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Names without a dot or slash stay as typed in messages; the files that
+	// exist are still processed
+	got, err := runInput(t, dir, "head missing notes")
+	if err == nil || !strings.Contains(err.Error(), "head: missing:") {
+		t.Errorf("expected error naming the missing file, got %v", err)
+	}
+	if !strings.Contains(got, "one\n") {
+		t.Errorf("notes was not printed. got=%q", got)
+	}
+}
+
+func TestTextFilterValue(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "fruit"), []byte("pear\napple\nfig\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	e, out := newTestEvaluator(t, dir)
+
+	// The value is kept where something uses it
+	if err := evalInput(t, e, "x = grep a fruit\nn = len(head -n 2 fruit)\ny = show fruit | grep i"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for name, expected := range map[string]Value{"x": "pear\napple\n", "n": int64(11), "y": "fig\n"} {
+		if got := e.vars[name]; got != expected {
+			t.Errorf("%s: expected %q, got %q", name, expected, got)
+		}
+	}
+
+	// Run as a statement, in a pipeline or redirected, the filters print
+	// without keeping a copy
+	for _, input := range []string{"grep a fruit", "show fruit | grep a", "grep a fruit > found"} {
+		program := parser.New(lexer.NewLexer(input)).ParseProgram()
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		out.Reset()
+		result, err := e.evalDiscarding(stmt.Expression)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", input, err)
+		}
+		if result != "" {
+			t.Errorf("%s: expected no value, got %q", input, result)
+		}
+		if !strings.HasSuffix(input, "found") && out.String() != "pear\napple\n" {
+			t.Errorf("%s: expected the lines printed, got %q", input, out.String())
+		}
+	}
+	if data, err := os.ReadFile(filepath.Join(dir, "found")); err != nil || string(data) != "pear\napple\n" {
+		t.Errorf("expected the redirected lines in found, got %q, %v", data, err)
+	}
+}
@@ -0,0 +1,272 @@
+package evaluator
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"ravenshell/ast"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The trash follows the freedesktop.org Trash specification: deleted files
+// live in $XDG_DATA_HOME/Trash/files and each has a matching
+// Trash/info/NAME.trashinfo recording where it came from and when.
+
+const trashTimeFormat = "2006-01-02T15:04:05"
+
+// trashItem is a file or directory held in the trash
+type trashItem struct {
+	name     string    // name inside Trash/files
+	original string    // absolute path it was deleted from
+	deleted  time.Time // when it was moved to the trash
+}
+
+// trashDir returns the user's trash directory, creating it if needed
+func trashDir() (string, error) {
+	dataHome := os.Getenv("XDG_DATA_HOME")
+	if dataHome == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		dataHome = filepath.Join(home, ".local", "share")
+	}
+
+	dir := filepath.Join(dataHome, "Trash")
+	for _, sub := range []string{"files", "info"} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0700); err != nil {
+			return "", err
+		}
+	}
+	return dir, nil
+}
+
+// moveToTrash moves path into the trash and records where it came from
+func moveToTrash(path string) (trashItem, error) {
+	dir, err := trashDir()
+	if err != nil {
+		return trashItem{}, err
+	}
+
+	item := trashItem{original: path, deleted: time.Now()}
+
+	// Reserve a unique name by creating its info file exclusively, as the
+	// specification requires, so concurrent shells never collide
+	base := filepath.Base(path)
+	var info *os.File
+	for i := 1; ; i++ {
+		item.name = base
+		if i > 1 {
+			item.name = base + "." + strconv.Itoa(i)
+		}
+		info, err = os.OpenFile(item.infoPath(dir), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+		if err == nil {
+			break
+		}
+		if !os.IsExist(err) {
+			return trashItem{}, err
+		}
+	}
+
+	escaped := (&url.URL{Path: path}).EscapedPath()
+	fmt.Fprintf(info, "[Trash Info]\nPath=%s\nDeletionDate=%s\n", escaped, item.deleted.Format(trashTimeFormat))
+	if err := info.Close(); err != nil {
+		os.Remove(item.infoPath(dir))
+		return trashItem{}, err
+	}
+
+	if err := movePath(path, item.filePath(dir)); err != nil {
+		os.Remove(item.infoPath(dir))
+		return trashItem{}, err
+	}
+	return item, nil
+}
+
+// restoreFromTrash moves an item back to where it was deleted from
+func restoreFromTrash(item trashItem) error {
+	dir, err := trashDir()
+	if err != nil {
+		return err
+	}
+
+	if _, err := os.Lstat(item.original); err == nil {
+		return fmt.Errorf("'%s' already exists", item.original)
+	}
+	if err := os.MkdirAll(filepath.Dir(item.original), 0755); err != nil {
+		return err
+	}
+	if err := movePath(item.filePath(dir), item.original); err != nil {
+		return err
+	}
+	return os.Remove(item.infoPath(dir))
+}
+
+// listTrash returns the items in the trash, oldest first
+func listTrash() ([]trashItem, error) {
+	dir, err := trashDir()
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := os.ReadDir(filepath.Join(dir, "info"))
+	if err != nil {
+		return nil, err
+	}
+
+	var items []trashItem
+	for _, entry := range entries {
+		name, ok := strings.CutSuffix(entry.Name(), ".trashinfo")
+		if !ok {
+			continue
+		}
+		item, err := readTrashInfo(dir, name)
+		if err != nil {
+			// Skip info files written by other programs that we can't read
+			continue
+		}
+		items = append(items, item)
+	}
+
+	sort.SliceStable(items, func(i, j int) bool { return items[i].deleted.Before(items[j].deleted) })
+	return items, nil
+}
+
+// readTrashInfo parses Trash/info/NAME.trashinfo
+func readTrashInfo(dir, name string) (trashItem, error) {
+	item := trashItem{name: name}
+	file, err := os.Open(item.infoPath(dir))
+	if err != nil {
+		return item, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), "=")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "Path":
+			if item.original, err = url.PathUnescape(value); err != nil {
+				return item, err
+			}
+		case "DeletionDate":
+			if item.deleted, err = time.ParseInLocation(trashTimeFormat, value, time.Local); err != nil {
+				return item, err
+			}
+		}
+	}
+	if item.original == "" {
+		return item, fmt.Errorf("%s: missing Path", name)
+	}
+	return item, scanner.Err()
+}
+
+func (t trashItem) filePath(dir string) string {
+	return filepath.Join(dir, "files", t.name)
+}
+
+func (t trashItem) infoPath(dir string) string {
+	return filepath.Join(dir, "info", t.name+".trashinfo")
+}
+
+// isInTrash reports whether path is inside the trash directory, where
+// removing something must delete it for real
+func isInTrash(path string) bool {
+	dir, err := trashDir()
+	if err != nil {
+		return false
+	}
+	return path == dir || isWithin(path, dir)
+}
+
+// execTrash implements trash list, trash restore and trash empty
+func (e *Evaluator) execTrash(args []string) (string, error) {
+	spec := builtinSpecs[ast.CMD_TRASH]
+	if len(args) == 0 {
+		return "", spec.usageError("missing subcommand")
+	}
+
+	switch args[0] {
+	case "list":
+		if len(args) > 1 {
+			return "", spec.usageError("list takes no arguments")
+		}
+		items, err := listTrash()
+		if err != nil {
+			return "", fmt.Errorf("trash: %v", err)
+		}
+		var output bytes.Buffer
+		for _, item := range items {
+			fmt.Fprintf(&output, "%s  %s\n", item.deleted.Format("2006-01-02 15:04:05"), item.original)
+		}
+		result := output.String()
+		fmt.Fprint(e.stdout, result)
+		return result, nil
+
+	case "restore":
+		if len(args) < 2 {
+			return "", spec.usageError("restore needs a path")
+		}
+		items, err := listTrash()
+		if err != nil {
+			return "", fmt.Errorf("trash: %v", err)
+		}
+		var restored []trashItem
+		for _, arg := range args[1:] {
+			item, ok := findTrashItem(items, e.resolvePath(arg), arg)
+			if !ok {
+				return "", fmt.Errorf("trash: '%s' is not in the trash", arg)
+			}
+			if err := restoreFromTrash(item); err != nil {
+				return "", fmt.Errorf("trash: cannot restore '%s': %v", arg, err)
+			}
+			restored = append(restored, item)
+		}
+		e.record(fileOp{command: "trash restore", restored: restored})
+		return "", nil
+
+	case "empty":
+		if len(args) > 1 {
+			return "", spec.usageError("empty takes no arguments")
+		}
+		dir, err := trashDir()
+		if err != nil {
+			return "", fmt.Errorf("trash: %v", err)
+		}
+		for _, sub := range []string{"files", "info"} {
+			if err := os.RemoveAll(filepath.Join(dir, sub)); err != nil {
+				return "", fmt.Errorf("trash: %v", err)
+			}
+		}
+		// Entries in the journal now point at files that no longer exist
+		e.journal = nil
+		return "", nil
+
+	default:
+		return "", spec.usageError("unknown subcommand '%s'", args[0])
+	}
+}
+
+// findTrashItem finds the most recently deleted item with the given original
+// path, or else the item stored under the given trash name
+func findTrashItem(items []trashItem, path, name string) (trashItem, bool) {
+	for i := len(items) - 1; i >= 0; i-- {
+		if items[i].original == path {
+			return items[i], true
+		}
+	}
+	for _, item := range items {
+		if item.name == name {
+			return item, true
+		}
+	}
+	return trashItem{}, false
+}
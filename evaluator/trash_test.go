@@ -0,0 +1,159 @@
+package evaluator
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRemoveMovesToTrash(t *testing.T) {
+	dir := t.TempDir()
+	makeTree(t, dir)
+	e, out := newTestEvaluator(t, dir)
+
+	if err := evalInput(t, e, "rm -r tree"); err != nil {
+		t.Fatalf("rm returned error: %v", err)
+	}
+	if exists(filepath.Join(dir, "tree")) {
+		t.Fatal("tree still exists after rm")
+	}
+
+	trash := filepath.Join(os.Getenv("XDG_DATA_HOME"), "Trash")
+	if !exists(filepath.Join(trash, "files", "tree", "sub", "b.txt")) {
+		t.Error("tree contents not found in Trash/files")
+	}
+	info, err := os.ReadFile(filepath.Join(trash, "info", "tree.trashinfo"))
+	if err != nil {
+		t.Fatalf("missing trashinfo: %v", err)
+	}
+	if !strings.Contains(string(info), "Path="+filepath.Join(dir, "tree")+"\n") {
+		t.Errorf("trashinfo has wrong Path. got=%q", info)
+	}
+
+	out.Reset()
+	if err := evalInput(t, e, "trash list"); err != nil {
+		t.Fatalf("trash list returned error: %v", err)
+	}
+	if !strings.HasSuffix(out.String(), "  "+filepath.Join(dir, "tree")+"\n") {
+		t.Errorf("trash list missing entry. got=%q", out.String())
+	}
+}
+
+func TestUndo(t *testing.T) {
+	tests := []struct {
+		name  string
+		setup string
+		check func(t *testing.T, dir string)
+	}{
+		{"rm", "rm -r tree", func(t *testing.T, dir string) {
+			if !exists(filepath.Join(dir, "tree", "sub", "b.txt")) {
+				t.Error("undo did not restore the removed tree")
+			}
+		}},
+		{"rmdir", "mkdir empty\nrmdir empty", func(t *testing.T, dir string) {
+			if !exists(filepath.Join(dir, "empty")) {
+				t.Error("undo did not restore the removed directory")
+			}
+		}},
+		{"mkdir", "mkdir new/nested", func(t *testing.T, dir string) {
+			if exists(filepath.Join(dir, "new")) {
+				t.Error("undo did not remove the created directories")
+			}
+		}},
+		{"mkfile over existing", "mkfile tree/a.txt", func(t *testing.T, dir string) {
+			data, err := os.ReadFile(filepath.Join(dir, "tree", "a.txt"))
+			if err != nil || string(data) != "keep me" {
+				t.Errorf("undo did not restore the old contents. got=%q, err=%v", data, err)
+			}
+		}},
+		{"> over existing", `print "new" > tree/a.txt`, func(t *testing.T, dir string) {
+			data, err := os.ReadFile(filepath.Join(dir, "tree", "a.txt"))
+			if err != nil || string(data) != "keep me" {
+				t.Errorf("undo did not restore the old contents. got=%q, err=%v", data, err)
+			}
+		}},
+		{"> new file", `print "new" > out.txt`, func(t *testing.T, dir string) {
+			if exists(filepath.Join(dir, "out.txt")) {
+				t.Error("undo did not remove the created file")
+			}
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			makeTree(t, dir)
+			if err := os.WriteFile(filepath.Join(dir, "tree", "a.txt"), []byte("keep me"), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			e, _ := newTestEvaluator(t, dir)
+			if err := evalInput(t, e, tt.setup); err != nil {
+				t.Fatalf("%q returned error: %v", tt.setup, err)
+			}
+			if err := evalInput(t, e, "undo"); err != nil {
+				t.Fatalf("undo returned error: %v", err)
+			}
+			tt.check(t, dir)
+		})
+	}
+}
+
+func TestUndoResumesAfterFailure(t *testing.T) {
+	dir := t.TempDir()
+	makeTree(t, dir)
+	e, _ := newTestEvaluator(t, dir)
+
+	if err := evalInput(t, e, "mkdir dest\nmv tree/a.txt tree/sub/b.txt dest"); err != nil {
+		t.Fatalf("mv returned error: %v", err)
+	}
+	// A new tree/a.txt blocks the second step of the undo
+	if err := os.WriteFile(filepath.Join(dir, "tree", "a.txt"), []byte("new"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := evalInput(t, e, "undo"); err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Fatalf("expected undo to fail on the existing file, got %v", err)
+	}
+	if !exists(filepath.Join(dir, "tree", "sub", "b.txt")) {
+		t.Error("the step before the failure was not undone")
+	}
+
+	if err := os.Remove(filepath.Join(dir, "tree", "a.txt")); err != nil {
+		t.Fatal(err)
+	}
+	if err := evalInput(t, e, "undo"); err != nil {
+		t.Fatalf("undo after clearing the way returned error: %v", err)
+	}
+	if !exists(filepath.Join(dir, "tree", "a.txt")) || exists(filepath.Join(dir, "dest", "a.txt")) {
+		t.Error("the rest of the mv was not undone")
+	}
+
+	// The mv is fully undone, so the next undo removes dest
+	if err := evalInput(t, e, "undo"); err != nil {
+		t.Fatalf("undo of mkdir returned error: %v", err)
+	}
+	if exists(filepath.Join(dir, "dest")) {
+		t.Error("undo did not reach the mkdir")
+	}
+}
+
+func TestTrashRestore(t *testing.T) {
+	dir := t.TempDir()
+	makeTree(t, dir)
+	e, _ := newTestEvaluator(t, dir)
+
+	if err := evalInput(t, e, "rm tree/a.txt"); err != nil {
+		t.Fatalf("rm returned error: %v", err)
+	}
+	if err := evalInput(t, e, "trash restore tree/a.txt"); err != nil {
+		t.Fatalf("trash restore returned error: %v", err)
+	}
+	if !exists(filepath.Join(dir, "tree", "a.txt")) {
+		t.Fatal("file not restored")
+	}
+
+	if err := evalInput(t, e, "trash restore tree/a.txt"); err == nil {
+		t.Error("expected error restoring a path that is not in the trash")
+	}
+}
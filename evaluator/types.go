@@ -0,0 +1,157 @@
+package evaluator
+
+import (
+	"fmt"
+	"math"
+	"ravenshell/ast"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// A typed array is declared with a hint, as in xs = []int or
+// xs = []int[1, 2, 3]. The element type belongs to the variable, the way
+// typeset attributes do in other shells: every array later assigned to it,
+// including through append or xs[i] = v, has its elements converted to that
+// type, and a value that can't be converted is an error.
+
+// elementTypes lists the types an array hint may name
+var elementTypes = map[string]bool{"int": true, "float": true, "string": true, "bool": true}
+
+// checkElementType reports an unknown type hint
+func checkElementType(hint string) error {
+	if !elementTypes[hint] {
+		return fmt.Errorf("unknown array type '%s' (use int, float, string or bool)", hint)
+	}
+	return nil
+}
+
+// convertElement converts val to the element type t
+func (e *Evaluator) convertElement(val Value, t string) (Value, error) {
+	switch t {
+	case "int":
+		switch v := val.(type) {
+		case int64:
+			return v, nil
+		case int:
+			return int64(v), nil
+		case float64:
+			if v == math.Trunc(v) && math.Abs(v) < math.MaxInt64 {
+				return int64(v), nil
+			}
+		case string:
+			if n, err := strconv.ParseInt(strings.TrimSpace(v), 10, 64); err == nil {
+				return n, nil
+			}
+		}
+	case "float":
+		switch v := val.(type) {
+		case float64:
+			return v, nil
+		case int64:
+			return float64(v), nil
+		case int:
+			return float64(v), nil
+		case string:
+			if f, err := strconv.ParseFloat(strings.TrimSpace(v), 64); err == nil {
+				return f, nil
+			}
+		}
+	case "string":
+		switch val.(type) {
+		case string, int64, int, float64, bool, time.Time:
+			return e.valueToString(val), nil
+		}
+	case "bool":
+		switch v := val.(type) {
+		case bool:
+			return v, nil
+		case string:
+			if b, err := strconv.ParseBool(strings.TrimSpace(v)); err == nil {
+				return b, nil
+			}
+		}
+	}
+
+	shown := e.valueToString(val)
+	if s, ok := val.(string); ok {
+		shown = strconv.Quote(s)
+	}
+	return nil, fmt.Errorf("cannot convert %s %s to %s", typeName(val), shown, t)
+}
+
+// convertElements returns a copy of arr with every element converted to t
+func (e *Evaluator) convertElements(arr []Value, t string) ([]Value, error) {
+	converted := make([]Value, len(arr))
+	for i, elem := range arr {
+		val, err := e.convertElement(elem, t)
+		if err != nil {
+			return nil, err
+		}
+		converted[i] = val
+	}
+	return converted, nil
+}
+
+// arrayType returns the element type of the array expr evaluates to, or ""
+// if it is untyped. Typed literals and variables carry a type, and so do the
+// functions that build an array from another one.
+func (e *Evaluator) arrayType(expr ast.Expression) string {
+	switch node := expr.(type) {
+	case *ast.ArrayLiteral:
+		return node.TypeHint
+	case *ast.Identifier:
+		return e.arrayTypes[node.Value]
+	case *ast.SliceExpression:
+		return e.arrayType(node.Left)
+	case *ast.CallExpression:
+		switch node.Function {
+		case "append", "reverse", "sort", "slice":
+			if len(node.Arguments) > 0 {
+				return e.arrayType(node.Arguments[0])
+			}
+		}
+	}
+	return ""
+}
+
+// setVar stores val in the variable name. A typed array variable only
+// takes arrays, converted to its element type; elemType, if not empty,
+// declares a new element type for the variable.
+func (e *Evaluator) setVar(name string, val Value, elemType string) error {
+	if elemType == "" {
+		elemType = e.arrayTypes[name]
+	}
+	if elemType != "" {
+		arr, ok := val.([]Value)
+		if !ok {
+			return fmt.Errorf("cannot assign %s to %s, which is a []%s array", typeName(val), name, elemType)
+		}
+		converted, err := e.convertElements(arr, elemType)
+		if err != nil {
+			return fmt.Errorf("%s is a []%s array: %v", name, elemType, err)
+		}
+		val = converted
+		e.arrayTypes[name] = elemType
+	}
+	e.vars[name] = val
+	return nil
+}
+
+// typeOf names the type of val the way scripts spell it, so that
+// type(x) == "int" matches the []int hint
+func typeOf(val Value) string {
+	switch val.(type) {
+	case int64, int:
+		return "int"
+	case float64:
+		return "float"
+	}
+	return typeName(val)
+}
+
+// builtinType implements type(x). Typed arrays are reported by
+// evalCallExpression, which can see the variable or literal.
+func (e *Evaluator) builtinType(name string, args []Value) (Value, error) {
+	return typeOf(args[0]), nil
+}
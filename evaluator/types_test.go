@@ -0,0 +1,80 @@
+package evaluator
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTypedArrays(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`xs = []int[1, "2", 3]
+print xs
+print type(xs)`, "[1, 2, 3]\n[]int\n"},
+		{`xs = []int
+xs = append(xs, "4")
+xs = append(xs, 5)
+print xs[0] + xs[1]`, "9\n"},
+		{`xs = []string[1, 2]
+xs[0] = 10
+print xs[0] + xs[1]`, "102\n"},
+		{`xs = []float["1.5"]
+ys = append(xs, 2)
+print ys
+print type(ys)
+print type(ys[:1])`, "[1.5, 2]\n[]float\n[]float\n"},
+		{`flags = []bool["true", false]
+print flags`, "[true, false]\n"},
+		{`xs = []int[1]
+xs = []string
+xs = append(xs, 1)
+print type(xs)`, "[]string\n"},
+		{`print type(1)
+print type("a")
+print type([1, "a"])
+print type(from-json('{"a": 1.5}'))
+print type(from-json('1.5'))`, "int\nstring\narray\nrecord\nfloat\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := runInput(t, t.TempDir(), tt.input)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("wrong output.\nexpected=%q\ngot=%q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestTypedArrayErrors(t *testing.T) {
+	tests := []struct {
+		input   string
+		wantErr string
+	}{
+		{`xs = []int["x"]`, `[]int literal: cannot convert string "x" to int`},
+		{`xs = []int
+xs = append(xs, "x")`, `xs is a []int array: cannot convert string "x" to int`},
+		{`xs = []int[1]
+xs[0] = "one"`, `xs is a []int array: cannot convert string "one" to int`},
+		{`xs = []int
+xs = "a"`, "cannot assign string to xs, which is a []int array"},
+		{`xs = []string[[1]]`, "cannot convert array [1] to string"},
+		{`xs = []bool[1]`, "cannot convert integer 1 to bool"},
+		{`xs = []number`, "unknown array type 'number' (use int, float, string or bool)"},
+		{`x = type()`, "type() takes exactly 1 argument"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			_, err := runInput(t, t.TempDir(), tt.input)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,307 @@
+package evaluator
+
+import "ravenshell/ast"
+
+// builtinSpecs lists the options and usage of every built-in command.
+// evalCommand uses it to parse options and to answer --help.
+var builtinSpecs = map[ast.CommandType]*commandSpec{
+	ast.CMD_LIST: {
+		name:    "ls",
+		usage:   "[options] [path...]",
+		summary: "List directory contents.",
+		flags: []flagSpec{
+			{short: 'l', help: "long format: mode, owner, size, mtime and link target"},
+			{short: 'a', long: "all", help: "include entries starting with ."},
+			{short: 't', help: "sort by modification time, newest first"},
+			{short: 'S', help: "sort by size, largest first"},
+			{short: 'r', long: "reverse", help: "reverse the sort order"},
+			{short: 'h', long: "human-readable", help: "show sizes like 1.5K and 20M"},
+			{short: 'R', long: "recursive", help: "list subdirectories recursively"},
+		},
+	},
+	ast.CMD_CHANGEDIR: {
+		name:    "cd",
+		usage:   "[path]",
+		summary: "Change the current directory (home if no path is given).",
+	},
+	ast.CMD_CURRENTDIR: {
+		name:    "cwd",
+		usage:   "",
+		summary: "Print the current directory.",
+	},
+	ast.CMD_MAKEDIR: {
+		name:    "mkdir",
+		usage:   "path...",
+		summary: "Create directories, including missing parents.",
+	},
+	ast.CMD_REMOVEDIR: {
+		name:    "rmdir",
+		usage:   "path...",
+		summary: "Move empty directories to the trash.",
+	},
+	ast.CMD_REMOVE: {
+		name:    "rm",
+		usage:   "[options] path...",
+		summary: "Move files, and directories with -r, to the trash. /, ~ and rm_protected paths are never removed.",
+		flags: []flagSpec{
+			{short: 'r', long: "recursive", help: "remove directories and their contents"},
+			{short: 'f', long: "force", help: "ignore missing paths and never ask"},
+			{short: 'i', long: "interactive", help: "ask before each removal"},
+			{long: "dry-run", help: "print what would be removed without removing it"},
+			{long: "permanent", help: "delete instead of moving to the trash"},
+		},
+	},
+	ast.CMD_MAKEFILE: {
+		name:    "mkfile",
+		usage:   "path...",
+		summary: "Create empty files. Existing contents are moved to the trash.",
+	},
+	ast.CMD_WHOAMI: {
+		name:    "whoami",
+		usage:   "",
+		summary: "Print the current user name.",
+	},
+	ast.CMD_PRINT: {
+		name:    "print",
+		usage:   "[value...]",
+		summary: "Print values, or piped input when used after |.",
+	},
+	ast.CMD_OUTPUT: {
+		name:    "output",
+		usage:   "[value...]",
+		summary: "Same as print.",
+	},
+	ast.CMD_SHOW: {
+		name:    "show",
+		usage:   "path...",
+		summary: "Print the contents of files.",
+	},
+	ast.CMD_CLEAR: {
+		name:    "clear",
+		usage:   "",
+		summary: "Clear the screen.",
+	},
+	ast.CMD_SET: {
+		name:    "set",
+		usage:   "[--unset] [name [value]]",
+		summary: "List shell options, or set one (to \"on\" when no value is given).",
+		flags: []flagSpec{
+			{short: 'u', long: "unset", help: "restore the option's default value"},
+		},
+	},
+	ast.CMD_TRASH: {
+		name:    "trash",
+		usage:   "list | restore path... | empty",
+		summary: "List the trash, restore items to where they were deleted from, or empty it.",
+	},
+	ast.CMD_UNDO: {
+		name:    "undo",
+		usage:   "",
+		summary: "Reverse the last file operation: rm, rmdir, mkdir, mkfile, cp, mv, ln, touch or trash restore.",
+	},
+	ast.CMD_COPY: {
+		name:       "cp",
+		typedPaths: true,
+		usage:      "[options] source... destination",
+		summary:    "Copy files, and directories with -r. Files that get overwritten are moved to the trash.",
+		flags: []flagSpec{
+			{short: 'r', long: "recursive", help: "copy directories and their contents"},
+			{short: 'p', long: "preserve", help: "keep modification times (permissions are always kept)"},
+			{short: 'n', long: "no-clobber", help: "skip destinations that already exist"},
+			{short: 'P', long: "no-dereference", help: "copy a symbolic link source as a link, not the file it points to"},
+		},
+	},
+	ast.CMD_MOVE: {
+		name:       "mv",
+		typedPaths: true,
+		usage:      "[options] source... destination",
+		summary:    "Move or rename files and directories, across filesystems if needed. Files that get overwritten are moved to the trash.",
+		flags: []flagSpec{
+			{short: 'n', long: "no-clobber", help: "skip destinations that already exist"},
+		},
+	},
+	ast.CMD_LINK: {
+		name:       "ln",
+		typedPaths: true,
+		usage:      "[options] target [link]",
+		summary:    "Create a hard link, or a symbolic link with -s, to target.",
+		flags: []flagSpec{
+			{short: 's', long: "symbolic", help: "make a symbolic link"},
+			{short: 'f', long: "force", help: "replace an existing link (the old file goes to the trash)"},
+		},
+	},
+	ast.CMD_TOUCH: {
+		name:       "touch",
+		typedPaths: true,
+		usage:      "[options] path...",
+		summary:    "Create empty files, or update the modification time of existing ones without changing their contents.",
+		flags: []flagSpec{
+			{short: 'c', long: "no-create", help: "don't create missing files"},
+			{short: 'd', long: "date", arg: "TIME", help: "use TIME (2006-01-02 15:04:05, RFC 3339, ...) instead of now"},
+		},
+	},
+	ast.CMD_TILDE: {
+		name:    "~",
+		usage:   "",
+		summary: "Print the home directory.",
+	},
+	ast.CMD_GREP: {
+		name:    "grep",
+		usage:   "[options] pattern [file...]",
+		summary: "Print lines matching a regular expression, from files or piped input.",
+		flags: []flagSpec{
+			{short: 'i', long: "ignore-case", help: "match upper and lower case alike"},
+			{short: 'v', long: "invert-match", help: "print lines that don't match"},
+			{short: 'c', long: "count", help: "print the number of matching lines"},
+			{short: 'n', long: "line-number", help: "prefix each line with its line number"},
+			{short: 'l', long: "files-with-matches", help: "print only the names of files with a match"},
+			{short: 'F', long: "fixed-strings", help: "treat the pattern as plain text"},
+		},
+	},
+	ast.CMD_HEAD: {
+		name:    "head",
+		usage:   "[options] [file...]",
+		summary: "Print the first lines of files or piped input.",
+		flags: []flagSpec{
+			{short: 'n', long: "lines", arg: "N", help: "print the first N lines (default 10)"},
+		},
+	},
+	ast.CMD_TAIL: {
+		name:    "tail",
+		usage:   "[options] [file...]",
+		summary: "Print the last lines of files or piped input.",
+		flags: []flagSpec{
+			{short: 'n', long: "lines", arg: "N", help: "print the last N lines (default 10)"},
+		},
+	},
+	ast.CMD_WC: {
+		name:    "wc",
+		usage:   "[options] [file...]",
+		summary: "Count lines, words and bytes in files or piped input.",
+		flags: []flagSpec{
+			{short: 'l', long: "lines", help: "print the line count"},
+			{short: 'w', long: "words", help: "print the word count"},
+			{short: 'c', long: "bytes", help: "print the byte count"},
+		},
+	},
+	ast.CMD_SORT: {
+		name:    "sort",
+		usage:   "[options] [file...]",
+		summary: "Print lines of files or piped input in sorted order.",
+		flags: []flagSpec{
+			{short: 'n', long: "numeric", help: "compare by the number at the start of each line"},
+			{short: 'r', long: "reverse", help: "reverse the order"},
+			{short: 'f', long: "ignore-case", help: "compare upper and lower case alike"},
+			{short: 'u', long: "unique", help: "print only the first of equal lines"},
+		},
+	},
+	ast.CMD_UNIQ: {
+		name:    "uniq",
+		usage:   "[options] [file...]",
+		summary: "Collapse runs of identical adjacent lines from files or piped input.",
+		flags: []flagSpec{
+			{short: 'c', long: "count", help: "prefix lines with the number of times they occurred"},
+			{short: 'd', long: "repeated", help: "print only lines that were repeated"},
+			{short: 'u', long: "unique", help: "print only lines that were not repeated"},
+			{short: 'i', long: "ignore-case", help: "compare upper and lower case alike"},
+		},
+	},
+	ast.CMD_FIND: {
+		name:    "find",
+		usage:   "[options] [path...]",
+		summary: "Print the files and directories below each path (default .) that match every option given. In an assignment or for loop the paths become an array.",
+		flags: []flagSpec{
+			{short: 'n', long: "name", arg: "GLOB", help: "base name matches GLOB, e.g. \"*.o\""},
+			{short: 't', long: "type", arg: "TYPE", help: "f for files, d for directories, l for symlinks"},
+			{short: 's', long: "size", arg: "SIZE", help: "size is SIZE, +SIZE (more) or -SIZE (less); units c, k, M, G"},
+			{short: 'm', long: "mtime", arg: "AGE", help: "modified AGE ago, +AGE (longer) or -AGE (less); units s, m, h, d, w (default days)"},
+			{short: 'd', long: "max-depth", arg: "N", help: "descend at most N levels below each path"},
+			{short: 'p', long: "prune", arg: "GLOBS", help: "don't descend into directories matching these colon-separated globs"},
+		},
+	},
+	ast.CMD_WHERE: {
+		name:    "where",
+		usage:   "condition",
+		summary: "Keep the rows of a piped-in table for which condition is true; fields are variables: ls | where size > 1000.",
+	},
+	ast.CMD_SORTBY: {
+		name:    "sort-by",
+		usage:   "[options] field...",
+		summary: "Sort the rows of a piped-in table by one or more fields: ls | sort-by mtime.",
+		flags: []flagSpec{
+			{short: 'r', long: "reverse", help: "largest or newest first"},
+		},
+	},
+	ast.CMD_SELECT: {
+		name:    "select",
+		usage:   "field...",
+		summary: "Keep only the named fields, in that order, of a piped-in table: ls | select name size.",
+	},
+	ast.CMD_FROMJSON: {
+		name:    "from-json",
+		usage:   "[options] [file]",
+		summary: "Parse JSON from a file or piped input into shell values: objects become records, arrays become arrays.",
+		flags: []flagSpec{
+			{short: 'p', long: "path", arg: "PATH", help: "return only the value at PATH, e.g. servers.0.host"},
+		},
+	},
+	ast.CMD_TOJSON: {
+		name:    "to-json",
+		usage:   "[options]",
+		summary: "Print the value piped in as JSON.",
+		flags: []flagSpec{
+			{short: 'p', long: "pretty", help: "indent nested values by two spaces"},
+			{short: 'i', long: "indent", arg: "N", help: "indent nested values by N spaces"},
+		},
+	},
+	ast.CMD_FROMCSV: {
+		name:    "from-csv",
+		usage:   "[options] [file]",
+		summary: "Parse comma-separated text from a file or piped input into a table, taking field names from the first row.",
+		flags: []flagSpec{
+			{short: 'n', long: "no-header", help: "the first row is data; each row becomes a list"},
+			{short: 'c', long: "columns", arg: "NAMES", help: "name the fields NAMES (comma-separated, quoted); the first row is data"},
+			{short: 'd', long: "delimiter", arg: "CHAR", help: "fields are separated by CHAR instead of a comma"},
+			{short: 'l', long: "lazy-quotes", help: "accept stray quotes inside fields"},
+		},
+	},
+	ast.CMD_TOCSV: {
+		name:    "to-csv",
+		usage:   "[options]",
+		summary: "Print the table or list of rows piped in as comma-separated text, with a header row of field names.",
+		flags: []flagSpec{
+			{short: 'n', long: "no-header", help: "leave out the header row"},
+			{short: 'd', long: "delimiter", arg: "CHAR", help: "separate fields with CHAR instead of a comma"},
+			{short: 'q', long: "quote-all", help: "quote every field, not just those that need it"},
+		},
+	},
+	ast.CMD_FROMTSV: {
+		name:    "from-tsv",
+		usage:   "[options] [file]",
+		summary: "Parse tab-separated text from a file or piped input into a table, taking field names from the first row.",
+		flags: []flagSpec{
+			{short: 'n', long: "no-header", help: "the first row is data; each row becomes a list"},
+			{short: 'c', long: "columns", arg: "NAMES", help: "name the fields NAMES (comma-separated, quoted); the first row is data"},
+		},
+	},
+	ast.CMD_TOTSV: {
+		name:    "to-tsv",
+		usage:   "[options]",
+		summary: "Print the table or list of rows piped in as tab-separated text, with a header row of field names.",
+		flags: []flagSpec{
+			{short: 'n', long: "no-header", help: "leave out the header row"},
+			{short: 'q', long: "quote-all", help: "quote every field, not just those that need it"},
+		},
+	},
+	ast.CMD_BIND: {
+		name:    "bind",
+		usage:   "[key action] | --command key command | --insert key text | --unset key | --actions",
+		summary: "List the REPL's key bindings, or bind a key to an editor action, a command or text.",
+		flags: []flagSpec{
+			{short: 'c', long: "command", help: "run the command when the key is pressed"},
+			{short: 'i', long: "insert", help: "insert the text when the key is pressed"},
+			{short: 'u', long: "unset", help: "remove the key's binding"},
+			{short: 'a', long: "actions", help: "list the editor actions keys can be bound to"},
+		},
+	},
+}
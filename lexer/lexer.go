@@ -2,6 +2,7 @@ package lexer
 
 import (
 	"ravenshell/token"
+	"strings"
 	"unicode"
 )
 
@@ -24,6 +25,15 @@ func (l *Lexer) SetPos(pos int) {
 	l.pos = pos
 }
 
+// LineBreakBetween reports whether a line ends between the offsets start
+// and end
+func (l *Lexer) LineBreakBetween(start, end int) bool {
+	if start < 0 || end > len(l.input) || start >= end {
+		return false
+	}
+	return strings.IndexByte(l.input[start:end], '\n') >= 0
+}
+
 func (l *Lexer) peek() byte {
 	if l.pos >= len(l.input) {
 		return 0
@@ -45,19 +55,35 @@ func (l *Lexer) peekNext() byte {
 }
 
 func (l *Lexer) NextToken() token.Token {
-	ch := l.peek()
-	if unicode.IsSpace(rune(ch)) {
-		l.advance()
-		return l.NextToken()
-	}
+	l.skipWhitespaceAndComments()
+	start := l.pos
+	tok := l.readToken()
+	tok.Pos = start
+	tok.End = l.pos
+	return tok
+}
 
-	// Skip comments (from # to end of line)
-	if ch == '#' {
-		for l.peek() != '\n' && l.peek() != 0 {
+// skipWhitespaceAndComments skips spaces and comments (from # to end of line)
+func (l *Lexer) skipWhitespaceAndComments() {
+	for {
+		ch := l.peek()
+		if ch != 0 && unicode.IsSpace(rune(ch)) {
 			l.advance()
+			continue
+		}
+		if ch == '#' {
+			for l.peek() != '\n' && l.peek() != 0 {
+				l.advance()
+			}
+			continue
 		}
-		return l.NextToken()
+		return
 	}
+}
+
+// readToken reads the token starting at the current position
+func (l *Lexer) readToken() token.Token {
+	ch := l.peek()
 
 	switch ch {
 	case '|':
@@ -82,11 +108,20 @@ func (l *Lexer) NextToken() token.Token {
 		return token.Token{Type: token.LBRACKET, Literal: string(l.advance())}
 	case ']':
 		return token.Token{Type: token.RBRACKET, Literal: string(l.advance())}
+	case ':':
+		return token.Token{Type: token.COLON, Literal: string(l.advance())}
 	case ',':
 		return token.Token{Type: token.COMMA, Literal: string(l.advance())}
 	case '+':
 		return token.Token{Type: token.PLUS, Literal: string(l.advance())}
 	case '-':
+		// A dash at the start of a word followed by a letter or another dash
+		// is a command option (ls -la, rm --dry-run); anything else is the
+		// minus operator. The parser reads an option after a value outside
+		// a command's arguments as minus again: w = y -z.
+		if l.atWordStart() && (unicode.IsLetter(rune(l.peekNext())) || l.peekNext() == '-') {
+			return l.readFlag()
+		}
 		return token.Token{Type: token.MINUS, Literal: string(l.advance())}
 	case '*':
 		return token.Token{Type: token.ASTERISK, Literal: string(l.advance())}
@@ -99,6 +134,18 @@ func (l *Lexer) NextToken() token.Token {
 			l.advance()
 			return token.Token{Type: token.EQ, Literal: l.input[start:l.pos]}
 		}
+		if l.peekNext() == '~' {
+			start := l.pos
+			l.advance()
+			l.advance()
+			return token.Token{Type: token.MATCH, Literal: l.input[start:l.pos]}
+		}
+		if l.peekNext() == '>' {
+			start := l.pos
+			l.advance()
+			l.advance()
+			return token.Token{Type: token.ARROW, Literal: l.input[start:l.pos]}
+		}
 		return token.Token{Type: token.ASSIGN, Literal: string(l.advance())}
 	case '!':
 		if l.peekNext() == '=' {
@@ -107,7 +154,7 @@ func (l *Lexer) NextToken() token.Token {
 			l.advance()
 			return token.Token{Type: token.NOT_EQ, Literal: l.input[start:l.pos]}
 		}
-		return token.Token{Type: token.ILLEGAL, Literal: string(l.advance())}
+		return token.Token{Type: token.BANG, Literal: string(l.advance())}
 	case '>':
 		if l.peekNext() == '>' {
 			start := l.pos
@@ -196,6 +243,17 @@ func (l *Lexer) NextToken() token.Token {
 		for isAlphanumeric(l.peek()) {
 			l.advance()
 		}
+		// Commands with a dash in their name (sort-by) are read whole; in
+		// any other word the dash stays an operator
+		if l.peek() == '-' && unicode.IsLetter(rune(l.peekNext())) {
+			end := l.pos + 1
+			for end < len(l.input) && isAlphanumeric(l.input[end]) {
+				end++
+			}
+			if _, ok := token.TokenMap[l.input[start:end]]; ok {
+				l.pos = end
+			}
+		}
 		literal := l.input[start:l.pos]
 		// Check if it's a keyword
 		if tokType, ok := token.TokenMap[literal]; ok {
@@ -206,6 +264,59 @@ func (l *Lexer) NextToken() token.Token {
 	return token.Token{Type: token.ILLEGAL, Literal: string(l.advance())}
 }
 
+// atWordStart reports whether the current character begins a new word,
+// i.e. it is at the start of input or preceded by whitespace
+func (l *Lexer) atWordStart() bool {
+	return l.pos == 0 || unicode.IsSpace(rune(l.input[l.pos-1]))
+}
+
+// readFlag reads a command option: -l, -la, --all, --name=value or a bare --
+func (l *Lexer) readFlag() token.Token {
+	start := l.pos
+	l.advance() // consume -
+	if l.peek() == '-' {
+		l.advance()
+	}
+	for isAlphanumeric(l.peek()) || l.peek() == '-' {
+		l.advance()
+	}
+
+	if l.peek() != '=' || l.pos-start < 3 || l.input[start+1] != '-' {
+		return token.Token{Type: token.FLAG, Literal: l.input[start:l.pos]}
+	}
+
+	// --name=value: the value runs to the end of the word and may be quoted
+	l.advance() // consume =
+	name := l.input[start:l.pos]
+	if quote := l.peek(); quote == '"' || quote == '\'' {
+		l.advance()
+		valueStart := l.pos
+		for l.peek() != quote && l.peek() != 0 {
+			l.advance()
+		}
+		value := l.input[valueStart:l.pos]
+		if l.peek() != quote {
+			return token.Token{Type: token.ILLEGAL, Literal: name + value}
+		}
+		l.advance()
+		return token.Token{Type: token.FLAG, Literal: name + value}
+	}
+
+	for l.peek() != 0 && !unicode.IsSpace(rune(l.peek())) && !isWordBreak(l.peek()) {
+		l.advance()
+	}
+	return token.Token{Type: token.FLAG, Literal: l.input[start:l.pos]}
+}
+
+// isWordBreak reports whether ch ends an unquoted option value
+func isWordBreak(ch byte) bool {
+	switch ch {
+	case '|', '<', '>', '(', ')', '{', '}', '[', ']', ',', '#':
+		return true
+	}
+	return false
+}
+
 func isAlphanumeric(ch byte) bool {
 	return unicode.IsLetter(rune(ch)) || unicode.IsDigit(rune(ch)) || ch == '_'
 }
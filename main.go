@@ -2,6 +2,7 @@ package main
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -45,9 +46,20 @@ func runScript(filename string) {
 
 	if err := eval.Eval(program); err != nil {
 		fmt.Printf("error: %s\n", err)
+		os.Exit(exitStatus(err))
 	}
 }
 
+// exitStatus returns the status an error ends a script with: an external
+// program's or raise's own, otherwise 1
+func exitStatus(err error) int {
+	var shellErr *evaluator.ShellError
+	if errors.As(err, &shellErr) {
+		return int(shellErr.Status)
+	}
+	return 1
+}
+
 // loadRavenRC loads and executes the .ravenrc file from the user's home directory
 func loadRavenRC(eval *evaluator.Evaluator) {
 	home, err := os.UserHomeDir()
@@ -90,16 +102,27 @@ func loadRavenRC(eval *evaluator.Evaluator) {
 
 func repl() {
 	eval := evaluator.New()
+	rl := readline.New("")
 
-	// Load .ravenrc configuration file
-	loadRavenRC(eval)
+	// Set up path completion to use evaluator's current directory, and
+	// command, variable and option completion to use its state
+	rl.SetCwdFunc(eval.GetCwd)
+	rl.SetProvider(eval)
+	rl.SetHighlighter(eval.Highlight)
 
-	rl := readline.New("# ")
+	// bind changes the editor's keys, and keys bound to commands run them
+	// here, so the editor is set up before .ravenrc binds any
+	eval.SetKeyBinder(rl)
+	rl.SetCommandRunner(func(command string) {
+		runLine(eval, command)
+		setPrompts(rl, eval)
+	})
 
-	// Set up path completion to use evaluator's current directory
-	rl.SetCwdFunc(eval.GetCwd)
+	// Load .ravenrc configuration file
+	loadRavenRC(eval)
 
 	for {
+		setPrompts(rl, eval)
 		input, err := rl.ReadLine()
 		if err != nil {
 			// EOF or error
@@ -115,19 +138,36 @@ func repl() {
 			continue
 		}
 
-		l := lexer.NewLexer(input)
-		p := parser.New(l)
-		program := p.ParseProgram()
+		runLine(eval, input)
+	}
+}
 
-		if len(p.Errors()) > 0 {
-			for _, err := range p.Errors() {
-				fmt.Printf("parse error: %s\n", err)
-			}
-			continue
-		}
+// setPrompts prepares the line editor for the next line. PROMPT and
+// RPROMPT may show the directory, status or time, so they are expanded
+// again for every line, and the edit mode options may have changed.
+func setPrompts(rl *readline.Readline, eval *evaluator.Evaluator) {
+	prompt, rprompt := eval.Prompt()
+	rl.SetPrompt(prompt)
+	rl.SetRightPrompt(rprompt)
+	rl.SetViMode(eval.EditMode() == "vi")
+	rl.SetModeIndicators(eval.ModeIndicators())
+}
 
-		if err := eval.Eval(program); err != nil {
-			fmt.Printf("error: %s\n", err)
+// runLine runs a line typed in the REPL, reporting errors
+func runLine(eval *evaluator.Evaluator, input string) {
+	l := lexer.NewLexer(input)
+	p := parser.New(l)
+	program := p.ParseProgram()
+
+	if len(p.Errors()) > 0 {
+		for _, err := range p.Errors() {
+			fmt.Printf("parse error: %s\n", err)
 		}
+		return
+	}
+
+	if err := eval.Eval(program); err != nil {
+		fmt.Printf("error: %s\n", err)
+		eval.SetStatus(int64(exitStatus(err)))
 	}
 }
@@ -6,6 +6,7 @@ import (
 	"ravenshell/lexer"
 	"ravenshell/token"
 	"strconv"
+	"strings"
 )
 
 // Operator precedence levels (lower = binds looser)
@@ -14,11 +15,11 @@ const (
 	LOWEST
 	REDIRECT    // >, >>, <
 	PIPE        // |
-	EQUALS      // ==, !=
+	EQUALS      // ==, !=, =~
 	LESSGREATER // <, >
 	SUM         // +, -
 	PRODUCT     // *, /, %
-	PREFIX      // $ (variable reference)
+	PREFIX      // $ (variable reference), -x, !x
 	INDEX       // array[index]
 	COMMAND     // commands
 )
@@ -30,6 +31,7 @@ var precedences = map[token.TokenType]int{
 	token.OUT:      REDIRECT,
 	token.EQ:       EQUALS,
 	token.NOT_EQ:   EQUALS,
+	token.MATCH:    EQUALS,
 	token.LT:       REDIRECT, // Low precedence so redirections bind looser than pipes
 	token.GT:       REDIRECT, // Low precedence so redirections bind looser than pipes
 	token.LTE:      LESSGREATER,
@@ -54,6 +56,13 @@ type Parser struct {
 	curToken  token.Token
 	peekToken token.Token
 
+	inCommandArgs bool // parsing a command's arguments, where ~ is a path
+	inCondition   bool // parsing a where condition, where < or > compares
+	inBrackets    bool // inside ( ) or [ ], where -x negates even in a command's arguments
+	commandStart  int  // offset of the token starting a statement or pipeline stage
+	valueStart    int  // offset of the value being assigned, where y -z subtracts
+	readingExpr   bool // reading a command line again as an expression, as in y -z
+
 	prefixParseFns map[token.TokenType]prefixParseFn
 	infixParseFns  map[token.TokenType]infixParseFn
 }
@@ -61,8 +70,10 @@ type Parser struct {
 // New creates a new Parser
 func New(l *lexer.Lexer) *Parser {
 	p := &Parser{
-		l:      l,
-		errors: []string{},
+		l:            l,
+		errors:       []string{},
+		commandStart: -1,
+		valueStart:   -1,
 	}
 
 	// Register prefix parse functions
@@ -74,6 +85,7 @@ func New(l *lexer.Lexer) *Parser {
 	p.registerPrefix(token.FULLSTOP, p.parsePath)
 	p.registerPrefix(token.FSLASH, p.parsePath)
 	p.registerPrefix(token.TILDE, p.parseTilde)
+	p.registerPrefix(token.FLAG, p.parseFlagLiteral)
 	p.registerPrefix(token.LBRACKET, p.parseArrayLiteral)
 	p.registerPrefix(token.RANGE, p.parseCallExpression)
 	p.registerPrefix(token.APPEND, p.parseCallExpression)
@@ -92,6 +104,34 @@ func New(l *lexer.Lexer) *Parser {
 	p.registerPrefix(token.PRINT, p.parseCommandKeyword)
 	p.registerPrefix(token.SHOW, p.parseCommandKeyword)
 	p.registerPrefix(token.CLEAR, p.parseCommandKeyword)
+	p.registerPrefix(token.SET, p.parseCommandKeyword)
+	p.registerPrefix(token.TRASH, p.parseCommandKeyword)
+	p.registerPrefix(token.UNDO, p.parseCommandKeyword)
+	p.registerPrefix(token.COPY, p.parseCommandKeyword)
+	p.registerPrefix(token.MOVE, p.parseCommandKeyword)
+	p.registerPrefix(token.LINK, p.parseCommandKeyword)
+	p.registerPrefix(token.TOUCH, p.parseCommandKeyword)
+	p.registerPrefix(token.GREP, p.parseCommandKeyword)
+	p.registerPrefix(token.HEAD, p.parseCommandKeyword)
+	p.registerPrefix(token.TAIL, p.parseCommandKeyword)
+	p.registerPrefix(token.WC, p.parseCommandKeyword)
+	p.registerPrefix(token.SORT, p.parseCommandKeyword)
+	p.registerPrefix(token.UNIQ, p.parseCommandKeyword)
+	p.registerPrefix(token.FIND, p.parseCommandKeyword)
+	p.registerPrefix(token.WHERE, p.parseCommandKeyword)
+	p.registerPrefix(token.SORTBY, p.parseCommandKeyword)
+	p.registerPrefix(token.SELECT, p.parseCommandKeyword)
+	p.registerPrefix(token.FROMJSON, p.parseCommandKeyword)
+	p.registerPrefix(token.TOJSON, p.parseCommandKeyword)
+	p.registerPrefix(token.FROMCSV, p.parseCommandKeyword)
+	p.registerPrefix(token.TOCSV, p.parseCommandKeyword)
+	p.registerPrefix(token.FROMTSV, p.parseCommandKeyword)
+	p.registerPrefix(token.TOTSV, p.parseCommandKeyword)
+	p.registerPrefix(token.BIND, p.parseCommandKeyword)
+
+	p.registerPrefix(token.MINUS, p.parsePrefixExpression)
+	p.registerPrefix(token.PLUS, p.parsePrefixExpression)
+	p.registerPrefix(token.BANG, p.parsePrefixExpression)
 
 	// Register infix parse functions
 	p.infixParseFns = make(map[token.TokenType]infixParseFn)
@@ -104,11 +144,13 @@ func New(l *lexer.Lexer) *Parser {
 	p.registerInfix(token.PERCENT, p.parseInfixExpression)
 	p.registerInfix(token.EQ, p.parseInfixExpression)
 	p.registerInfix(token.NOT_EQ, p.parseInfixExpression)
+	p.registerInfix(token.MATCH, p.parseInfixExpression)
 	p.registerInfix(token.LT, p.parseComparisonOrRedirection)
 	p.registerInfix(token.GT, p.parseComparisonOrRedirection)
 	p.registerInfix(token.LTE, p.parseInfixExpression)
 	p.registerInfix(token.GTE, p.parseInfixExpression)
 	p.registerInfix(token.LBRACKET, p.parseIndexExpression)
+	p.registerInfix(token.FLAG, p.parseFlagAsMinus)
 
 	// Read two tokens to initialize curToken and peekToken
 	p.nextToken()
@@ -138,15 +180,39 @@ func (p *Parser) peekTokenIs(t token.TokenType) bool {
 	return p.peekToken.Type == t
 }
 
+// peekIsAdjacent reports whether the peek token directly follows the current
+// token with no whitespace in between
+func (p *Parser) peekIsAdjacent() bool {
+	return p.peekToken.Pos == p.curToken.Pos+len(p.curToken.Literal)
+}
+
+// peekOnNextLine reports whether a line ends between the current token and
+// the peek token
+func (p *Parser) peekOnNextLine() bool {
+	return p.l.LineBreakBetween(p.curToken.Pos, p.peekToken.Pos)
+}
+
+// peekPrecedence looks up the binding power of the peek token. Outside a
+// command's arguments a word such as -z after an operand is a subtraction,
+// so it binds like -.
 func (p *Parser) peekPrecedence() int {
-	if prec, ok := precedences[p.peekToken.Type]; ok {
-		return prec
+	if p.peekTokenIs(token.FLAG) && isMinusFlag(p.peekToken) && (!p.inCommandArgs || p.inBrackets) {
+		return SUM
 	}
-	return LOWEST
+	return p.precedence(p.peekToken.Type)
 }
 
 func (p *Parser) curPrecedence() int {
-	if prec, ok := precedences[p.curToken.Type]; ok {
+	return p.precedence(p.curToken.Type)
+}
+
+// precedence looks up an operator's binding power. In a where condition
+// < and > are comparisons, so they bind like <= and >=.
+func (p *Parser) precedence(tt token.TokenType) int {
+	if p.inCondition && (tt == token.LT || tt == token.GT) {
+		return LESSGREATER
+	}
+	if prec, ok := precedences[tt]; ok {
 		return prec
 	}
 	return LOWEST
@@ -190,20 +256,46 @@ func (p *Parser) parseStatement() ast.Statement {
 		return p.parseForStatement()
 	case token.IF:
 		return p.parseIfStatement()
+	case token.TRY:
+		return p.parseTryStatement()
 	case token.IDENT:
+		if p.isMatchStatement() {
+			return p.parseMatchStatement()
+		}
 		// Check if this is an assignment (IDENT = value)
 		if p.peekTokenIs(token.ASSIGN) {
 			return p.parseAssignmentStatement()
 		}
 		return p.parseExpressionStatement()
 	default:
+		// head = 3 assigns to a variable named like a command
+		if token.ContextualCommands[p.curToken.Type] && p.peekTokenIs(token.ASSIGN) {
+			return p.parseAssignmentStatement()
+		}
 		return p.parseExpressionStatement()
 	}
 }
 
-func (p *Parser) parseExpressionStatement() *ast.ExpressionStatement {
+func (p *Parser) parseExpressionStatement() ast.Statement {
 	stmt := &ast.ExpressionStatement{Token: p.curToken}
+	p.commandStart = p.curToken.Pos
 	stmt.Expression = p.parseExpression(LOWEST)
+
+	// An indexed variable followed by = is an element assignment: arr[0] = 1
+	if target, ok := stmt.Expression.(*ast.IndexExpression); ok && p.peekTokenIs(token.ASSIGN) {
+		p.nextToken()
+		assign := &ast.AssignmentStatement{Token: p.curToken, Target: target}
+		p.nextToken()
+		p.commandStart, p.valueStart = p.curToken.Pos, p.curToken.Pos
+		assign.Value = p.parseExpression(LOWEST)
+		return assign
+	}
+	if _, ok := stmt.Expression.(*ast.SliceExpression); ok && p.peekTokenIs(token.ASSIGN) {
+		p.errors = append(p.errors, fmt.Sprintf("cannot assign to a slice: %s", stmt.Expression.String()))
+		p.nextToken()
+		p.nextToken()
+		p.parseExpression(LOWEST)
+	}
 	return stmt
 }
 
@@ -236,20 +328,134 @@ func (p *Parser) parseIdentifierOrCommand() ast.Expression {
 		return p.parseCommand(cmdType)
 	}
 
+	// A name directly followed by ( is a function call: replace(s, "a", "b")
+	if p.peekTokenIs(token.LPAREN) && p.peekIsAdjacent() {
+		return p.parseCallExpression()
+	}
+
 	// Check if this identifier is followed by path tokens (e.g., file.txt, foo/bar)
-	if p.peekTokenIs(token.FSLASH) || p.peekTokenIs(token.FULLSTOP) {
+	if (p.peekTokenIs(token.FSLASH) || p.peekTokenIs(token.FULLSTOP)) && p.peekIsAdjacent() {
 		return p.parsePathFromIdent()
 	}
 
+	// Any other name starting a statement or pipeline stage runs a program:
+	// git status, make | tail
+	if p.isExternalCommand() {
+		reading, end := p.parseExpressionReading()
+		cmd := p.parseCommand(token.IDENT).(*ast.Command)
+		if reading != nil && p.curToken.Pos+len(p.curToken.Literal) == end {
+			cmd.Expression = reading
+		}
+		return cmd
+	}
+
 	// Otherwise, it's a regular identifier
 	return &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
 }
 
+// isExternalCommand reports whether the current identifier names a program
+// to run: it starts a statement or pipeline stage and is followed by
+// arguments on the same line. A name without arguments stays an
+// identifier, since it may be a variable (rows | select name); the
+// evaluator runs it as a program if no variable has that name.
+//
+// On the right of =, a name followed by -z is a subtraction, as in
+// w = y -z, not a program run with an option.
+func (p *Parser) isExternalCommand() bool {
+	if p.curToken.Pos != p.commandStart || p.readingExpr || p.peekOnNextLine() {
+		return false
+	}
+	switch p.peekToken.Type {
+	case token.MINUS, token.PLUS, token.BANG:
+		// count + 1 is arithmetic, not a program
+		return false
+	case token.FLAG:
+		if p.curToken.Pos == p.valueStart && isMinusFlag(p.peekToken) {
+			return false
+		}
+	}
+	return p.isArgumentToken(p.peekToken.Type) && !p.isNextAssignment()
+}
+
+// parseExpressionReading parses a program's name and arguments as an
+// expression instead, when they start with a word such as -z that can be
+// a subtraction. The evaluator uses it when the name turns out to be a
+// variable, so y -z subtracts if y is one. It returns nil if the line
+// isn't an expression, and where the expression ends, leaving the parser
+// where it was.
+func (p *Parser) parseExpressionReading() (ast.Expression, int) {
+	if !p.peekTokenIs(token.FLAG) || !isMinusFlag(p.peekToken) {
+		return nil, 0
+	}
+	savedPos := p.l.GetPos()
+	savedCur := p.curToken
+	savedPeek := p.peekToken
+	savedErrors := len(p.errors)
+
+	p.readingExpr = true
+	reading := p.parseExpression(PIPE)
+	p.readingExpr = false
+	end := p.curToken.Pos + len(p.curToken.Literal)
+	if len(p.errors) > savedErrors {
+		reading = nil
+	}
+
+	p.l.SetPos(savedPos)
+	p.curToken = savedCur
+	p.peekToken = savedPeek
+	p.errors = p.errors[:savedErrors]
+
+	return reading, end
+}
+
+// commandFunctions are the commands that also work as functions when their
+// name is directly followed by (: from-json(text)
+var commandFunctions = map[token.TokenType]bool{
+	token.FROMJSON: true,
+	token.TOJSON:   true,
+	token.FROMCSV:  true,
+	token.TOCSV:    true,
+	token.FROMTSV:  true,
+	token.TOTSV:    true,
+	token.SORT:     true,
+}
+
 // parseCommandKeyword handles command keyword tokens (LIST, REMOVE, etc.)
 func (p *Parser) parseCommandKeyword() ast.Expression {
+	if commandFunctions[p.curToken.Type] && p.peekTokenIs(token.LPAREN) && p.peekIsAdjacent() {
+		return p.parseCallExpression()
+	}
+	if token.ContextualCommands[p.curToken.Type] && !p.isCommandName() {
+		return &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	}
 	return p.parseCommand(p.curToken.Type)
 }
 
+// isCommandName reports whether one of token.ContextualCommands names the
+// command here rather than a variable. It does at the start of a statement
+// or pipeline stage, and inside an expression when arguments follow it on
+// the same line: x = find src --type f, len(head -n 2 notes). Among another
+// command's arguments it is a word, as in grep sort notes, and on the right
+// of = without arguments a variable, as in n = head.
+func (p *Parser) isCommandName() bool {
+	if p.peekTokenIs(token.ASSIGN) || (p.peekTokenIs(token.LBRACKET) && p.peekIsAdjacent()) {
+		// head = 3, head[0]
+		return false
+	}
+	if p.curToken.Pos == p.commandStart && p.curToken.Pos != p.valueStart {
+		return true
+	}
+	if p.inCommandArgs && !p.inBrackets {
+		return false
+	}
+	switch p.peekToken.Type {
+	case token.MINUS, token.PLUS, token.BANG:
+		// head + 1 is arithmetic
+		return false
+	}
+	return p.isArgumentToken(p.peekToken.Type) && !p.peekOnNextLine()
+}
+
 func (p *Parser) parseCommand(cmdTokenType token.TokenType) ast.Expression {
 	cmd := &ast.Command{
 		Token: p.curToken,
@@ -257,17 +463,43 @@ func (p *Parser) parseCommand(cmdTokenType token.TokenType) ast.Expression {
 		Type:  tokenTypeToCommandType(cmdTokenType),
 	}
 
+	// where takes a single condition, evaluated later against each row
+	if cmd.Type == ast.CMD_WHERE {
+		cmd.Arguments = p.parseCondition()
+		return cmd
+	}
+
 	// Parse arguments until we hit an operator or EOF
 	cmd.Arguments = p.parseCommandArguments()
 
 	return cmd
 }
 
+// parseCondition parses the condition after where, stopping at a pipe or
+// redirection: ls | where size > 1000 | sort-by mtime
+func (p *Parser) parseCondition() []ast.Expression {
+	if p.peekTokenIs(token.EOF) || p.peekTokenIs(token.PIPE) || p.peekTokenIs(token.LBRACE) {
+		return []ast.Expression{}
+	}
+
+	inCondition := p.inCondition
+	p.inCondition = true
+	defer func() { p.inCondition = inCondition }()
+
+	p.nextToken()
+	return []ast.Expression{p.parseExpression(PIPE)}
+}
+
 func (p *Parser) parseCommandArguments() []ast.Expression {
 	args := []ast.Expression{}
 
-	// Continue while next token can start an argument expression
-	for p.isArgumentToken(p.peekToken.Type) {
+	inArgs, inBrackets := p.inCommandArgs, p.inBrackets
+	p.inCommandArgs, p.inBrackets = true, false
+	defer func() { p.inCommandArgs, p.inBrackets = inArgs, inBrackets }()
+
+	// Continue while next token can start an argument expression, up to
+	// the end of the line
+	for (p.isArgumentToken(p.peekToken.Type) || p.isNextCall()) && !p.peekOnNextLine() {
 		// Stop if we see IDENT followed by ASSIGN - that's a new assignment statement
 		if p.peekTokenIs(token.IDENT) && p.isNextAssignment() {
 			break
@@ -282,7 +514,8 @@ func (p *Parser) parseCommandArguments() []ast.Expression {
 	return args
 }
 
-// isNextAssignment checks if peek token is IDENT and the token after that is ASSIGN
+// isNextAssignment checks if peek token is IDENT and the token after that is
+// ASSIGN, allowing indexes in between: x = 1, arr[0] = 1
 func (p *Parser) isNextAssignment() bool {
 	// We need to look two tokens ahead: peek is IDENT, and the one after is ASSIGN
 	// Save current state
@@ -292,6 +525,21 @@ func (p *Parser) isNextAssignment() bool {
 
 	// Advance to check
 	p.nextToken() // now curToken is the IDENT
+	for p.peekTokenIs(token.LBRACKET) && p.peekIsAdjacent() {
+		// Skip to the matching ]
+		depth := 0
+		for {
+			p.nextToken()
+			if p.curTokenIs(token.LBRACKET) {
+				depth++
+			} else if p.curTokenIs(token.RBRACKET) {
+				depth--
+			}
+			if depth == 0 || p.curTokenIs(token.EOF) {
+				break
+			}
+		}
+	}
 	isAssign := p.peekTokenIs(token.ASSIGN)
 
 	// Restore state
@@ -302,13 +550,38 @@ func (p *Parser) isNextAssignment() bool {
 	return isAssign
 }
 
+// isNextCall checks if peek token is a function name directly followed by
+// (, so print from-json(text) passes the call's result to print
+func (p *Parser) isNextCall() bool {
+	if !p.peekTokenIs(token.RANGE) && !p.peekTokenIs(token.APPEND) && !commandFunctions[p.peekToken.Type] {
+		return false
+	}
+
+	savedPos := p.l.GetPos()
+	savedCur := p.curToken
+	savedPeek := p.peekToken
+
+	p.nextToken() // now curToken is the function name
+	isCall := p.peekTokenIs(token.LPAREN) && p.peekIsAdjacent()
+
+	p.l.SetPos(savedPos)
+	p.curToken = savedCur
+	p.peekToken = savedPeek
+
+	return isCall
+}
+
 // isArgumentToken returns true if the token type can be a command argument
 func (p *Parser) isArgumentToken(tt token.TokenType) bool {
 	switch tt {
-	case token.IDENT, token.STRING, token.INTEGER, token.DOLLAR, token.FULLSTOP, token.FSLASH, token.TILDE:
+	case token.IDENT, token.STRING, token.INTEGER, token.DOLLAR, token.FULLSTOP, token.FSLASH, token.TILDE, token.FLAG:
+		return true
+	case token.MINUS, token.PLUS, token.BANG:
+		// print -1, print !done
 		return true
 	default:
-		return false
+		// grep sort notes: a command name used as a word
+		return token.ContextualCommands[tt]
 	}
 }
 
@@ -340,6 +613,56 @@ func (p *Parser) parseStringLiteral() ast.Expression {
 	return &ast.StringLiteral{Token: p.curToken, Value: p.curToken.Literal}
 }
 
+// parseFlagLiteral parses a command option: -l, -la, --all, --name=value.
+// Outside a command's arguments a single dash before a name is a negation,
+// so y = -x and if -len(s) < n work.
+func (p *Parser) parseFlagLiteral() ast.Expression {
+	flag := p.curToken
+	if (p.inCommandArgs && !p.inBrackets) || strings.HasPrefix(flag.Literal, "--") || strings.Contains(flag.Literal, "=") {
+		return &ast.FlagLiteral{Token: flag, Value: flag.Literal}
+	}
+
+	// Re-read the word as - and a name, leaving anything after the name
+	// (such as the - 1 in -x-1) to be read again as operators
+	name := strings.FieldsFunc(flag.Literal[1:], func(r rune) bool { return r == '-' })[0]
+	if end := flag.Pos + 1 + len(name); end < flag.Pos+len(flag.Literal) {
+		p.l.SetPos(end)
+		p.peekToken = p.l.NextToken()
+	}
+
+	expr := &ast.PrefixExpression{
+		Token:    token.Token{Type: token.MINUS, Literal: "-", Pos: flag.Pos},
+		Operator: "-",
+	}
+	p.curToken = token.Token{Type: token.IDENT, Literal: name, Pos: flag.Pos + 1}
+	expr.Right = p.parseExpression(PREFIX)
+	return expr
+}
+
+// isMinusFlag reports whether an option word can also be read as minus and
+// what follows it: -z and --z can, but --name=value and a bare -- can't
+func isMinusFlag(flag token.Token) bool {
+	return flag.Literal != "--" && !strings.Contains(flag.Literal, "=")
+}
+
+// parseFlagAsMinus parses a word such as -z after an operand as a
+// subtraction, re-reading it as - and what follows: y -z is y - z
+func (p *Parser) parseFlagAsMinus(left ast.Expression) ast.Expression {
+	flag := p.curToken
+	p.l.SetPos(flag.Pos + 1)
+	p.curToken = token.Token{Type: token.MINUS, Literal: "-", Pos: flag.Pos}
+	p.peekToken = p.l.NextToken()
+	return p.parseInfixExpression(left)
+}
+
+// parsePrefixExpression parses -x, +x and !x
+func (p *Parser) parsePrefixExpression() ast.Expression {
+	expr := &ast.PrefixExpression{Token: p.curToken, Operator: p.curToken.Literal}
+	p.nextToken()
+	expr.Right = p.parseExpression(PREFIX)
+	return expr
+}
+
 // parsePath parses a file path (./foo, ../bar, /absolute/path, etc.)
 func (p *Parser) parsePath() ast.Expression {
 	path := &ast.PathExpression{Token: p.curToken}
@@ -349,8 +672,8 @@ func (p *Parser) parsePath() ast.Expression {
 	pathStr += p.curToken.Literal
 	lastWasExtension := false
 
-	// Continue while next token is part of a path
-	for p.isPathToken(p.peekToken.Type) {
+	// Continue while next token is part of a path (whitespace ends the path)
+	for p.isPathToken(p.peekToken.Type) && p.peekIsAdjacent() {
 		// After an extension (. + IDENT), only continue if next is FSLASH
 		if lastWasExtension && !p.peekTokenIs(token.FSLASH) {
 			break
@@ -373,8 +696,8 @@ func (p *Parser) parsePathFromIdent() ast.Expression {
 	pathStr := p.curToken.Literal
 	lastWasExtension := false
 
-	// Continue while next token is part of a path
-	for p.isPathToken(p.peekToken.Type) {
+	// Continue while next token is part of a path (whitespace ends the path)
+	for p.isPathToken(p.peekToken.Type) && p.peekIsAdjacent() {
 		// After an extension (. + IDENT), only continue if next is FSLASH
 		if lastWasExtension && !p.peekTokenIs(token.FSLASH) {
 			break
@@ -394,10 +717,15 @@ func (p *Parser) parsePathFromIdent() ast.Expression {
 // parseTilde handles ~ - either as a path prefix (~/foo) or as a home command
 func (p *Parser) parseTilde() ast.Expression {
 	// If followed by FSLASH, it's a path like ~/foo
-	if p.peekTokenIs(token.FSLASH) {
+	if p.peekTokenIs(token.FSLASH) && p.peekIsAdjacent() {
 		return p.parsePath()
 	}
 
+	// As a command argument, a lone ~ is the home directory (rm -r ~)
+	if p.inCommandArgs {
+		return &ast.PathExpression{Token: p.curToken, Value: p.curToken.Literal}
+	}
+
 	// Standalone ~ is a command to print/go to home directory
 	cmd := &ast.Command{
 		Token:     p.curToken,
@@ -430,6 +758,7 @@ func (p *Parser) parsePipeExpression(left ast.Expression) ast.Expression {
 
 	precedence := p.curPrecedence()
 	p.nextToken()
+	p.commandStart = p.curToken.Pos
 	expression.Right = p.parseExpression(precedence)
 
 	return expression
@@ -464,9 +793,13 @@ func (p *Parser) parseRedirectionTarget() ast.Expression {
 		token.LIST, token.REMOVE, token.CHANGEDIR, token.REMOVEDIR, token.MAKEDIR,
 		token.WHOAMI, token.CURRENTDIR, token.MAKEFILE, token.OUTPUT, token.PRINT,
 		token.SHOW, token.CLEAR, token.FOR, token.IN, token.IF, token.ELSE,
-		token.RANGE, token.APPEND:
+		token.SET, token.TRASH, token.UNDO, token.COPY, token.MOVE, token.LINK,
+		token.TOUCH, token.GREP, token.HEAD, token.TAIL, token.WC, token.SORT,
+		token.UNIQ, token.FIND, token.WHERE, token.SORTBY, token.SELECT, token.FROMJSON,
+		token.TOJSON, token.FROMCSV, token.TOCSV, token.FROMTSV, token.TOTSV, token.BIND,
+		token.TRY, token.CATCH, token.FINALLY, token.RANGE, token.APPEND:
 		// Check if followed by path tokens (e.g., output.txt, foo/bar)
-		if p.peekTokenIs(token.FSLASH) || p.peekTokenIs(token.FULLSTOP) {
+		if (p.peekTokenIs(token.FSLASH) || p.peekTokenIs(token.FULLSTOP)) && p.peekIsAdjacent() {
 			return p.parsePathFromIdent()
 		}
 		// Plain identifier
@@ -514,6 +847,54 @@ func tokenTypeToCommandType(tt token.TokenType) ast.CommandType {
 		return ast.CMD_SHOW
 	case token.CLEAR:
 		return ast.CMD_CLEAR
+	case token.SET:
+		return ast.CMD_SET
+	case token.TRASH:
+		return ast.CMD_TRASH
+	case token.UNDO:
+		return ast.CMD_UNDO
+	case token.COPY:
+		return ast.CMD_COPY
+	case token.MOVE:
+		return ast.CMD_MOVE
+	case token.LINK:
+		return ast.CMD_LINK
+	case token.TOUCH:
+		return ast.CMD_TOUCH
+	case token.GREP:
+		return ast.CMD_GREP
+	case token.HEAD:
+		return ast.CMD_HEAD
+	case token.TAIL:
+		return ast.CMD_TAIL
+	case token.WC:
+		return ast.CMD_WC
+	case token.SORT:
+		return ast.CMD_SORT
+	case token.UNIQ:
+		return ast.CMD_UNIQ
+	case token.FIND:
+		return ast.CMD_FIND
+	case token.WHERE:
+		return ast.CMD_WHERE
+	case token.SORTBY:
+		return ast.CMD_SORTBY
+	case token.SELECT:
+		return ast.CMD_SELECT
+	case token.FROMJSON:
+		return ast.CMD_FROMJSON
+	case token.TOJSON:
+		return ast.CMD_TOJSON
+	case token.FROMCSV:
+		return ast.CMD_FROMCSV
+	case token.TOCSV:
+		return ast.CMD_TOCSV
+	case token.FROMTSV:
+		return ast.CMD_FROMTSV
+	case token.TOTSV:
+		return ast.CMD_TOTSV
+	case token.BIND:
+		return ast.CMD_BIND
 	default:
 		return ast.CMD_EXTERNAL
 	}
@@ -522,13 +903,14 @@ func tokenTypeToCommandType(tt token.TokenType) ast.CommandType {
 // parseAssignmentStatement parses: identifier = expression
 func (p *Parser) parseAssignmentStatement() *ast.AssignmentStatement {
 	stmt := &ast.AssignmentStatement{Token: p.curToken}
-	stmt.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	stmt.Target = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
 
 	if !p.expectPeek(token.ASSIGN) {
 		return nil
 	}
 
 	p.nextToken()
+	p.commandStart, p.valueStart = p.curToken.Pos, p.curToken.Pos
 	stmt.Value = p.parseExpression(LOWEST)
 
 	return stmt
@@ -538,7 +920,10 @@ func (p *Parser) parseAssignmentStatement() *ast.AssignmentStatement {
 func (p *Parser) parseForStatement() *ast.ForStatement {
 	stmt := &ast.ForStatement{Token: p.curToken}
 
-	if !p.expectPeek(token.IDENT) {
+	if token.ContextualCommands[p.peekToken.Type] {
+		// for find in finds { ... }
+		p.nextToken()
+	} else if !p.expectPeek(token.IDENT) {
 		return nil
 	}
 	stmt.Variable = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
@@ -559,7 +944,8 @@ func (p *Parser) parseForStatement() *ast.ForStatement {
 	return stmt
 }
 
-// parseIfStatement parses: if expression { block } [else { block }]
+// parseIfStatement parses: if expression { block } [else { block }]. An
+// else if chain is kept as an else block holding the next if statement.
 func (p *Parser) parseIfStatement() *ast.IfStatement {
 	stmt := &ast.IfStatement{Token: p.curToken}
 
@@ -575,6 +961,16 @@ func (p *Parser) parseIfStatement() *ast.IfStatement {
 	if p.peekTokenIs(token.ELSE) {
 		p.nextToken()
 
+		if p.peekTokenIs(token.IF) {
+			p.nextToken()
+			elseIf := p.parseIfStatement()
+			if elseIf == nil {
+				return nil
+			}
+			stmt.Alternative = &ast.BlockStatement{Token: elseIf.Token, Statements: []ast.Statement{elseIf}}
+			return stmt
+		}
+
 		if !p.expectPeek(token.LBRACE) {
 			return nil
 		}
@@ -585,6 +981,147 @@ func (p *Parser) parseIfStatement() *ast.IfStatement {
 	return stmt
 }
 
+// isMatchStatement reports whether a statement starting with the name
+// match is a match statement. match is a keyword only when a value and {
+// follow it, so scripts can still use it as a variable or program name.
+func (p *Parser) isMatchStatement() bool {
+	if p.curToken.Literal != "match" || p.peekTokenIs(token.ASSIGN) ||
+		p.peekTokenIs(token.LBRACE) || p.peekTokenIs(token.EOF) {
+		return false
+	}
+	savedPos := p.l.GetPos()
+	savedCur := p.curToken
+	savedPeek := p.peekToken
+	savedErrors := len(p.errors)
+	savedStart, savedValue := p.commandStart, p.valueStart
+
+	p.nextToken()
+	p.parseExpression(LOWEST)
+	isMatch := len(p.errors) == savedErrors && p.peekTokenIs(token.LBRACE)
+
+	p.l.SetPos(savedPos)
+	p.curToken = savedCur
+	p.peekToken = savedPeek
+	p.errors = p.errors[:savedErrors]
+	p.commandStart, p.valueStart = savedStart, savedValue
+
+	return isMatch
+}
+
+// parseMatchStatement parses: match value { pattern => { block } ... }.
+// Arms may be separated by commas or line breaks.
+func (p *Parser) parseMatchStatement() *ast.MatchStatement {
+	stmt := &ast.MatchStatement{Token: p.curToken}
+
+	p.nextToken()
+	stmt.Value = p.parseExpression(LOWEST)
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+	p.nextToken()
+
+	for !p.curTokenIs(token.RBRACE) {
+		if p.curTokenIs(token.EOF) {
+			p.errors = append(p.errors, "match is missing its closing }")
+			return nil
+		}
+		arm := p.parseMatchArm()
+		if arm == nil {
+			return nil
+		}
+		stmt.Arms = append(stmt.Arms, arm)
+
+		p.nextToken()
+		if p.curTokenIs(token.COMMA) {
+			p.nextToken()
+		}
+	}
+
+	return stmt
+}
+
+// parseMatchArm parses: pattern | pattern ... => { block }
+func (p *Parser) parseMatchArm() *ast.MatchArm {
+	arm := &ast.MatchArm{}
+
+	for {
+		pattern := p.parseMatchPattern()
+		if pattern == nil {
+			return nil
+		}
+		arm.Patterns = append(arm.Patterns, pattern)
+		if !p.peekTokenIs(token.PIPE) {
+			break
+		}
+		p.nextToken()
+		p.nextToken()
+	}
+
+	if !p.expectPeek(token.ARROW) || !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+	arm.Body = p.parseBlockStatement()
+	return arm
+}
+
+// parseMatchPattern parses one pattern: _, =~ "regex", or an expression.
+// Patterns bind tighter than |, which separates alternatives.
+func (p *Parser) parseMatchPattern() *ast.MatchPattern {
+	pattern := &ast.MatchPattern{Token: p.curToken}
+
+	if p.curTokenIs(token.IDENT) && p.curToken.Literal == "_" {
+		return pattern
+	}
+	if p.curTokenIs(token.MATCH) {
+		pattern.Regex = true
+		p.nextToken()
+	}
+
+	pattern.Value = p.parseExpression(PIPE)
+	if pattern.Value == nil {
+		return nil
+	}
+	return pattern
+}
+
+// parseTryStatement parses: try { } catch err { } finally { }. The catch
+// name can be left out, and so can either the catch or the finally block.
+func (p *Parser) parseTryStatement() *ast.TryStatement {
+	stmt := &ast.TryStatement{Token: p.curToken}
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+	stmt.Body = p.parseBlockStatement()
+
+	if p.peekTokenIs(token.CATCH) {
+		p.nextToken()
+		if p.peekTokenIs(token.IDENT) {
+			p.nextToken()
+			stmt.CatchName = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+		}
+		if !p.expectPeek(token.LBRACE) {
+			return nil
+		}
+		stmt.Catch = p.parseBlockStatement()
+	}
+
+	if p.peekTokenIs(token.FINALLY) {
+		p.nextToken()
+		if !p.expectPeek(token.LBRACE) {
+			return nil
+		}
+		stmt.Finally = p.parseBlockStatement()
+	}
+
+	if stmt.Catch == nil && stmt.Finally == nil {
+		p.errors = append(p.errors, "try needs a catch or finally block")
+		return nil
+	}
+	return stmt
+}
+
 // parseBlockStatement parses: { statements }
 func (p *Parser) parseBlockStatement() *ast.BlockStatement {
 	block := &ast.BlockStatement{Token: p.curToken}
@@ -605,6 +1142,20 @@ func (p *Parser) parseBlockStatement() *ast.BlockStatement {
 
 // parseComparisonOrRedirection handles > and < which can be either comparison or redirection
 func (p *Parser) parseComparisonOrRedirection(left ast.Expression) ast.Expression {
+	// In a where condition the first < or > compares; any later one
+	// redirects the pipeline: ls | where size > 10 > big.txt
+	if p.inCondition {
+		p.inCondition = false
+		expression := &ast.InfixExpression{
+			Token:    p.curToken,
+			Operator: p.curToken.Literal,
+			Left:     left,
+		}
+		p.nextToken()
+		expression.Right = p.parseExpression(LESSGREATER)
+		return expression
+	}
+
 	// If left is a command or pipe expression, treat as redirection
 	switch left.(type) {
 	case *ast.Command, *ast.PipeExpression:
@@ -667,6 +1218,10 @@ func (p *Parser) parseCallExpression() ast.Expression {
 func (p *Parser) parseExpressionList(end token.TokenType) []ast.Expression {
 	list := []ast.Expression{}
 
+	inBrackets := p.inBrackets
+	p.inBrackets = true
+	defer func() { p.inBrackets = inBrackets }()
+
 	if p.peekTokenIs(end) {
 		p.nextToken()
 		return list
@@ -692,7 +1247,8 @@ func (p *Parser) parseExpressionList(end token.TokenType) []ast.Expression {
 func (p *Parser) parseArrayLiteral() ast.Expression {
 	array := &ast.ArrayLiteral{Token: p.curToken}
 
-	// Check for []type syntax (empty array with type hint)
+	// Check for []type syntax (a type hint, optionally followed by the
+	// elements as in []int[1, 2, 3])
 	if p.peekTokenIs(token.RBRACKET) {
 		p.nextToken()
 		// Check if followed by a type identifier
@@ -700,6 +1256,10 @@ func (p *Parser) parseArrayLiteral() ast.Expression {
 			p.nextToken()
 			array.TypeHint = p.curToken.Literal
 			array.Elements = []ast.Expression{}
+			if p.peekTokenIs(token.LBRACKET) && p.peekIsAdjacent() {
+				p.nextToken()
+				array.Elements = p.parseExpressionList(token.RBRACKET)
+			}
 			return array
 		}
 		// Empty array without type
@@ -712,22 +1272,46 @@ func (p *Parser) parseArrayLiteral() ast.Expression {
 	return array
 }
 
-// parseIndexExpression parses: expression[index]
+// parseIndexExpression parses arr[i] and the slices arr[i:j], arr[:j] and
+// arr[i:]
 func (p *Parser) parseIndexExpression(left ast.Expression) ast.Expression {
-	exp := &ast.IndexExpression{Token: p.curToken, Left: left}
+	bracket := p.curToken
+	inBrackets := p.inBrackets
+	p.inBrackets = true
+	defer func() { p.inBrackets = inBrackets }()
 
 	p.nextToken()
-	exp.Index = p.parseExpression(LOWEST)
 
+	var start ast.Expression
+	if !p.curTokenIs(token.COLON) {
+		start = p.parseExpression(LOWEST)
+		if !p.peekTokenIs(token.COLON) {
+			if !p.expectPeek(token.RBRACKET) {
+				return nil
+			}
+			return &ast.IndexExpression{Token: bracket, Left: left, Index: start}
+		}
+		p.nextToken()
+	}
+
+	// curToken is the colon
+	slice := &ast.SliceExpression{Token: bracket, Left: left, Start: start}
+	if !p.peekTokenIs(token.RBRACKET) {
+		p.nextToken()
+		slice.End = p.parseExpression(LOWEST)
+	}
 	if !p.expectPeek(token.RBRACKET) {
 		return nil
 	}
-
-	return exp
+	return slice
 }
 
 // parseGroupedExpression parses: (expression)
 func (p *Parser) parseGroupedExpression() ast.Expression {
+	inBrackets := p.inBrackets
+	p.inBrackets = true
+	defer func() { p.inBrackets = inBrackets }()
+
 	p.nextToken()
 
 	exp := p.parseExpression(LOWEST)
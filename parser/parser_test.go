@@ -473,6 +473,21 @@ func TestIdentifierAsExpression(t *testing.T) {
 	}
 }
 
+func TestSubtractionWithoutSpace(t *testing.T) {
+	input := "y = 3\nz = 1\nw = y -z"
+	l := lexer.NewLexer(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 3 {
+		t.Fatalf("expected 3 statements, got %d", len(program.Statements))
+	}
+	if got := program.Statements[2].String(); got != "w = (y - z)" {
+		t.Errorf("wrong parse. expected=%q, got=%q", "w = (y - z)", got)
+	}
+}
+
 func TestIntegerLiteral(t *testing.T) {
 	input := "123"
 	l := lexer.NewLexer(input)
@@ -491,8 +506,550 @@ func TestIntegerLiteral(t *testing.T) {
 	}
 }
 
+func TestCommandWithFlags(t *testing.T) {
+	input := "ls -la -R ./src"
+	l := lexer.NewLexer(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	cmd := stmt.Expression.(*ast.Command)
+
+	if len(cmd.Arguments) != 3 {
+		t.Fatalf("wrong number of arguments. expected=3, got=%d", len(cmd.Arguments))
+	}
+
+	testFlag(t, cmd.Arguments[0], "-la")
+	testFlag(t, cmd.Arguments[1], "-R")
+	testPath(t, cmd.Arguments[2], "./src")
+}
+
+func TestLongFlags(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{"ls --all", []string{"--all"}},
+		{"rm --dry-run file", []string{"--dry-run", "file"}},
+		{"ls --sort=time", []string{"--sort=time"}},
+		{`ls --format="one per line"`, []string{"--format=one per line"}},
+		{"rm -- -file", []string{"--", "-file"}},
+	}
+
+	for _, tt := range tests {
+		l := lexer.NewLexer(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		cmd := stmt.Expression.(*ast.Command)
+
+		if len(cmd.Arguments) != len(tt.expected) {
+			t.Fatalf("for input %q: wrong number of arguments. expected=%d, got=%d",
+				tt.input, len(tt.expected), len(cmd.Arguments))
+		}
+		for i, arg := range cmd.Arguments {
+			if arg.String() != tt.expected[i] {
+				t.Errorf("for input %q: argument %d wrong. expected=%q, got=%q",
+					tt.input, i, tt.expected[i], arg.String())
+			}
+		}
+	}
+}
+
+func TestMinusIsStillAnOperator(t *testing.T) {
+	input := "print count - 1"
+	l := lexer.NewLexer(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	cmd := stmt.Expression.(*ast.Command)
+
+	if len(cmd.Arguments) != 1 {
+		t.Fatalf("wrong number of arguments. expected=1, got=%d", len(cmd.Arguments))
+	}
+
+	if _, ok := cmd.Arguments[0].(*ast.InfixExpression); !ok {
+		t.Errorf("argument is not InfixExpression. got=%T", cmd.Arguments[0])
+	}
+}
+
+func TestAssignedValueIsAnExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"x = a -b", "x = (a - b)"},
+		{"x = a - b", "x = (a - b)"},
+		{"x = a -1", "x = (a - 1)"},
+		{"x = a --b", "x = (a - (-b))"},
+		{"arr[0] = a -b", "(arr[0]) = (a - b)"},
+		{`x = git branch "--show-current"`, `x = git branch "--show-current"`},
+	}
+
+	for _, tt := range tests {
+		l := lexer.NewLexer(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("for input %q: expected 1 statement, got %d", tt.input, len(program.Statements))
+		}
+		if got := program.Statements[0].String(); got != tt.expected {
+			t.Errorf("for input %q: expected=%q, got=%q", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestPrefixExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"x = -5", "x = (-5)"},
+		{"x = 0 - -1", "x = (0 - (-1))"},
+		{"x = -a * b", "x = ((-a) * b)"},
+		{"x = -a-1", "x = ((-a) - 1)"},
+		{"x = -arr[0]", "x = (-(arr[0]))"},
+		{"x = !(a == b)", "x = (!(a == b))"},
+		{"x = +n", "x = (+n)"},
+		{"print -1", "print (-1)"},
+		{"print abs(-n)", "print abs((-n))"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.NewLexer(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("for input %q: expected 1 statement, got %d", tt.input, len(program.Statements))
+		}
+		if got := program.Statements[0].String(); got != tt.expected {
+			t.Errorf("for input %q: expected=%q, got=%q", tt.input, tt.expected, got)
+		}
+	}
+
+	// In a command's arguments -name is still an option
+	p := New(lexer.NewLexer("ls -la"))
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+	cmd := program.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.Command)
+	testFlag(t, cmd.Arguments[0], "-la")
+}
+
+func TestMultiplePathArguments(t *testing.T) {
+	input := "ls /tmp /var/log ~/docs"
+	l := lexer.NewLexer(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	cmd := stmt.Expression.(*ast.Command)
+
+	if len(cmd.Arguments) != 3 {
+		t.Fatalf("wrong number of arguments. expected=3, got=%d", len(cmd.Arguments))
+	}
+
+	testPath(t, cmd.Arguments[0], "/tmp")
+	testPath(t, cmd.Arguments[1], "/var/log")
+	testPath(t, cmd.Arguments[2], "~/docs")
+}
+
+func TestTildeAsCommandArgument(t *testing.T) {
+	input := "rm -r ~"
+	l := lexer.NewLexer(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	cmd := stmt.Expression.(*ast.Command)
+
+	if len(cmd.Arguments) != 2 {
+		t.Fatalf("wrong number of arguments. expected=2, got=%d", len(cmd.Arguments))
+	}
+
+	testFlag(t, cmd.Arguments[0], "-r")
+	testPath(t, cmd.Arguments[1], "~")
+}
+
 // Helper functions
 
+func TestWhereCondition(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"ls | where size > 1000 | sort-by mtime", "((ls | where (size > 1000)) | sort-by mtime)"},
+		{`ls | where type == "dir"`, `(ls | where (type == "dir"))`},
+		{"ls | where size < 10 + 5", "(ls | where (size < (10 + 5)))"},
+		{"ls | where size > 10 > big.txt", "((ls | where (size > 10)) > big.txt)"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.NewLexer(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("for input %q: expected 1 statement, got %d", tt.input, len(program.Statements))
+		}
+		if got := program.Statements[0].String(); got != tt.expected {
+			t.Errorf("for input %q: expected=%q, got=%q", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestMatchAndFunctionCalls(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`ok = line =~ "^v(\d+)"`, `ok = (line =~ "^v(\d+)")`},
+		{`ls | where name =~ "txt$"`, `(ls | where (name =~ "txt$"))`},
+		{`parts = split(line, ",")`, `parts = split(line, ",")`},
+		{`print replace(s, "a", "b")`, `print replace(s, "a", "b")`},
+		{`words = sort(words)`, `words = sort(words)`},
+	}
+
+	for _, tt := range tests {
+		l := lexer.NewLexer(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("for input %q: expected 1 statement, got %d", tt.input, len(program.Statements))
+		}
+		if got := program.Statements[0].String(); got != tt.expected {
+			t.Errorf("for input %q: expected=%q, got=%q", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestSlicesAndElementAssignment(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"x = arr[1:3]", "x = (arr[1:3])"},
+		{"x = arr[:n + 1]", "x = (arr[:(n + 1)])"},
+		{"x = s[2:]", "x = (s[2:])"},
+		{"arr[0] = 5", "(arr[0]) = 5"},
+		{`rows[1]["name"] = "x"`, `((rows[1])["name"]) = "x"`},
+	}
+
+	for _, tt := range tests {
+		l := lexer.NewLexer(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("for input %q: expected 1 statement, got %d", tt.input, len(program.Statements))
+		}
+		if got := program.Statements[0].String(); got != tt.expected {
+			t.Errorf("for input %q: expected=%q, got=%q", tt.input, tt.expected, got)
+		}
+	}
+
+	// A command's arguments stop before an element assignment
+	l := lexer.NewLexer("print x arr[0] = 1")
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+	if len(program.Statements) != 2 {
+		t.Fatalf("expected 2 statements, got %d", len(program.Statements))
+	}
+
+	p = New(lexer.NewLexer("arr[1:2] = x"))
+	p.ParseProgram()
+	if len(p.Errors()) == 0 {
+		t.Error("expected an error assigning to a slice")
+	}
+}
+
+func TestTypedArrayLiterals(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+		hint     string
+		elements int
+	}{
+		{"xs = []int", "xs = []int", "int", 0},
+		{"xs = []int[1, 2, 3]", "xs = []int[1, 2, 3]", "int", 3},
+		{`xs = []string["a"]`, `xs = []string["a"]`, "string", 1},
+		{"xs = [1, 2]", "xs = [1, 2]", "", 2},
+	}
+
+	for _, tt := range tests {
+		l := lexer.NewLexer(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if got := program.Statements[0].String(); got != tt.expected {
+			t.Errorf("for input %q: expected=%q, got=%q", tt.input, tt.expected, got)
+		}
+		array, ok := program.Statements[0].(*ast.AssignmentStatement).Value.(*ast.ArrayLiteral)
+		if !ok {
+			t.Fatalf("for input %q: value is not *ast.ArrayLiteral", tt.input)
+		}
+		if array.TypeHint != tt.hint || len(array.Elements) != tt.elements {
+			t.Errorf("for input %q: expected hint %q with %d elements, got %q with %d",
+				tt.input, tt.hint, tt.elements, array.TypeHint, len(array.Elements))
+		}
+	}
+}
+
+func TestTryStatement(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"try { rm old } catch err { print err } finally { print done }",
+			"try { rm old } catch err { print err } finally { print done }"},
+		{"try { rm old } catch { print failed }", "try { rm old } catch { print failed }"},
+		{"try { rm old } finally { print done }", "try { rm old } finally { print done }"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.NewLexer(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if _, ok := program.Statements[0].(*ast.TryStatement); !ok {
+			t.Fatalf("for input %q: statement is not *ast.TryStatement. got=%T", tt.input, program.Statements[0])
+		}
+		if got := program.Statements[0].String(); got != tt.expected {
+			t.Errorf("for input %q: expected=%q, got=%q", tt.input, tt.expected, got)
+		}
+	}
+
+	p := New(lexer.NewLexer("try { rm old }"))
+	p.ParseProgram()
+	if len(p.Errors()) == 0 || p.Errors()[0] != "try needs a catch or finally block" {
+		t.Errorf("expected an error for try without catch or finally, got %v", p.Errors())
+	}
+}
+
+func TestElseIf(t *testing.T) {
+	input := "if x > 10 { print large } else if x > 5 { print medium } else { print small }"
+	program := New(lexer.NewLexer(input)).ParseProgram()
+
+	stmt, ok := program.Statements[0].(*ast.IfStatement)
+	if !ok {
+		t.Fatalf("statement is not *ast.IfStatement. got=%T", program.Statements[0])
+	}
+	if len(stmt.Alternative.Statements) != 1 {
+		t.Fatalf("else block should hold one statement. got=%d", len(stmt.Alternative.Statements))
+	}
+	elseIf, ok := stmt.Alternative.Statements[0].(*ast.IfStatement)
+	if !ok {
+		t.Fatalf("else block does not hold an *ast.IfStatement. got=%T", stmt.Alternative.Statements[0])
+	}
+	if elseIf.Condition.String() != "(x > 5)" || elseIf.Alternative == nil {
+		t.Errorf("wrong else if. got=%q", elseIf.String())
+	}
+}
+
+func TestMatchStatement(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`match x { "a" => { print a }, 1 | 2 => { print n }, _ => { print other } }`,
+			`match x { "a" => { print a } 1 | 2 => { print n } _ => { print other } }`},
+		{"match name {\n    \"*.txt\" => { print text }\n    =~ \"^v\\d\" => { print version }\n}",
+			`match name { "*.txt" => { print text } =~ "^v\d" => { print version } }`},
+		{`match n { -1 => { print neg } }`, `match n { (-1) => { print neg } }`},
+	}
+
+	for _, tt := range tests {
+		l := lexer.NewLexer(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt, ok := program.Statements[0].(*ast.MatchStatement)
+		if !ok {
+			t.Fatalf("for input %q: statement is not *ast.MatchStatement. got=%T", tt.input, program.Statements[0])
+		}
+		if got := stmt.String(); got != tt.expected {
+			t.Errorf("for input %q: expected=%q, got=%q", tt.input, tt.expected, got)
+		}
+	}
+
+	p := New(lexer.NewLexer(`match x { "a" { print a } }`))
+	p.ParseProgram()
+	if len(p.Errors()) == 0 {
+		t.Errorf("expected an error for an arm without =>")
+	}
+}
+
+func TestMatchAsName(t *testing.T) {
+	// match is a keyword only when a value and { follow it
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"match = 5", "match = 5"},
+		{"match = [1, 2]\nprint match[0]", "match = [1, 2]print (match[0])"},
+		{"x = match + 1", "x = (match + 1)"},
+		{"print match", "print match"},
+		{"for match in items { print match }", "for match in items { print match }"},
+		{"switch = 1\nprint switch", "switch = 1print switch"},
+		{"match match { 1 => { print one } }", "match match { 1 => { print one } }"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.NewLexer(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if got := program.String(); got != tt.expected {
+			t.Errorf("for input %q: expected=%q, got=%q", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestCommandNamesAsVariables(t *testing.T) {
+	// Names like head and find are commands only where a command can stand
+	tests := []struct {
+		input   string
+		name    string
+		command bool
+	}{
+		{"head = 3", "head", false},
+		{`find = "x"`, "find", false},
+		{"tail[0] = 1", "tail", false},
+		{"x = head + 1", "head", false},
+		{"x = head", "head", false},
+		{"print head", "head", false},
+		{"grep sort notes", "sort", false},
+		{"x = [head, tail]", "tail", false},
+		{"head", "head", true},
+		{"head -n 2 notes", "head", true},
+		{"x = find src --type f", "find", true},
+		{"ls | sort", "sort", true},
+		{"print len(find src)", "find", true},
+	}
+
+	for _, tt := range tests {
+		l := lexer.NewLexer(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		node := findName(program.Statements[0], tt.name)
+		if node == nil {
+			t.Errorf("for input %q: %s not found in %q", tt.input, tt.name, program.String())
+			continue
+		}
+		if _, isCommand := node.(*ast.Command); isCommand != tt.command {
+			t.Errorf("for input %q: expected %s as a command: %v, got %T", tt.input, tt.name, tt.command, node)
+		}
+	}
+}
+
+// findName returns the first identifier or command called name in node
+func findName(node ast.Node, name string) ast.Node {
+	var children []ast.Node
+	switch n := node.(type) {
+	case *ast.Identifier:
+		if n.Value == name {
+			return n
+		}
+	case *ast.Command:
+		if n.Name == name {
+			return n
+		}
+		for _, arg := range n.Arguments {
+			children = append(children, arg)
+		}
+	case *ast.ExpressionStatement:
+		children = append(children, n.Expression)
+	case *ast.AssignmentStatement:
+		children = append(children, n.Target, n.Value)
+	case *ast.InfixExpression:
+		children = append(children, n.Left, n.Right)
+	case *ast.PipeExpression:
+		children = append(children, n.Left, n.Right)
+	case *ast.IndexExpression:
+		children = append(children, n.Left, n.Index)
+	case *ast.CallExpression:
+		for _, arg := range n.Arguments {
+			children = append(children, arg)
+		}
+	case *ast.ArrayLiteral:
+		for _, elem := range n.Elements {
+			children = append(children, elem)
+		}
+	}
+	for _, child := range children {
+		if found := findName(child, name); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+func TestExternalCommands(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"git status --short", "git status --short"},
+		{"make | tail -n 3", "(make | tail -n 3)"},
+		{"rows | select name", "(rows | select name)"},
+		{`rev = git "rev-parse" HEAD`, `rev = git "rev-parse" HEAD`},
+		{"x = count + 1", "x = (count + 1)"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.NewLexer(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("for input %q: expected 1 statement, got %d", tt.input, len(program.Statements))
+		}
+		if got := program.Statements[0].String(); got != tt.expected {
+			t.Errorf("for input %q: expected=%q, got=%q", tt.input, tt.expected, got)
+		}
+	}
+
+	// A program run with an option that could be a subtraction keeps that
+	// reading too, for when its name is a variable
+	program := New(lexer.NewLexer("y -z")).ParseProgram()
+	cmd, ok := program.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.Command)
+	if !ok || cmd.Expression == nil || cmd.Expression.String() != "(y - z)" {
+		t.Errorf("expected y -z to keep the reading (y - z), got %s", program.Statements[0])
+	}
+
+	// A program's arguments, like a built-in command's, end with the line
+	program = New(lexer.NewLexer("git pull\nmake install\nprint done\nlater")).ParseProgram()
+	if len(program.Statements) != 4 {
+		t.Fatalf("expected 4 statements, got %d", len(program.Statements))
+	}
+	cmd, ok = program.Statements[1].(*ast.ExpressionStatement).Expression.(*ast.Command)
+	if !ok || cmd.Type != ast.CMD_EXTERNAL || cmd.Name != "make" || len(cmd.Arguments) != 1 {
+		t.Errorf("expected make with one argument, got %s", program.Statements[1])
+	}
+}
+
 func checkParserErrors(t *testing.T, p *Parser) {
 	errors := p.Errors()
 	if len(errors) == 0 {
@@ -541,3 +1098,15 @@ func testPath(t *testing.T, exp ast.Expression, expectedValue string) {
 		t.Errorf("path.Value not %s. got=%s", expectedValue, path.Value)
 	}
 }
+
+func testFlag(t *testing.T, exp ast.Expression, expectedValue string) {
+	t.Helper()
+	flag, ok := exp.(*ast.FlagLiteral)
+	if !ok {
+		t.Errorf("exp not *ast.FlagLiteral. got=%T", exp)
+		return
+	}
+	if flag.Value != expectedValue {
+		t.Errorf("flag.Value not %s. got=%s", expectedValue, flag.Value)
+	}
+}
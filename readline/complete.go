@@ -0,0 +1,371 @@
+package readline
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Provider supplies the names tab completion offers. The shell implements
+// it, so completion sees the variables and commands that exist when Tab is
+// pressed.
+type Provider interface {
+	Commands() []string            // built-in commands
+	Functions() []string           // built-in functions, completed with (
+	Variables() []string           // script variables
+	EnvVars() []string             // names $NAME expands
+	Executables() []string         // programs on $PATH
+	Flags(command string) []string // options of a built-in command
+}
+
+// noNames is the Provider of a Readline that hasn't been given one: it has
+// no names, so only paths complete. The shell gives its own, which knows
+// the commands it has.
+type noNames struct{}
+
+func (noNames) Commands() []string    { return nil }
+func (noNames) Functions() []string   { return nil }
+func (noNames) Variables() []string   { return nil }
+func (noNames) EnvVars() []string     { return nil }
+func (noNames) Executables() []string { return nil }
+func (noNames) Flags(string) []string { return nil }
+
+// maxMenuRows bounds the rows of completions shown below the line; the
+// rest scroll into view as Tab moves through them
+const maxMenuRows = 10
+
+// completionMenu holds the completions shown below the line when Tab finds
+// several. Pressing Tab again puts each one in the line in turn.
+type completionMenu struct {
+	items    []string
+	selected int    // index of the item in the line, -1 before Tab cycles
+	start    int    // where the word being completed starts
+	tail     []rune // the line after the word
+}
+
+// SetProvider sets where completion finds commands, functions, variables,
+// programs and options
+func (r *Readline) SetProvider(p Provider) {
+	r.provider = p
+}
+
+// complete returns completions for the current input
+func (r *Readline) complete(line string, pos int) []string {
+	// Use custom completer if set
+	if r.completer != nil {
+		return r.completer(line, pos)
+	}
+
+	// Default completion
+	return r.defaultComplete(line, pos)
+}
+
+// defaultComplete offers what fits where the cursor is: $ names, commands
+// and programs at the start of a command, variables and functions inside
+// brackets, options after -, directories after cd, and otherwise paths
+// and values
+func (r *Readline) defaultComplete(line string, pos int) []string {
+	runes := []rune(line)
+	start := wordStart(runes, pos)
+	word := string(runes[start:pos])
+	before := strings.TrimRight(string(runes[:start]), " ")
+
+	switch {
+	case strings.HasPrefix(word, "$"):
+		return matching(word, "$", "", r.provider.EnvVars())
+	case atCommandStart(before):
+		matches := matching(word, "", "", r.provider.Commands(), r.provider.Executables(), r.provider.Variables())
+		return append(matches, matching(word, "", "(", r.provider.Functions())...)
+	case strings.HasSuffix(before, "(") || strings.HasSuffix(before, "[") || strings.HasSuffix(before, ","):
+		matches := matching(word, "", "", r.provider.Variables())
+		return append(matches, matching(word, "", "(", r.provider.Functions())...)
+	}
+
+	command := segmentCommand(before)
+	if strings.HasPrefix(word, "-") {
+		return matching(word, "", "", r.provider.Flags(command))
+	}
+	matches := r.completePath(word, command == "cd")
+	if word != "" && command != "cd" && !strings.ContainsAny(word, "/.~") {
+		// Arguments can be values too: print count, print len(name)
+		matches = append(matches, matching(word, "", "", r.provider.Variables())...)
+		matches = append(matches, matching(word, "", "(", r.provider.Functions())...)
+	}
+	return matches
+}
+
+// wordStart returns where the word ending at pos starts. Words end at
+// spaces, quotes, brackets, pipes, commas and =, so --name=value and
+// len(x completes the value.
+func wordStart(line []rune, pos int) int {
+	start := pos
+	for start > 0 && !strings.ContainsRune(" \"'|()[]{},=", line[start-1]) {
+		start--
+	}
+	return start
+}
+
+// atCommandStart reports whether a word following before starts a command:
+// it is first on the line, or follows a pipe, a brace or an assignment
+func atCommandStart(before string) bool {
+	return before == "" || strings.HasSuffix(before, "|") || strings.HasSuffix(before, "{") ||
+		strings.HasSuffix(before, " =")
+}
+
+// segmentCommand returns the command name of the pipeline stage before
+// ends in
+func segmentCommand(before string) string {
+	if i := strings.LastIndexAny(before, "|{"); i != -1 {
+		before = before[i+1:]
+	}
+	if i := strings.LastIndex(before, " = "); i != -1 {
+		before = before[i+3:]
+	}
+	fields := strings.Fields(before)
+	if len(fields) == 0 {
+		return ""
+	}
+	return fields[0]
+}
+
+// matching returns the names in lists that start with word once prefix is
+// added, each with prefix and suffix added, without duplicates
+func matching(word, prefix, suffix string, lists ...[]string) []string {
+	var matches []string
+	seen := make(map[string]bool)
+	for _, list := range lists {
+		for _, name := range list {
+			candidate := prefix + name + suffix
+			if strings.HasPrefix(candidate, word) && !seen[candidate] {
+				seen[candidate] = true
+				matches = append(matches, candidate)
+			}
+		}
+	}
+	return matches
+}
+
+// completePath returns matching file/directory paths, or only directories
+func (r *Readline) completePath(prefix string, dirsOnly bool) []string {
+	cwd := "."
+	if r.cwd != nil {
+		cwd = r.cwd()
+	}
+
+	// Handle different path prefixes
+	searchDir := cwd
+	searchPrefix := prefix
+
+	if prefix == "" {
+		searchDir = cwd
+		searchPrefix = ""
+	} else if strings.HasPrefix(prefix, "~/") {
+		home, err := os.UserHomeDir()
+		if err == nil {
+			if idx := strings.LastIndex(prefix, "/"); idx != -1 {
+				searchDir = filepath.Join(home, prefix[2:idx+1])
+				searchPrefix = prefix[idx+1:]
+			} else {
+				searchDir = home
+				searchPrefix = prefix[2:]
+			}
+		}
+	} else if strings.HasPrefix(prefix, "/") {
+		if idx := strings.LastIndex(prefix, "/"); idx != -1 {
+			searchDir = prefix[:idx+1]
+			searchPrefix = prefix[idx+1:]
+		}
+	} else if strings.Contains(prefix, "/") {
+		if idx := strings.LastIndex(prefix, "/"); idx != -1 {
+			searchDir = filepath.Join(cwd, prefix[:idx+1])
+			searchPrefix = prefix[idx+1:]
+		}
+	} else {
+		searchDir = cwd
+		searchPrefix = prefix
+	}
+
+	entries, err := os.ReadDir(searchDir)
+	if err != nil {
+		return nil
+	}
+
+	var matches []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if strings.HasPrefix(name, searchPrefix) {
+			isDir := entry.IsDir()
+			if entry.Type()&os.ModeSymlink != 0 {
+				if info, err := os.Stat(filepath.Join(searchDir, name)); err == nil {
+					isDir = info.IsDir()
+				}
+			}
+			if dirsOnly && !isDir {
+				continue
+			}
+			// Build the full completion
+			completion := name
+			if prefix != "" {
+				if idx := strings.LastIndex(prefix, "/"); idx != -1 {
+					completion = prefix[:idx+1] + name
+				}
+			}
+			if isDir {
+				completion += "/"
+			}
+			matches = append(matches, completion)
+		}
+	}
+
+	return matches
+}
+
+// tabComplete handles Tab. One completion goes straight into the line; of
+// several, their common start is filled in, and if there is none to add a
+// menu of them opens below the line. While the menu is open each Tab puts
+// the next one in the line.
+func (r *Readline) tabComplete(line []rune, pos int) ([]rune, int) {
+	if r.menu != nil {
+		return r.cycleMenu(line, 1)
+	}
+
+	completions := r.complete(string(line), pos)
+	start := wordStart(line, pos)
+	switch len(completions) {
+	case 0:
+		fmt.Print("\a")
+		return line, pos
+	case 1:
+		return r.applyCompletion(line, pos, completions[0])
+	}
+
+	if prefix := commonPrefix(completions); len([]rune(prefix)) > pos-start {
+		newLine := append(append([]rune{}, line[:start]...), []rune(prefix)...)
+		newPos := len(newLine)
+		return append(newLine, line[pos:]...), newPos
+	}
+
+	r.menu = &completionMenu{
+		items:    completions,
+		selected: -1,
+		start:    start,
+		tail:     append([]rune{}, line[pos:]...),
+	}
+	return line, pos
+}
+
+// cycleMenu puts the next completion of the open menu in the line, or the
+// previous one when step is -1
+func (r *Readline) cycleMenu(line []rune, step int) ([]rune, int) {
+	m := r.menu
+	if m.selected == -1 && step < 0 {
+		m.selected = len(m.items) - 1
+	} else {
+		m.selected = (m.selected + step + len(m.items)) % len(m.items)
+	}
+	newLine := append(append([]rune{}, line[:m.start]...), []rune(m.items[m.selected])...)
+	newPos := len(newLine)
+	return append(newLine, m.tail...), newPos
+}
+
+// completeBackward handles Shift+Tab: the previous completion of the open
+// menu
+func (r *Readline) completeBackward(line []rune, pos int) ([]rune, int) {
+	if r.menu == nil {
+		return line, pos
+	}
+	return r.cycleMenu(line, -1)
+}
+
+// commonPrefix returns the longest start shared by every string
+func commonPrefix(items []string) string {
+	prefix := []rune(items[0])
+	for _, item := range items[1:] {
+		runes := []rune(item)
+		n := 0
+		for n < len(prefix) && n < len(runes) && prefix[n] == runes[n] {
+			n++
+		}
+		prefix = prefix[:n]
+	}
+	return string(prefix)
+}
+
+// lines lays the menu out in columns that fit width, with the selected
+// item in reverse video. At most maxMenuRows rows are shown, scrolled to
+// keep the selection in view.
+func (m *completionMenu) lines(width int) []string {
+	labels := make([]string, len(m.items))
+	colWidth := 0
+	for i, item := range m.items {
+		label := menuLabel(item)
+		if runes := []rune(label); len(runes) > width-1 {
+			label = string(runes[:max(width-1, 1)])
+		}
+		labels[i] = label
+		colWidth = max(colWidth, len([]rune(label)))
+	}
+
+	cols := max(1, (width+2)/(colWidth+2))
+	rows := (len(labels) + cols - 1) / cols
+	top := 0
+	if selRow := m.selected / cols; m.selected >= 0 && selRow >= maxMenuRows {
+		top = selRow - maxMenuRows + 1
+	}
+
+	var lines []string
+	for row := top; row < rows && row < top+maxMenuRows; row++ {
+		var b strings.Builder
+		for col := 0; col < cols; col++ {
+			i := row*cols + col
+			if i >= len(labels) {
+				break
+			}
+			if col > 0 {
+				b.WriteString("  ")
+			}
+			cell := labels[i] + strings.Repeat(" ", colWidth-len([]rune(labels[i])))
+			if i == m.selected {
+				cell = "\033[7m" + cell + "\033[0m"
+			}
+			b.WriteString(cell)
+		}
+		lines = append(lines, b.String())
+	}
+	if rows > maxMenuRows {
+		lines = append(lines, fmt.Sprintf("-- %d completions --", len(m.items)))
+	}
+	return lines
+}
+
+// menuLabel is how a completion appears in the menu: a path by its last
+// element
+func menuLabel(item string) string {
+	if i := strings.LastIndex(strings.TrimSuffix(item, "/"), "/"); i != -1 {
+		return item[i+1:]
+	}
+	return item
+}
+
+// applyCompletion replaces the word before the cursor with completion. A
+// space follows it, except after a directory, an opening bracket or an
+// option's =, where more is typed straight on.
+func (r *Readline) applyCompletion(line []rune, pos int, completion string) ([]rune, int) {
+	wordStart := wordStart(line, pos)
+
+	// Build new line
+	newLine := string(line[:wordStart]) + completion
+	rest := ""
+	if pos < len(line) {
+		rest = string(line[pos:])
+	}
+
+	if !strings.HasSuffix(completion, "/") && !strings.HasSuffix(completion, "(") &&
+		!strings.HasSuffix(completion, "=") {
+		newLine += " "
+	}
+	newLine += rest
+
+	return []rune(newLine), len([]rune(newLine)) - len([]rune(rest))
+}
@@ -0,0 +1,119 @@
+package readline
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// testProvider is a Provider with fixed names
+type testProvider struct{}
+
+func (testProvider) Commands() []string    { return []string{"cd", "cp", "ls", "print"} }
+func (testProvider) Functions() []string   { return []string{"len", "lower"} }
+func (testProvider) Variables() []string   { return []string{"count", "limit"} }
+func (testProvider) EnvVars() []string     { return []string{"HOME", "HOSTNAME"} }
+func (testProvider) Executables() []string { return []string{"cat", "curl", "cp"} }
+func (testProvider) Flags(command string) []string {
+	if command == "ls" {
+		return []string{"--all", "--reverse", "-a", "-l"}
+	}
+	return nil
+}
+
+// newCompletionReadline returns a Readline completing from testProvider,
+// in a directory holding a file and a subdirectory
+func newCompletionReadline(t *testing.T) *Readline {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "loop.txt"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(filepath.Join(dir, "lib"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	r := New("> ")
+	r.SetProvider(testProvider{})
+	r.SetCwdFunc(func() string { return dir })
+	return r
+}
+
+func TestCompleteFromProvider(t *testing.T) {
+	tests := []struct {
+		line     string
+		expected []string
+	}{
+		{"c", []string{"cd", "cp", "cat", "curl", "count"}},
+		{"l", []string{"ls", "limit", "len(", "lower("}},
+		{"ls | c", []string{"cd", "cp", "cat", "curl", "count"}},
+		{"x = l", []string{"ls", "limit", "len(", "lower("}},
+		{"$HO", []string{"$HOME", "$HOSTNAME"}},
+		{"print $H", []string{"$HOME", "$HOSTNAME"}},
+		{"ls --", []string{"--all", "--reverse"}},
+		{"ls -", []string{"--all", "--reverse", "-a", "-l"}},
+		{"cp -", nil},
+		{"print len(c", []string{"count"}},
+		{"print [1, l", []string{"limit", "len(", "lower("}},
+		{"print l", []string{"lib/", "loop.txt", "limit", "len(", "lower("}},
+		{"cd l", []string{"lib/"}},
+		{"ls lo", []string{"loop.txt", "lower("}},
+		{"ls ./l", []string{"./lib/", "./loop.txt"}},
+		{"zz", nil},
+	}
+
+	for _, tt := range tests {
+		r := newCompletionReadline(t)
+		got := r.complete(tt.line, len(tt.line))
+		if !slices.Equal(got, tt.expected) {
+			t.Errorf("complete(%q): expected %q, got %q", tt.line, tt.expected, got)
+		}
+	}
+}
+
+func TestCompleteWithoutProvider(t *testing.T) {
+	r := New("> ")
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "ls-notes"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	r.SetCwdFunc(func() string { return dir })
+
+	if got := r.complete("l", 1); len(got) != 0 {
+		t.Errorf("expected no command names without a provider, got %q", got)
+	}
+	if got := r.complete("cat l", 5); !slices.Equal(got, []string{"ls-notes"}) {
+		t.Errorf("expected paths without a provider, got %q", got)
+	}
+}
+
+func TestTabComplete(t *testing.T) {
+	tests := []struct {
+		name     string
+		line     string
+		pos      int
+		keys     []string
+		expected string
+		pos2     int
+	}{
+		{"one match", "pri", 3, []string{"\t"}, "print ", 6},
+		{"common prefix", "ls --r", 6, []string{"\t"}, "ls --reverse ", 13},
+		{"extends to the common prefix", "$H", 2, []string{"\t"}, "$HO", 3},
+		{"menu cycles", "$HO", 3, []string{"\t", "\t"}, "$HOME", 5},
+		{"menu cycles round", "$HO", 3, []string{"\t", "\t", "\t", "\t"}, "$HOME", 5},
+		{"menu backward", "$HO", 3, []string{"\t", "\x1b[Z"}, "$HOSTNAME", 9},
+		{"enter keeps the choice", "$HO", 3, []string{"\t", "\t", "\r"}, "$HOME", 5},
+		{"before the rest of the line", "pri x", 3, []string{"\t"}, "print  x", 6},
+		{"no match", "zz", 2, []string{"\t"}, "zz", 2},
+	}
+
+	for _, tt := range tests {
+		r := newCompletionReadline(t)
+		line, pos := typeKeys(t, r, tt.line, tt.pos, tt.keys...)
+		if line != tt.expected || pos != tt.pos2 {
+			t.Errorf("%s: expected %q at %d, got %q at %d", tt.name, tt.expected, tt.pos2, line, pos)
+		}
+		if r.end != lineOpen {
+			t.Errorf("%s: completion ended the line", tt.name)
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package readline
+
+import "fmt"
+
+// The kill ring and undo. Text removed by Ctrl+U, Ctrl+K, Ctrl+W, Alt+D and
+// Alt+Backspace goes into the kill ring; kills made one after another join
+// into a single entry, the way repeated Ctrl+W yanks back as one phrase.
+// Ctrl+Y inserts the newest entry and Alt+Y straight after it swaps in the
+// one before. Every change to the line can be undone with Ctrl+_, or u in
+// vi normal mode; a run of typed characters is undone as one step, and so
+// is everything typed in one vi insert.
+
+// maxKills bounds the entries kept in the kill ring
+const maxKills = 32
+
+// Kinds of command, recorded in lastCommand so the next key can tell what
+// came before it
+const (
+	cmdInsert = "insert" // a character was typed
+	cmdKill   = "kill"
+	cmdYank   = "yank"
+	cmdUndo   = "undo"
+)
+
+// killRing holds killed text, the newest entry last, and where the last
+// yank put its text so yank-pop can replace it
+type killRing struct {
+	entries    [][]rune
+	index      int // entry the last yank inserted
+	start, end int // where in the line it went
+}
+
+// kill removes line[start:end] and saves it in the kill ring. If the key
+// before was a kill too, the text joins its entry: in front of it when
+// deleting backward from the cursor at pos, otherwise after it.
+func (r *Readline) kill(line []rune, pos, start, end int) ([]rune, int) {
+	if start >= end {
+		return line, pos
+	}
+	text := append([]rune{}, line[start:end]...)
+	ring := &r.kills
+	if n := len(ring.entries); n > 0 && r.lastCommand == cmdKill {
+		if end == pos && start < pos {
+			ring.entries[n-1] = append(text, ring.entries[n-1]...)
+		} else {
+			ring.entries[n-1] = append(ring.entries[n-1], text...)
+		}
+	} else {
+		ring.entries = append(ring.entries, text)
+		if len(ring.entries) > maxKills {
+			ring.entries = ring.entries[1:]
+		}
+	}
+	r.thisCommand = cmdKill
+	return append(line[:start], line[end:]...), start
+}
+
+// backwardKillLine kills the line before the cursor
+func (r *Readline) backwardKillLine(line []rune, pos int) ([]rune, int) {
+	return r.kill(line, pos, 0, pos)
+}
+
+// killLine kills the line after the cursor
+func (r *Readline) killLine(line []rune, pos int) ([]rune, int) {
+	return r.kill(line, pos, pos, len(line))
+}
+
+// backwardKillWord kills the word before the cursor
+func (r *Readline) backwardKillWord(line []rune, pos int) ([]rune, int) {
+	return r.kill(line, pos, prevWordStart(line, pos, true), pos)
+}
+
+// killWord kills the word after the cursor
+func (r *Readline) killWord(line []rune, pos int) ([]rune, int) {
+	return r.kill(line, pos, pos, wordEnd(line, pos))
+}
+
+// yank handles Ctrl+Y: the newest kill is inserted at the cursor
+func (r *Readline) yank(line []rune, pos int) ([]rune, int) {
+	ring := &r.kills
+	if len(ring.entries) == 0 {
+		fmt.Print("\a")
+		return line, pos
+	}
+	ring.index = len(ring.entries) - 1
+	text := ring.entries[ring.index]
+	ring.start, ring.end = pos, pos+len(text)
+	r.thisCommand = cmdYank
+	return insertRunes(line, pos, text), ring.end
+}
+
+// yankPop handles Alt+Y straight after a yank: the yanked text is replaced
+// by the kill before it, going round to the newest after the oldest
+func (r *Readline) yankPop(line []rune, pos int) ([]rune, int) {
+	ring := &r.kills
+	if r.lastCommand != cmdYank || len(ring.entries) == 0 {
+		fmt.Print("\a")
+		return line, pos
+	}
+	ring.index = (ring.index - 1 + len(ring.entries)) % len(ring.entries)
+	text := ring.entries[ring.index]
+	line = append(line[:ring.start], line[ring.end:]...)
+	ring.end = ring.start + len(text)
+	r.thisCommand = cmdYank
+	return insertRunes(line, ring.start, text), ring.end
+}
+
+// transposeChars handles Ctrl+T: the characters before and under the
+// cursor swap places and the cursor moves past them. At the end of the
+// line the last two characters swap.
+func (r *Readline) transposeChars(line []rune, pos int) ([]rune, int) {
+	if len(line) < 2 || pos == 0 {
+		fmt.Print("\a")
+		return line, pos
+	}
+	if pos == len(line) {
+		pos--
+	}
+	line[pos-1], line[pos] = line[pos], line[pos-1]
+	return line, pos + 1
+}
+
+// recordUndo saves the line as it was before the last key if that key
+// changed it. Typing continues the undo step of the characters typed just
+// before it, and in vi mode a whole insert is one step.
+func (r *Readline) recordUndo(before snapshot, line []rune) {
+	inserting := r.thisCommand == cmdInsert || (r.viMode && !r.vi.normal)
+	if r.thisCommand == cmdUndo || string(before.line) == string(line) {
+		// A key that leaves the line as it is, such as a move, ends a run
+		// of typing
+		r.undoGroup = r.undoGroup && inserting
+		return
+	}
+	if !r.undoGroup || !inserting {
+		r.undo = append(r.undo, before)
+	}
+	r.undoGroup = inserting
+}
+
+// undoAction handles Ctrl+_, ringing the bell when there is nothing left
+// to undo
+func (r *Readline) undoAction(line []rune, pos int) ([]rune, int) {
+	line, pos, ok := r.undoChange(line, pos)
+	if !ok {
+		fmt.Print("\a")
+	}
+	return line, pos
+}
+
+// undoChange handles Ctrl+_ and vi's u: the line goes back to how it was
+// before the last change
+func (r *Readline) undoChange(line []rune, pos int) ([]rune, int, bool) {
+	n := len(r.undo)
+	if n == 0 {
+		return line, pos, false
+	}
+	last := r.undo[n-1]
+	r.undo = r.undo[:n-1]
+	r.undoGroup = false
+	r.thisCommand = cmdUndo
+	return last.line, last.pos, true
+}
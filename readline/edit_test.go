@@ -0,0 +1,159 @@
+package readline
+
+import (
+	"testing"
+)
+
+func TestKillRing(t *testing.T) {
+	tests := []struct {
+		name     string
+		line     string
+		pos      int
+		keys     []string
+		expected string
+		pos2     int
+		kills    []string
+	}{
+		{"ctrl-w twice joins backward", "one two three", 13,
+			[]string{"\x17", "\x17"}, "one ", 4, []string{"two three"}},
+		{"ctrl-w twice yanks back as one", "one two three", 13,
+			[]string{"\x17", "\x17", "\x19"}, "one two three", 13, []string{"two three"}},
+		{"alt-d twice joins forward", "one two three", 0,
+			[]string{"\x1bd", "\x1bd"}, " three", 0, []string{"one two"}},
+		{"ctrl-u then ctrl-k", "abc def", 3,
+			[]string{"\x15", "\x0b", "\x19"}, "abc def", 7, []string{"abc def"}},
+		{"a move between kills", "a b c", 5,
+			[]string{"\x17", "\x1b[D", "\x17"}, "a  ", 2, []string{"c", "b"}},
+		{"typing between kills", "ab", 2,
+			[]string{"\x15", "x", "\x15", "\x19"}, "x", 1, []string{"ab", "x"}},
+		{"kill of nothing", "abc", 3,
+			[]string{"\x0b"}, "abc", 3, nil},
+	}
+
+	for _, tt := range tests {
+		r := New("> ")
+		line, pos := typeKeys(t, r, tt.line, tt.pos, tt.keys...)
+		if line != tt.expected || pos != tt.pos2 {
+			t.Errorf("%s: expected %q at %d, got %q at %d", tt.name, tt.expected, tt.pos2, line, pos)
+		}
+		var kills []string
+		for _, entry := range r.kills.entries {
+			kills = append(kills, string(entry))
+		}
+		if len(kills) != len(tt.kills) {
+			t.Errorf("%s: expected kills %q, got %q", tt.name, tt.kills, kills)
+			continue
+		}
+		for i := range kills {
+			if kills[i] != tt.kills[i] {
+				t.Errorf("%s: expected kills %q, got %q", tt.name, tt.kills, kills)
+				break
+			}
+		}
+	}
+}
+
+func TestKillRingBound(t *testing.T) {
+	r := New("> ")
+	for i := 0; i < maxKills+5; i++ {
+		typeKeys(t, r, "word", 4, "\x17", "x")
+	}
+	if len(r.kills.entries) != maxKills {
+		t.Errorf("expected %d kills kept, got %d", maxKills, len(r.kills.entries))
+	}
+}
+
+func TestYankPop(t *testing.T) {
+	tests := []struct {
+		name     string
+		line     string
+		pos      int
+		keys     []string
+		expected string
+		pos2     int
+	}{
+		{"yank", "", 0, []string{"\x19"}, "three", 5},
+		{"yank-pop", "", 0, []string{"\x19", "\x1by"}, "two", 3},
+		{"yank-pop twice", "", 0, []string{"\x19", "\x1by", "\x1by"}, "one", 3},
+		{"yank-pop goes round", "", 0, []string{"\x19", "\x1by", "\x1by", "\x1by"}, "three", 5},
+		{"yank-pop mid line", "[]", 1, []string{"\x19", "\x1by"}, "[two]", 4},
+		{"yank-pop after typing", "", 0, []string{"\x19", "x", "\x1by"}, "threex", 6},
+		{"yank-pop with no yank", "ab", 2, []string{"\x1by"}, "ab", 2},
+		{"undo a yank-pop", "", 0, []string{"\x19", "\x1by", "\x1f"}, "three", 5},
+	}
+
+	for _, tt := range tests {
+		r := New("> ")
+		r.kills.entries = [][]rune{[]rune("one"), []rune("two"), []rune("three")}
+		line, pos := typeKeys(t, r, tt.line, tt.pos, tt.keys...)
+		if line != tt.expected || pos != tt.pos2 {
+			t.Errorf("%s: expected %q at %d, got %q at %d", tt.name, tt.expected, tt.pos2, line, pos)
+		}
+	}
+
+	r := New("> ")
+	if line, pos := typeKeys(t, r, "ab", 1, "\x19"); line != "ab" || pos != 1 {
+		t.Errorf("yank with an empty ring: expected %q at 1, got %q at %d", "ab", line, pos)
+	}
+}
+
+func TestUndoGrouping(t *testing.T) {
+	tests := []struct {
+		name     string
+		line     string
+		pos      int
+		keys     []string
+		expected string
+		pos2     int
+	}{
+		{"typing is one step", "", 0, []string{"a", "b", "c", "\x1f"}, "", 0},
+		{"typed at once is one step", "", 0, []string{"abc", "\x1f"}, "", 0},
+		{"a kill is its own step", "", 0, []string{"ab", "\x17", "\x1f"}, "ab", 2},
+		{"undo past a kill", "", 0, []string{"ab", "\x17", "\x1f", "\x1f"}, "", 0},
+		{"a move ends a typing step", "", 0, []string{"ab", "\x1b[D", "c", "\x1f"}, "ab", 1},
+		{"undo both typing steps", "", 0, []string{"ab", "\x1b[D", "c", "\x1f", "\x1f"}, "", 0},
+		{"typing after undo is a new step", "", 0, []string{"ab", "\x1f", "cd", "\x1f"}, "", 0},
+		{"a move alone is not a step", "ab", 2, []string{"\x1b[D", "\x1f"}, "ab", 1},
+		{"deletes are steps", "abc", 3, []string{"\x7f", "\x7f", "\x1f"}, "ab", 2},
+		{"transpose is a step", "abc", 3, []string{"\x14", "\x1f"}, "abc", 3},
+		{"nothing to undo", "abc", 1, []string{"\x1f"}, "abc", 1},
+	}
+
+	for _, tt := range tests {
+		r := New("> ")
+		line, pos := typeKeys(t, r, tt.line, tt.pos, tt.keys...)
+		if line != tt.expected || pos != tt.pos2 {
+			t.Errorf("%s: expected %q at %d, got %q at %d", tt.name, tt.expected, tt.pos2, line, pos)
+		}
+	}
+}
+
+func TestTransposeChars(t *testing.T) {
+	tests := []struct {
+		line     string
+		pos      int
+		expected string
+		pos2     int
+	}{
+		{"abc", 0, "abc", 0},
+		{"abc", 1, "bac", 2},
+		{"abc", 2, "acb", 3},
+		{"abc", 3, "acb", 3},
+		{"a", 1, "a", 1},
+		{"", 0, "", 0},
+	}
+
+	for _, tt := range tests {
+		r := New("> ")
+		line, pos := typeKeys(t, r, tt.line, tt.pos, "\x14")
+		if line != tt.expected || pos != tt.pos2 {
+			t.Errorf("ctrl-t in %q at %d: expected %q at %d, got %q at %d",
+				tt.line, tt.pos, tt.expected, tt.pos2, line, pos)
+		}
+	}
+
+	r := New("> ")
+	if line, pos := typeKeys(t, r, "abc", 3, "\x14", "\x14"); line != "abc" || pos != 3 {
+		t.Errorf("ctrl-t twice at the end: expected %q at 3, got %q at %d", "abc", line, pos)
+	}
+}
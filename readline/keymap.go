@@ -0,0 +1,273 @@
+package readline
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// Key bindings: the keymap maps the bytes a key sends, such as "\x14" for
+// Ctrl+T or "\x1b[A" for Up, to what the key does. That is a named editor
+// action from actions, a shell command run without leaving the line, or
+// text inserted at the cursor. Printable characters with no binding insert
+// themselves. Keys are named as "ctrl-t", "alt-f", "up" or "a".
+//
+// Bindings apply in emacs mode and in vi insert mode; in vi normal mode
+// printable keys are vi commands.
+
+// action is an editor command keys are bound to. It gets the line and
+// cursor position and returns them changed.
+type action func(r *Readline, line []rune, pos int) ([]rune, int)
+
+// actions are the editor commands by the names bind uses
+var actions = map[string]action{
+	"accept_line":          (*Readline).acceptLine,
+	"backward_char":        (*Readline).backwardChar,
+	"backward_delete_char": (*Readline).backwardDeleteChar,
+	"backward_kill_line":   (*Readline).backwardKillLine,
+	"backward_kill_word":   (*Readline).backwardKillWord,
+	"backward_word":        (*Readline).backwardWord,
+	"beginning_of_line":    (*Readline).beginningOfLine,
+	"clear_screen":         (*Readline).clearScreen,
+	"complete":             (*Readline).tabComplete,
+	"complete_backward":    (*Readline).completeBackward,
+	"delete_char":          (*Readline).deleteChar,
+	"delete_char_or_eof":   (*Readline).deleteCharOrEOF,
+	"end_of_line":          (*Readline).moveToEnd,
+	"forward_char":         (*Readline).forwardChar,
+	"forward_word":         (*Readline).forwardWord,
+	"interrupt":            (*Readline).interrupt,
+	"kill_line":            (*Readline).killLine,
+	"kill_word":            (*Readline).killWord,
+	"next_history":         (*Readline).nextHistory,
+	"previous_history":     (*Readline).previousHistory,
+	"transpose_chars":      (*Readline).transposeChars,
+	"undo_change":          (*Readline).undoAction,
+	"yank":                 (*Readline).yank,
+	"yank_pop":             (*Readline).yankPop,
+}
+
+// defaultBindings are the keys every Readline starts with
+var defaultBindings = []struct{ key, action string }{
+	{"enter", "accept_line"},
+	{"ctrl-c", "interrupt"},
+	{"ctrl-d", "delete_char_or_eof"},
+	{"backspace", "backward_delete_char"},
+	{"delete", "delete_char"},
+	{"ctrl-a", "beginning_of_line"},
+	{"home", "beginning_of_line"},
+	{"ctrl-e", "end_of_line"},
+	{"end", "end_of_line"},
+	{"left", "backward_char"},
+	{"right", "forward_char"},
+	{"up", "previous_history"},
+	{"down", "next_history"},
+	{"alt-f", "forward_word"},
+	{"alt-b", "backward_word"},
+	{"ctrl-u", "backward_kill_line"},
+	{"ctrl-k", "kill_line"},
+	{"ctrl-w", "backward_kill_word"},
+	{"alt-backspace", "backward_kill_word"},
+	{"alt-d", "kill_word"},
+	{"ctrl-y", "yank"},
+	{"alt-y", "yank_pop"},
+	{"ctrl-t", "transpose_chars"},
+	{"ctrl-_", "undo_change"},
+	{"ctrl-l", "clear_screen"},
+	{"tab", "complete"},
+	{"shift-tab", "complete_backward"},
+}
+
+// namedKeys are the keys with names, and the sequences terminals send for
+// them
+var namedKeys = map[string][]string{
+	"enter":      {"\r"},
+	"tab":        {"\t"},
+	"shift-tab":  {"\x1b[Z"},
+	"space":      {" "},
+	"backspace":  {"\x7f"},
+	"delete":     {"\x1b[3~"},
+	"insert":     {"\x1b[2~"},
+	"up":         {"\x1b[A"},
+	"down":       {"\x1b[B"},
+	"right":      {"\x1b[C"},
+	"left":       {"\x1b[D"},
+	"ctrl-right": {"\x1b[1;5C"},
+	"ctrl-left":  {"\x1b[1;5D"},
+	"home":       {"\x1b[H", "\x1b[1~", "\x1bOH"},
+	"end":        {"\x1b[F", "\x1b[4~", "\x1bOF"},
+	"page-up":    {"\x1b[5~"},
+	"page-down":  {"\x1b[6~"},
+	"f1":         {"\x1bOP"},
+	"f2":         {"\x1bOQ"},
+	"f3":         {"\x1bOR"},
+	"f4":         {"\x1bOS"},
+	"f5":         {"\x1b[15~"},
+	"f6":         {"\x1b[17~"},
+	"f7":         {"\x1b[18~"},
+	"f8":         {"\x1b[19~"},
+	"f9":         {"\x1b[20~"},
+	"f10":        {"\x1b[21~"},
+	"f11":        {"\x1b[23~"},
+	"f12":        {"\x1b[24~"},
+}
+
+// binding is what a key does: one of an action, a command or text
+type binding struct {
+	action  string // name in actions
+	command string // shell command to run
+	text    string // text to insert
+}
+
+// String describes a binding the way Bindings lists it
+func (b binding) String() string {
+	switch {
+	case b.command != "":
+		return fmt.Sprintf("command %q", b.command)
+	case b.text != "":
+		return fmt.Sprintf("insert %q", b.text)
+	}
+	return b.action
+}
+
+// defaultKeymap returns the keymap of defaultBindings
+func defaultKeymap() map[string]binding {
+	keymap := make(map[string]binding)
+	for _, def := range defaultBindings {
+		seqs, err := keySequences(def.key)
+		if err != nil {
+			panic(err)
+		}
+		for _, seq := range seqs {
+			keymap[seq] = binding{action: def.action}
+		}
+	}
+	return keymap
+}
+
+// keySequences returns what the terminal sends for a named key. A name is
+// one of namedKeys, ctrl- and a letter or one of @[\]^_, alt- and a key,
+// or a single character.
+func keySequences(name string) ([]string, error) {
+	if utf8.RuneCountInString(name) == 1 {
+		if name[0] < 32 || name[0] == 127 {
+			return nil, fmt.Errorf("unknown key: %q", name)
+		}
+		return []string{name}, nil
+	}
+
+	lower := strings.ToLower(name)
+	if seqs, ok := namedKeys[lower]; ok {
+		return seqs, nil
+	}
+	if strings.HasPrefix(lower, "alt-") {
+		seqs, err := keySequences(name[len("alt-"):])
+		if err != nil {
+			return nil, err
+		}
+		withAlt := make([]string, len(seqs))
+		for i, seq := range seqs {
+			withAlt[i] = "\x1b" + seq
+		}
+		return withAlt, nil
+	}
+	if rest, ok := strings.CutPrefix(lower, "ctrl-"); ok && len(rest) == 1 {
+		if c := rest[0]; c >= 'a' && c <= 'z' || strings.IndexByte("@[\\]^_", c) != -1 {
+			return []string{string([]byte{c & 0x1f})}, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown key: %q", name)
+}
+
+// keyName returns the name of the key that sends seq
+func keyName(seq string) string {
+	for name, seqs := range namedKeys {
+		for _, s := range seqs {
+			if s == seq {
+				return name
+			}
+		}
+	}
+	switch {
+	case len(seq) == 1 && seq[0] < 32:
+		return "ctrl-" + strings.ToLower(string(rune(seq[0]|0x40)))
+	case len(seq) > 1 && seq[0] == '\x1b':
+		return "alt-" + keyName(seq[1:])
+	}
+	return seq
+}
+
+// BindAction binds a key to one of the editor actions. Its name may be
+// written with - in place of _, as in GNU readline.
+func (r *Readline) BindAction(key, name string) error {
+	name = strings.ReplaceAll(name, "-", "_")
+	if _, ok := actions[name]; !ok {
+		return fmt.Errorf("unknown action: %s", name)
+	}
+	return r.bind(key, binding{action: name})
+}
+
+// BindCommand binds a key to a shell command, run by the command runner
+// while the line being typed waits
+func (r *Readline) BindCommand(key, command string) error {
+	if r.runCommand == nil {
+		return fmt.Errorf("no command runner to run %q", command)
+	}
+	return r.bind(key, binding{command: command})
+}
+
+// BindText binds a key to text it inserts at the cursor
+func (r *Readline) BindText(key, text string) error {
+	return r.bind(key, binding{text: text})
+}
+
+// Unbind removes a key's binding; a printable key then types itself again
+func (r *Readline) Unbind(key string) error {
+	seqs, err := keySequences(key)
+	if err != nil {
+		return err
+	}
+	for _, seq := range seqs {
+		delete(r.keymap, seq)
+	}
+	return nil
+}
+
+// bind sets the binding of every sequence a key sends
+func (r *Readline) bind(key string, b binding) error {
+	seqs, err := keySequences(key)
+	if err != nil {
+		return err
+	}
+	for _, seq := range seqs {
+		r.keymap[seq] = b
+	}
+	return nil
+}
+
+// Bindings returns what each bound key does, by key name: an action name,
+// or command "..." or insert "..."
+func (r *Readline) Bindings() map[string]string {
+	bindings := make(map[string]string)
+	for seq, b := range r.keymap {
+		bindings[keyName(seq)] = b.String()
+	}
+	return bindings
+}
+
+// Actions returns the names of the editor actions, sorted
+func (r *Readline) Actions() []string {
+	names := make([]string, 0, len(actions))
+	for name := range actions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// SetCommandRunner sets how a key bound to a shell command runs it. The
+// terminal is back in its usual mode while it runs.
+func (r *Readline) SetCommandRunner(run func(command string)) {
+	r.runCommand = run
+}
@@ -0,0 +1,185 @@
+package readline
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestKeySequences(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected []string
+	}{
+		{"a", []string{"a"}},
+		{"A", []string{"A"}},
+		{"é", []string{"é"}},
+		{"ctrl-t", []string{"\x14"}},
+		{"Ctrl-T", []string{"\x14"}},
+		{"ctrl-_", []string{"\x1f"}},
+		{"ctrl-@", []string{"\x00"}},
+		{"ctrl-[", []string{"\x1b"}},
+		{"alt-f", []string{"\x1bf"}},
+		{"alt-F", []string{"\x1bF"}},
+		{"alt-ctrl-t", []string{"\x1b\x14"}},
+		{"alt-up", []string{"\x1b\x1b[A"}},
+		{"up", []string{"\x1b[A"}},
+		{"UP", []string{"\x1b[A"}},
+		{"home", []string{"\x1b[H", "\x1b[1~", "\x1bOH"}},
+		{"alt-home", []string{"\x1b\x1b[H", "\x1b\x1b[1~", "\x1b\x1bOH"}},
+		{"f12", []string{"\x1b[24~"}},
+	}
+
+	for _, tt := range tests {
+		got, err := keySequences(tt.name)
+		if err != nil {
+			t.Errorf("keySequences(%q): unexpected error: %v", tt.name, err)
+			continue
+		}
+		if !slices.Equal(got, tt.expected) {
+			t.Errorf("keySequences(%q): expected %q, got %q", tt.name, tt.expected, got)
+		}
+	}
+}
+
+func TestKeySequencesInvalid(t *testing.T) {
+	for _, name := range []string{
+		"", "\x01", "\x7f", "ctrl-", "ctrl-1", "ctrl-ab", "ctrl-up",
+		"alt-", "alt-nosuchkey", "f13", "hyper-a", "tabb",
+	} {
+		if seqs, err := keySequences(name); err == nil {
+			t.Errorf("keySequences(%q): expected an error, got %q", name, seqs)
+		}
+	}
+}
+
+func TestKeyName(t *testing.T) {
+	tests := []struct {
+		seq      string
+		expected string
+	}{
+		{"a", "a"},
+		{"\x14", "ctrl-t"},
+		{"\x1f", "ctrl-_"},
+		{"\x1bf", "alt-f"},
+		{"\x1b\x14", "alt-ctrl-t"},
+		{"\x1b[A", "up"},
+		{"\x1b\x1b[A", "alt-up"},
+		{"\x1b[3~", "delete"},
+	}
+
+	for _, tt := range tests {
+		if got := keyName(tt.seq); got != tt.expected {
+			t.Errorf("keyName(%q): expected %q, got %q", tt.seq, tt.expected, got)
+		}
+	}
+}
+
+func TestKeyNameRoundTrip(t *testing.T) {
+	for name, seqs := range namedKeys {
+		for _, seq := range seqs {
+			back, err := keySequences(keyName(seq))
+			if err != nil || !slices.Contains(back, seq) {
+				t.Errorf("%s: %q named %q, which sends %q", name, seq, keyName(seq), back)
+			}
+		}
+	}
+}
+
+func TestDefaultBindings(t *testing.T) {
+	r := New("> ")
+	for _, def := range defaultBindings {
+		if _, ok := actions[def.action]; !ok {
+			t.Errorf("%s is bound to unknown action %s", def.key, def.action)
+		}
+		if got := r.Bindings()[def.key]; got != def.action {
+			t.Errorf("%s: expected %s, got %q", def.key, def.action, got)
+		}
+	}
+}
+
+func TestRebindDefaultKey(t *testing.T) {
+	r := New("> ")
+	if err := r.BindAction("ctrl-t", "kill-line"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := r.Bindings()["ctrl-t"]; got != "kill_line" {
+		t.Errorf("expected ctrl-t bound to kill_line, got %q", got)
+	}
+	if line, pos := typeKeys(t, r, "abcdef", 2, "\x14"); line != "ab" || pos != 2 {
+		t.Errorf("rebound ctrl-t: expected %q at 2, got %q at %d", "ab", line, pos)
+	}
+
+	// Every sequence of a key with several is rebound
+	if err := r.BindText("home", "~"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, seq := range namedKeys["home"] {
+		if b := r.keymap[seq]; b.text != "~" {
+			t.Errorf("home sequence %q: expected insert \"~\", got %s", seq, b)
+		}
+	}
+	if line, pos := typeKeys(t, r, "ab", 1, "\x1bOH"); line != "a~b" || pos != 2 {
+		t.Errorf("home bound to text: expected %q at 2, got %q at %d", "a~b", line, pos)
+	}
+
+	// Rebinding one Readline leaves the defaults of others as they were
+	if got := New("> ").Bindings()["ctrl-t"]; got != "transpose_chars" {
+		t.Errorf("defaults changed by a rebind: ctrl-t is %q", got)
+	}
+}
+
+func TestBindErrors(t *testing.T) {
+	r := New("> ")
+	if err := r.BindAction("ctrl-t", "no_such_action"); err == nil {
+		t.Errorf("expected an error binding an unknown action")
+	}
+	if err := r.BindAction("nosuchkey", "yank"); err == nil {
+		t.Errorf("expected an error binding an unknown key")
+	}
+	if err := r.BindCommand("f5", "ls"); err == nil {
+		t.Errorf("expected an error binding a command with no runner")
+	}
+	if err := r.Unbind("ctrl-"); err == nil {
+		t.Errorf("expected an error unbinding an unknown key")
+	}
+	if got := r.Bindings()["ctrl-t"]; got != "transpose_chars" {
+		t.Errorf("a failed bind changed ctrl-t to %q", got)
+	}
+}
+
+func TestBindCommand(t *testing.T) {
+	r := New("> ")
+	r.SetCommandRunner(func(command string) {})
+	if err := r.BindCommand("f5", "ls"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := r.Bindings()["f5"]; got != `command "ls"` {
+		t.Errorf("expected f5 bound to command \"ls\", got %q", got)
+	}
+}
+
+func TestUnbind(t *testing.T) {
+	r := New("> ")
+	if err := r.BindText("x", "yz"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if line, _ := typeKeys(t, r, "", 0, "x"); line != "yz" {
+		t.Errorf("x bound to text: expected %q, got %q", "yz", line)
+	}
+	if err := r.Unbind("x"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if line, _ := typeKeys(t, r, "", 0, "x"); line != "x" {
+		t.Errorf("unbound x should type itself, got %q", line)
+	}
+
+	if err := r.Unbind("ctrl-t"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := r.Bindings()["ctrl-t"]; ok {
+		t.Errorf("ctrl-t still bound after Unbind")
+	}
+	if line, pos := typeKeys(t, r, "abc", 1, "\x14"); line != "abc" || pos != 1 {
+		t.Errorf("unbound ctrl-t: expected %q at 1, got %q at %d", "abc", line, pos)
+	}
+}
@@ -0,0 +1,58 @@
+package readline
+
+import (
+	"bytes"
+	"strings"
+)
+
+// Bracketed paste: while ReadLine waits for a line, the terminal is asked
+// to mark pasted text, sending pasteStart before it and pasteEnd after.
+// Pasted text is inserted as it is, newlines and all, rather than read as
+// keys, so a pasted block of several lines waits for Enter instead of the
+// first line running at once. Undo takes the whole paste back in one step.
+
+// Sequences turning bracketed paste on and off, and marking pasted text
+const (
+	pasteOn    = "\033[?2004h"
+	pasteOff   = "\033[?2004l"
+	pasteStart = "\x1b[200~"
+	pasteEnd   = "\x1b[201~"
+)
+
+// readPaste reads pasted text up to pasteEnd. Line endings become \n and
+// other control characters but tabs are dropped, so nothing pasted can act
+// as a key.
+func (r *Readline) readPaste() []rune {
+	var text []byte
+	for !bytes.HasSuffix(text, []byte(pasteEnd)) {
+		b, err := r.readByte()
+		if err != nil {
+			break
+		}
+		text = append(text, b)
+	}
+	pasted := strings.TrimSuffix(string(text), pasteEnd)
+	pasted = strings.ReplaceAll(pasted, "\r\n", "\n")
+	pasted = strings.ReplaceAll(pasted, "\r", "\n")
+	return []rune(strings.Map(func(c rune) rune {
+		if c < 32 && c != '\n' && c != '\t' || c == 127 {
+			return -1
+		}
+		return c
+	}, pasted))
+}
+
+// paste inserts pasted text at the cursor, leaving the cursor after it. In
+// vi normal mode it goes after the character under the cursor, as p puts.
+func (r *Readline) paste(line []rune, pos int) ([]rune, int) {
+	text := r.readPaste()
+	if r.viMode && r.vi.normal && len(line) > 0 && len(text) > 0 {
+		pos++
+	}
+	line = insertRunes(line, pos, text)
+	pos += len(text)
+	if r.viMode && r.vi.normal {
+		pos = normalPos(line, pos-1)
+	}
+	return line, pos
+}
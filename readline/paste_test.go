@@ -0,0 +1,80 @@
+package readline
+
+import (
+	"os"
+	"testing"
+)
+
+func TestPaste(t *testing.T) {
+	tests := []struct {
+		name     string
+		line     string
+		pos      int
+		keys     []string
+		expected string
+		pos2     int
+	}{
+		{"plain", "", 0, []string{pasteStart + "echo hi" + pasteEnd}, "echo hi", 7},
+		{"newlines", "", 0, []string{pasteStart + "a\r\nb\rc\nd" + pasteEnd}, "a\nb\nc\nd", 7},
+		{"at the cursor", "[]", 1, []string{pasteStart + "x\ny" + pasteEnd}, "[x\ny]", 4},
+		{"escape dropped", "", 0, []string{pasteStart + "a\x1bb\x1b[Ac" + pasteEnd}, "ab[Ac", 5},
+		{"control characters dropped", "", 0, []string{pasteStart + "a\x03\tb\x7f" + pasteEnd}, "a\tb", 3},
+		{"part of the end marker", "", 0, []string{pasteStart + "a\x1b[201b" + pasteEnd}, "a[201b", 6},
+		{"keys after the paste", "", 0, []string{pasteStart + "ab" + pasteEnd + "\x1f"}, "", 0},
+		{"enter in a paste", "", 0, []string{pasteStart + "a\r" + pasteEnd, "b"}, "a\nb", 3},
+		{"undone in one step", "x", 1, []string{pasteStart + "abc" + pasteEnd, "\x1f"}, "x", 1},
+	}
+
+	for _, tt := range tests {
+		r := New("> ")
+		line, pos := typeKeys(t, r, tt.line, tt.pos, tt.keys...)
+		if line != tt.expected || pos != tt.pos2 {
+			t.Errorf("%s: expected %q at %d, got %q at %d", tt.name, tt.expected, tt.pos2, line, pos)
+		}
+		if r.end != lineOpen {
+			t.Errorf("%s: a paste ended the line", tt.name)
+		}
+	}
+}
+
+func TestPasteViNormal(t *testing.T) {
+	r := newViReadline()
+	line, pos := typeKeys(t, r, "ac", 0, pasteStart+"b"+pasteEnd)
+	if line != "abc" || pos != 1 {
+		t.Errorf("expected %q at 1, got %q at %d", "abc", line, pos)
+	}
+	if !r.vi.normal {
+		t.Errorf("a paste should leave normal mode as it was")
+	}
+}
+
+func TestPasteWithoutEnd(t *testing.T) {
+	// Input ends before the end marker arrives
+	stdin := os.Stdin
+	in, out, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	out.Close()
+	os.Stdin = in
+	defer func() {
+		os.Stdin = stdin
+		in.Close()
+	}()
+
+	r := New("> ")
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.input = []byte(pasteStart + "echo hi\r\nls\x1b[20")
+	key, err := r.readKey()
+	if err != nil || key != pasteStart {
+		t.Fatalf("expected the paste start, got %q, %v", key, err)
+	}
+	line, pos := r.editKey(key, nil, 0)
+	if string(line) != "echo hi\nls[20" || pos != 13 {
+		t.Errorf("expected %q at 13, got %q at %d", "echo hi\nls[20", string(line), pos)
+	}
+	if _, err := r.readKey(); err == nil {
+		t.Errorf("expected the end of input after the paste")
+	}
+}
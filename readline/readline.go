@@ -2,53 +2,74 @@ package readline
 
 import (
 	"fmt"
+	"io"
 	"os"
-	"path/filepath"
 	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
 
 	"golang.org/x/term"
 )
 
 // Key constants
 const (
-	keyCtrlA     = 1
-	keyCtrlC     = 3
-	keyCtrlD     = 4
-	keyCtrlE     = 5
-	keyCtrlK     = 11
-	keyCtrlL     = 12
-	keyCtrlU     = 21
-	keyCtrlW     = 23
 	keyBackspace = 127
-	keyTab       = 9
-	keyEnter     = 13
 	keyEscape    = 27
 )
 
 // Completer is a function that returns completions for a given line and cursor position
 type Completer func(line string, pos int) []string
 
+// Highlighter returns line with ANSI colors added. It must not change the
+// text itself, which redraw relies on to place the cursor.
+type Highlighter func(line string) string
+
 // Readline handles interactive line editing with history and completion
 type Readline struct {
 	prompt     string
-	history    []string
+	rprompt    string // shown at the right edge of the first row, when it fits
+	history    []historyEntry
 	historyIdx int
 	completer  Completer
-	commands   []string // Built-in commands for completion
-	cwd        func() string // Function to get current working directory
+	highlight  Highlighter     // Colors the line as it is drawn, if set
+	provider   Provider        // Names for completion
+	menu       *completionMenu // Completions shown below the line, if any
+	cwd        func() string   // Function to get current working directory
+	cursorRow  int             // Terminal rows the cursor is below the prompt's first row
+	finished   bool            // The line was entered or cancelled, so shows no suggestion
+	savedLine  string          // The line being typed while history is browsed
+	input      []byte          // Bytes read from the terminal but not handled yet
+	viMode     bool            // Keys follow vi rather than emacs
+	vi         viState
+	kills      killRing
+	undo       []snapshot // The line before each change, newest last
+	undoGroup  bool       // The next typed characters join the last undo step
+	before     snapshot   // The line before the key being handled
+	// The kind of command run by the key being handled, and by the one
+	// before it, such as cmdKill
+	thisCommand, lastCommand string
+	keymap                   map[string]binding   // What each key sequence does
+	runCommand               func(command string) // Runs commands bound to keys
+	termState                *term.State          // The terminal's mode before ReadLine
+	end                      int                  // How the line ends, once an action ends it
+	insertMark               string               // Shown before the prompt in vi insert mode
+	normalMark               string               // Shown before the prompt in vi normal mode
+	// mu is held by ReadLine except while it waits for input, so a resize
+	// redraws only between keys
+	mu      sync.Mutex
+	reading bool     // ReadLine is running, so a resize redraws the line
+	shown   snapshot // The line and cursor position last drawn
 }
 
 // New creates a new Readline instance
 func New(prompt string) *Readline {
 	return &Readline{
 		prompt:     prompt,
-		history:    make([]string, 0),
+		history:    make([]historyEntry, 0),
 		historyIdx: -1,
-		commands: []string{
-			"ls", "rm", "mkdir", "rmdir", "cd", "cwd",
-			"whoami", "mkfile", "output", "print", "show",
-			"exit", "quit",
-		},
+		keymap:     defaultKeymap(),
+		provider:   noNames{},
 	}
 }
 
@@ -57,379 +78,590 @@ func (r *Readline) SetCompleter(c Completer) {
 	r.completer = c
 }
 
+// SetHighlighter sets a function that colors the line as it is typed
+func (r *Readline) SetHighlighter(h Highlighter) {
+	r.highlight = h
+}
+
 // SetCwdFunc sets a function to get current working directory for path completion
 func (r *Readline) SetCwdFunc(f func() string) {
 	r.cwd = f
 }
 
-// AddHistory adds a line to history
+// historyEntry is a line in the history and the directory it was run in
+type historyEntry struct {
+	line string
+	dir  string
+}
+
+// AddHistory adds a line to history, noting the current directory
 func (r *Readline) AddHistory(line string) {
 	if line == "" {
 		return
 	}
 	// Don't add duplicates at the end
-	if len(r.history) > 0 && r.history[len(r.history)-1] == line {
+	if len(r.history) > 0 && r.history[len(r.history)-1].line == line {
 		return
 	}
-	r.history = append(r.history, line)
+	r.history = append(r.history, historyEntry{line: line, dir: r.currentDir()})
+}
+
+// currentDir returns the shell's working directory, or "" if unknown
+func (r *Readline) currentDir() string {
+	if r.cwd == nil {
+		return ""
+	}
+	return r.cwd()
 }
 
+// How a line ends, set by the actions that end it
+const (
+	lineOpen = iota
+	lineAccepted
+	lineCancelled
+	lineEOF
+)
+
 // ReadLine reads a line with editing support
 func (r *Readline) ReadLine() (string, error) {
 	// Get terminal state
-	oldState, err := term.MakeRaw(int(os.Stdin.Fd()))
+	fd := int(os.Stdin.Fd())
+	oldState, err := term.MakeRaw(fd)
 	if err != nil {
 		return "", err
 	}
-	defer term.Restore(int(os.Stdin.Fd()), oldState)
+	r.termState = oldState
+	defer term.Restore(fd, oldState)
+	fmt.Print(pasteOn)
+	defer fmt.Print(pasteOff)
+	stop := r.watchResize()
+	defer stop()
+
+	r.mu.Lock()
+	r.reading = true
+	defer func() {
+		r.reading = false
+		r.mu.Unlock()
+	}()
 
 	// Line buffer and cursor position
 	line := []rune{}
 	pos := 0
 	r.historyIdx = len(r.history)
-	savedLine := ""
+	r.savedLine = ""
+	r.vi.reset()
+	r.undo = nil
+	r.undoGroup = false
+	r.thisCommand = ""
+	r.end = lineOpen
+	r.before = snapshot{}
 
 	// Print prompt
-	fmt.Print(r.prompt)
+	r.cursorRow = 0
+	r.finished = false
+	r.redraw(line, pos)
 
-	buf := make([]byte, 3)
 	for {
-		n, err := os.Stdin.Read(buf[:1])
-		if err != nil || n == 0 {
+		key, err := r.readKey()
+		if err != nil {
 			fmt.Println()
 			return "", err
 		}
-
-		switch buf[0] {
-		case keyEnter:
+		line, pos = r.editKey(key, line, pos)
+
+		switch r.end {
+		case lineAccepted:
+			// Leave the cursor below the whole line, however many rows it
+			// wraps to, with no suggestion left after it
+			r.finished = true
+			r.redraw(line, len(line))
 			fmt.Print("\r\n")
 			result := string(line)
 			r.AddHistory(result)
 			return result, nil
-
-		case keyCtrlC:
+		case lineCancelled:
+			r.finished = true
+			r.redraw(line, len(line))
 			fmt.Print("^C\r\n")
 			return "", nil
+		case lineEOF:
+			fmt.Print("\r\n")
+			return "", fmt.Errorf("EOF")
+		}
+		r.redraw(line, pos)
+	}
+}
 
-		case keyCtrlD:
-			if len(line) == 0 {
-				fmt.Print("\r\n")
-				return "", fmt.Errorf("EOF")
-			}
-			// Delete char under cursor
-			if pos < len(line) {
-				line = append(line[:pos], line[pos+1:]...)
-				r.redraw(line, pos)
-			}
+// editKey does what a key does to the line, returning the line and cursor
+// position after it
+func (r *Readline) editKey(key string, line []rune, pos int) ([]rune, int) {
+	r.recordUndo(r.before, line)
+	r.before = snapshot{line: append([]rune{}, line...), pos: pos}
+	r.lastCommand, r.thisCommand = r.thisCommand, ""
+
+	if key == "\x1b" {
+		// Escape pressed on its own rather than starting a sequence
+		if r.menu != nil {
+			r.closeMenu(line, pos)
+		}
+		if r.viMode && !r.vi.normal {
+			pos = r.viEscape(pos)
+		}
+		return line, pos
+	}
+	if key == pasteStart {
+		if r.menu != nil {
+			r.closeMenu(line, pos)
+		}
+		return r.paste(line, pos)
+	}
 
-		case keyBackspace:
-			if pos > 0 {
-				line = append(line[:pos-1], line[pos:]...)
-				pos--
-				r.redraw(line, pos)
-			}
+	// Any key but Tab closes the completion menu. Enter then keeps the
+	// completion chosen from it without running the line yet.
+	b, bound := r.keymap[key]
+	if r.menu != nil && b.action != "complete" && b.action != "complete_backward" {
+		chosen := r.menu.selected >= 0
+		r.closeMenu(line, pos)
+		if chosen && b.action == "accept_line" {
+			return line, pos
+		}
+	}
 
-		case keyCtrlA: // Home
-			pos = 0
-			r.redraw(line, pos)
-
-		case keyCtrlE: // End
-			pos = len(line)
-			r.redraw(line, pos)
-
-		case keyCtrlU: // Clear line before cursor
-			line = line[pos:]
-			pos = 0
-			r.redraw(line, pos)
-
-		case keyCtrlK: // Clear line after cursor
-			line = line[:pos]
-			r.redraw(line, pos)
-
-		case keyCtrlW: // Delete word before cursor
-			if pos > 0 {
-				// Find start of word
-				start := pos - 1
-				for start > 0 && line[start-1] == ' ' {
-					start--
-				}
-				for start > 0 && line[start-1] != ' ' {
-					start--
-				}
-				line = append(line[:start], line[pos:]...)
-				pos = start
-				r.redraw(line, pos)
-			}
+	switch {
+	case r.viMode && r.vi.normal && len(key) == 1 && isViCommandKey(key[0]):
+		return r.viCommand(key[0], line, pos)
+	case bound:
+		return r.runBinding(b, line, pos)
+	case len(key) == 1 && key[0] >= 32 && key[0] < 127:
+		return r.selfInsert(rune(key[0]), line, pos)
+	}
+	return line, pos // a key that isn't bound
+}
 
-		case keyCtrlL: // Clear screen
-			fmt.Print("\033[2J\033[H")
-			fmt.Print(r.prompt)
-			r.redraw(line, pos)
-
-		case keyTab:
-			completions := r.complete(string(line), pos)
-			if len(completions) == 1 {
-				// Single completion - insert it
-				newLine, newPos := r.applyCompletion(line, pos, completions[0])
-				line = newLine
-				pos = newPos
-				r.redraw(line, pos)
-			} else if len(completions) > 1 {
-				// Multiple completions - show them
-				fmt.Print("\r\n")
-				for _, c := range completions {
-					fmt.Printf("%s  ", c)
-				}
-				fmt.Print("\r\n")
-				fmt.Print(r.prompt)
-				r.redraw(line, pos)
-			}
+// runBinding does what a key is bound to
+func (r *Readline) runBinding(b binding, line []rune, pos int) ([]rune, int) {
+	switch {
+	case b.command != "":
+		r.runBoundCommand(b.command, line, pos)
+		return line, pos
+	case b.text != "":
+		return insertRunes(line, pos, []rune(b.text)), pos + len([]rune(b.text))
+	}
+	return actions[b.action](r, line, pos)
+}
 
-		case keyEscape:
-			// Read escape sequence
-			n, _ = os.Stdin.Read(buf[:2])
-			if n == 2 && buf[0] == '[' {
-				switch buf[1] {
-				case 'A': // Up arrow - history previous
-					if r.historyIdx > 0 {
-						if r.historyIdx == len(r.history) {
-							savedLine = string(line)
-						}
-						r.historyIdx--
-						line = []rune(r.history[r.historyIdx])
-						pos = len(line)
-						r.redraw(line, pos)
-					}
-
-				case 'B': // Down arrow - history next
-					if r.historyIdx < len(r.history) {
-						r.historyIdx++
-						if r.historyIdx == len(r.history) {
-							line = []rune(savedLine)
-						} else {
-							line = []rune(r.history[r.historyIdx])
-						}
-						pos = len(line)
-						r.redraw(line, pos)
-					}
-
-				case 'C': // Right arrow
-					if pos < len(line) {
-						pos++
-						fmt.Print("\033[C")
-					}
-
-				case 'D': // Left arrow
-					if pos > 0 {
-						pos--
-						fmt.Print("\033[D")
-					}
-
-				case 'H': // Home
-					pos = 0
-					r.redraw(line, pos)
-
-				case 'F': // End
-					pos = len(line)
-					r.redraw(line, pos)
-
-				case '3': // Delete key (followed by ~)
-					os.Stdin.Read(buf[:1]) // consume ~
-					if pos < len(line) {
-						line = append(line[:pos], line[pos+1:]...)
-						r.redraw(line, pos)
-					}
-
-				case '1': // Home (alternate)
-					os.Stdin.Read(buf[:1]) // consume ~
-					pos = 0
-					r.redraw(line, pos)
-
-				case '4': // End (alternate)
-					os.Stdin.Read(buf[:1]) // consume ~
-					pos = len(line)
-					r.redraw(line, pos)
-				}
-			}
+// runBoundCommand runs a shell command bound to a key below the line being
+// typed, with the terminal back in its usual mode, then draws the prompt
+// and line again under its output
+func (r *Readline) runBoundCommand(command string, line []rune, pos int) {
+	fd := int(os.Stdin.Fd())
+	r.finished = true
+	r.redraw(line, len(line))
+	fmt.Print("\r\n" + pasteOff)
+	term.Restore(fd, r.termState)
+	r.runCommand(command)
+	term.MakeRaw(fd)
+	fmt.Print(pasteOn)
+	r.cursorRow = 0
+	r.finished = false
+}
 
-		default:
-			// Regular character
-			if buf[0] >= 32 && buf[0] < 127 {
-				// Insert character at cursor position
-				ch := rune(buf[0])
-				line = append(line[:pos], append([]rune{ch}, line[pos:]...)...)
-				pos++
-				r.redraw(line, pos)
-			}
-		}
+// selfInsert types a character at the cursor
+func (r *Readline) selfInsert(ch rune, line []rune, pos int) ([]rune, int) {
+	r.thisCommand = cmdInsert
+	return insertRunes(line, pos, []rune{ch}), pos + 1
+}
+
+// acceptLine ends the line, to be run
+func (r *Readline) acceptLine(line []rune, pos int) ([]rune, int) {
+	r.end = lineAccepted
+	return line, pos
+}
+
+// interrupt abandons the line
+func (r *Readline) interrupt(line []rune, pos int) ([]rune, int) {
+	r.end = lineCancelled
+	return line, pos
+}
+
+// deleteCharOrEOF ends input on an empty line and otherwise deletes the
+// character under the cursor
+func (r *Readline) deleteCharOrEOF(line []rune, pos int) ([]rune, int) {
+	if len(line) == 0 {
+		r.end = lineEOF
+		return line, pos
 	}
+	return r.deleteChar(line, pos)
 }
 
-// redraw clears the line and redraws it with cursor at pos
-func (r *Readline) redraw(line []rune, pos int) {
-	// Move to beginning of line
-	fmt.Print("\r")
-	// Clear entire line
-	fmt.Print("\033[K")
-	// Print prompt and line
-	fmt.Print(r.prompt)
-	fmt.Print(string(line))
-	// Move cursor to correct position
+// deleteChar deletes the character under the cursor
+func (r *Readline) deleteChar(line []rune, pos int) ([]rune, int) {
 	if pos < len(line) {
-		// Move cursor back from end to pos
-		fmt.Printf("\033[%dD", len(line)-pos)
+		line = append(line[:pos], line[pos+1:]...)
+	}
+	return line, pos
+}
+
+// backwardDeleteChar deletes the character before the cursor
+func (r *Readline) backwardDeleteChar(line []rune, pos int) ([]rune, int) {
+	if pos > 0 {
+		line = append(line[:pos-1], line[pos:]...)
+		pos--
 	}
+	return line, pos
+}
+
+// beginningOfLine moves the cursor to the start of the line
+func (r *Readline) beginningOfLine(line []rune, pos int) ([]rune, int) {
+	return line, 0
 }
 
-// complete returns completions for the current input
-func (r *Readline) complete(line string, pos int) []string {
-	// Use custom completer if set
-	if r.completer != nil {
-		return r.completer(line, pos)
+// backwardChar moves the cursor left
+func (r *Readline) backwardChar(line []rune, pos int) ([]rune, int) {
+	return line, max(pos-1, 0)
+}
+
+// forwardChar moves the cursor right, or at the end of the line takes the
+// suggestion
+func (r *Readline) forwardChar(line []rune, pos int) ([]rune, int) {
+	if pos < len(line) {
+		return line, pos + 1
 	}
+	line = append(line, []rune(r.suggestion(line, pos))...)
+	return line, len(line)
+}
 
-	// Default completion
-	return r.defaultComplete(line, pos)
+// backwardWord moves the cursor to the start of the word before it
+func (r *Readline) backwardWord(line []rune, pos int) ([]rune, int) {
+	return line, prevWordStart(line, pos, true)
 }
 
-// defaultComplete provides basic command and path completion
-func (r *Readline) defaultComplete(line string, pos int) []string {
-	// Get the word being completed
-	lineUpToPos := line[:pos]
-	parts := strings.Fields(lineUpToPos)
+// previousHistory shows the history entry before the one shown
+func (r *Readline) previousHistory(line []rune, pos int) ([]rune, int) {
+	line = r.historyBack(line)
+	return line, len(line)
+}
 
-	// Check if we're completing a partial word
-	endsWithSpace := len(lineUpToPos) > 0 && lineUpToPos[len(lineUpToPos)-1] == ' '
+// nextHistory shows the history entry after the one shown
+func (r *Readline) nextHistory(line []rune, pos int) ([]rune, int) {
+	line = r.historyForward(line)
+	return line, len(line)
+}
 
-	if len(parts) == 0 || (len(parts) == 1 && !endsWithSpace) {
-		// Complete command name
-		prefix := ""
-		if len(parts) == 1 {
-			prefix = parts[0]
-		}
-		return r.completeCommand(prefix)
+// clearScreen clears the terminal, leaving the line at the top
+func (r *Readline) clearScreen(line []rune, pos int) ([]rune, int) {
+	fmt.Print("\033[2J\033[H")
+	r.cursorRow = 0
+	return line, pos
+}
+
+// readKey returns the next key: a character, a control character, or the
+// whole escape sequence of a key such as Up or Alt+F. Escape on its own
+// is returned as just ESC.
+func (r *Readline) readKey() (string, error) {
+	b, err := r.readByte()
+	if err != nil {
+		return "", err
 	}
+	// The Escape ending a change replayed by . is on its own, whatever
+	// follows it
+	if b == keyEscape && r.buffered() && !r.vi.atReplayEnd {
+		return "\x1b" + r.readEscape(), nil
+	}
+	return string([]byte{b}), nil
+}
 
-	// Complete file/directory path
-	prefix := ""
-	if !endsWithSpace {
-		prefix = parts[len(parts)-1]
+// readByte returns the next byte typed. The terminal is read as many bytes
+// at a time as are waiting, so the bytes of an escape sequence arrive
+// together and a lone Escape key can be told from the start of one.
+func (r *Readline) readByte() (byte, error) {
+	if len(r.input) == 0 {
+		buf := make([]byte, 256)
+		// Let a resize redraw the line while waiting
+		r.mu.Unlock()
+		n, err := os.Stdin.Read(buf)
+		r.mu.Lock()
+		if n == 0 {
+			if err == nil {
+				err = io.EOF
+			}
+			return 0, err
+		}
+		r.input = buf[:n]
 	}
-	return r.completePath(prefix)
+	b := r.input[0]
+	r.input = r.input[1:]
+	r.vi.advance(len(r.input))
+	r.vi.record(b)
+	return b, nil
 }
 
-// completeCommand returns matching command names
-func (r *Readline) completeCommand(prefix string) []string {
-	var matches []string
-	for _, cmd := range r.commands {
-		if strings.HasPrefix(cmd, prefix) {
-			matches = append(matches, cmd)
+// buffered reports whether bytes read along with the last one are waiting
+func (r *Readline) buffered() bool {
+	return len(r.input) > 0
+}
+
+// readEscape reads the rest of an escape sequence after its ESC: a control
+// sequence such as [A or [3~ up to its final byte, a sequence such as OH,
+// or the key pressed with Alt
+func (r *Readline) readEscape() string {
+	b, err := r.readByte()
+	if err != nil {
+		return ""
+	}
+	seq := []byte{b}
+	switch b {
+	case keyEscape:
+		// Alt with a key that sends a sequence itself, such as Alt+Up
+		if r.buffered() {
+			return "\x1b" + r.readEscape()
 		}
+	case '[':
+		for r.buffered() {
+			b, _ = r.readByte()
+			seq = append(seq, b)
+			if b >= 0x40 && b <= 0x7e {
+				break
+			}
+		}
+	case 'O':
+		if r.buffered() {
+			b, _ = r.readByte()
+			seq = append(seq, b)
+		}
+	}
+	return string(seq)
+}
+
+// historyBack returns the history entry before the one shown, keeping the
+// line being typed to come back to
+func (r *Readline) historyBack(line []rune) []rune {
+	if r.historyIdx <= 0 {
+		return line
+	}
+	if r.historyIdx == len(r.history) {
+		r.savedLine = string(line)
 	}
-	return matches
+	r.historyIdx--
+	return []rune(r.history[r.historyIdx].line)
 }
 
-// completePath returns matching file/directory paths
-func (r *Readline) completePath(prefix string) []string {
-	cwd := "."
-	if r.cwd != nil {
-		cwd = r.cwd()
+// historyForward returns the history entry after the one shown, or after
+// the last one the line that was being typed
+func (r *Readline) historyForward(line []rune) []rune {
+	if r.historyIdx >= len(r.history) {
+		return line
 	}
+	r.historyIdx++
+	if r.historyIdx == len(r.history) {
+		return []rune(r.savedLine)
+	}
+	return []rune(r.history[r.historyIdx].line)
+}
 
-	// Handle different path prefixes
-	searchDir := cwd
-	searchPrefix := prefix
+// redraw clears the prompt and line and draws them again with the cursor
+// at pos. Positions are worked out from the prompt's display width and
+// the terminal's, so a colored prompt, a line that wraps onto further rows
+// or one holding pasted newlines redraws in place. Everything is written
+// at once, so the line doesn't flicker as it is cleared and drawn.
+func (r *Readline) redraw(line []rune, pos int) {
+	width := terminalWidth()
+	prompt := r.modeIndicator() + r.prompt
+	promptWidth := displayWidth(prompt)
+	r.shown = snapshot{line: append([]rune{}, line...), pos: pos}
+	var out strings.Builder
+
+	// Go back to the prompt's first row and clear everything from there
+	if r.cursorRow > 0 {
+		fmt.Fprintf(&out, "\033[%dA", r.cursorRow)
+	}
+	out.WriteString("\r\033[J")
+	out.WriteString(prompt)
+	if r.highlight != nil {
+		out.WriteString(terminalText(r.highlight(string(line))))
+	} else {
+		out.WriteString(terminalText(string(line)))
+	}
 
-	if prefix == "" {
-		searchDir = cwd
-		searchPrefix = ""
-	} else if strings.HasPrefix(prefix, "~/") {
-		home, err := os.UserHomeDir()
-		if err == nil {
-			if idx := strings.LastIndex(prefix, "/"); idx != -1 {
-				searchDir = filepath.Join(home, prefix[2:idx+1])
-				searchPrefix = prefix[idx+1:]
-			} else {
-				searchDir = home
-				searchPrefix = prefix[2:]
-			}
-		}
-	} else if strings.HasPrefix(prefix, "/") {
-		if idx := strings.LastIndex(prefix, "/"); idx != -1 {
-			searchDir = prefix[:idx+1]
-			searchPrefix = prefix[idx+1:]
+	text := line
+	if r.menu == nil && !r.finished {
+		if suggestion := r.suggestion(line, pos); suggestion != "" {
+			out.WriteString("\033[2m" + terminalText(suggestion) + "\033[0m")
+			text = append(append([]rune{}, line...), []rune(suggestion)...)
 		}
-	} else if strings.Contains(prefix, "/") {
-		if idx := strings.LastIndex(prefix, "/"); idx != -1 {
-			searchDir = filepath.Join(cwd, prefix[:idx+1])
-			searchPrefix = prefix[idx+1:]
+	}
+
+	row, col := cursorPosition(promptWidth, text, width)
+	if col == 0 && row > 0 && (len(text) == 0 || text[len(text)-1] != '\n') {
+		// The terminal holds the cursor at the last column until the next
+		// character; move it to the new row so it is where row says
+		out.WriteString("\r\n")
+	}
+
+	if r.rprompt != "" {
+		rightWidth := displayWidth(r.rprompt)
+		if row == 0 && col+1+rightWidth <= width {
+			fmt.Fprintf(&out, "\r\033[%dC%s", width-rightWidth, r.rprompt)
 		}
-	} else {
-		searchDir = cwd
-		searchPrefix = prefix
 	}
 
-	entries, err := os.ReadDir(searchDir)
-	if err != nil {
-		return nil
-	}
-
-	var matches []string
-	for _, entry := range entries {
-		name := entry.Name()
-		if strings.HasPrefix(name, searchPrefix) {
-			// Build the full completion
-			completion := name
-			if prefix != "" {
-				if idx := strings.LastIndex(prefix, "/"); idx != -1 {
-					completion = prefix[:idx+1] + name
-				}
-			}
-			if entry.IsDir() {
-				completion += "/"
-			}
-			matches = append(matches, completion)
+	if r.menu != nil {
+		for _, menuLine := range r.menu.lines(width) {
+			out.WriteString("\r\n" + menuLine)
+			row++
 		}
 	}
 
-	return matches
+	// Move from the end of the text, or of the menu, to pos
+	targetRow, targetCol := cursorPosition(promptWidth, line[:pos], width)
+	if row > targetRow {
+		fmt.Fprintf(&out, "\033[%dA", row-targetRow)
+	}
+	out.WriteString("\r")
+	if targetCol > 0 {
+		fmt.Fprintf(&out, "\033[%dC", targetCol)
+	}
+	fmt.Print(out.String())
+	r.cursorRow = targetRow
 }
 
-// applyCompletion applies a completion to the line
-func (r *Readline) applyCompletion(line []rune, pos int, completion string) ([]rune, int) {
-	lineStr := string(line[:pos])
-	parts := strings.Fields(lineStr)
-
-	// Find the start of the word being completed
-	var wordStart int
-	if len(parts) == 0 {
-		wordStart = 0
-	} else if len(lineStr) > 0 && lineStr[len(lineStr)-1] == ' ' {
-		wordStart = pos
-	} else {
-		// Find the last word
-		wordStart = strings.LastIndex(lineStr, " ")
-		if wordStart == -1 {
-			wordStart = 0
+// cursorPosition returns the row, below the prompt's first, and column the
+// cursor is at after the prompt and text are written to a terminal width
+// columns wide. Text wraps after the last column and starts a new row at
+// each newline. The terminal holds the cursor on the last column until the
+// next character, so a newline there starts only one new row; a cursor
+// left there is given as the start of the next row. Wide characters take
+// two columns and combining marks none.
+func cursorPosition(promptWidth int, text []rune, width int) (int, int) {
+	row, col := promptWidth/width, promptWidth%width
+	atEdge := false
+	if promptWidth > 0 && col == 0 {
+		row, col, atEdge = row-1, width-1, true
+	}
+	for _, c := range text {
+		w := runeWidth(c)
+		switch {
+		case c == '\n':
+			row, col, atEdge = row+1, 0, false
+			continue
+		case c == '\t':
+			w = 1 // written as a space
+		case w == 0:
+			continue
+		case atEdge || col+w > width:
+			// A wide character that doesn't fit in the row's last column
+			// starts the next row, leaving that column empty
+			row, col, atEdge = row+1, 0, false
+		}
+		if col+w >= width {
+			col, atEdge = width-1, true
 		} else {
-			wordStart++
+			col += w
 		}
 	}
+	if atEdge {
+		row, col = row+1, 0
+	}
+	return row, col
+}
 
-	// Build new line
-	newLine := string(line[:wordStart]) + completion
-	rest := ""
-	if pos < len(line) {
-		rest = string(line[pos:])
+// terminalText returns text with its newlines written as the terminal
+// needs them in raw mode and its tabs as spaces, so each takes the room
+// cursorPosition gives it
+func terminalText(text string) string {
+	text = strings.ReplaceAll(text, "\n", "\r\n")
+	return strings.ReplaceAll(text, "\t", " ")
+}
+
+// resized redraws the line after the terminal changes size. The terminal
+// has rewrapped the rows already drawn, so the cursor's row is worked out
+// again for the new width.
+func (r *Readline) resized() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.reading {
+		return
 	}
+	prompt := r.modeIndicator() + r.prompt
+	line, pos := r.shown.line, r.shown.pos
+	r.cursorRow, _ = cursorPosition(displayWidth(prompt), line[:pos], terminalWidth())
+	r.redraw(line, pos)
+}
 
-	// Add space after completion if it's not a directory
-	if !strings.HasSuffix(completion, "/") {
-		newLine += " "
+// closeMenu closes the completion menu and redraws the line without it
+func (r *Readline) closeMenu(line []rune, pos int) {
+	r.menu = nil
+	r.redraw(line, pos)
+}
+
+// terminalWidth returns the number of columns of the terminal, or 80 when
+// stdout isn't one
+func terminalWidth() int {
+	width, _, err := term.GetSize(int(os.Stdout.Fd()))
+	if err != nil || width <= 0 {
+		return 80
 	}
-	newLine += rest
+	return width
+}
+
+// displayWidth returns the number of columns s takes up on the terminal:
+// the width of its characters, less ANSI escape sequences such as colors
+func displayWidth(s string) int {
+	width := 0
+	for i := 0; i < len(s); {
+		if s[i] == '\033' && i+1 < len(s) && s[i+1] == '[' {
+			// Skip a control sequence: parameters up to a final byte in @ to ~
+			i += 2
+			for i < len(s) && (s[i] < 0x40 || s[i] > 0x7e) {
+				i++
+			}
+			i++
+			continue
+		}
+		c, size := utf8.DecodeRuneInString(s[i:])
+		width += runeWidth(c)
+		i += size
+	}
+	return width
+}
 
-	return []rune(newLine), len([]rune(newLine)) - len([]rune(rest))
+// wideRanges are the characters a terminal shows two columns wide: East
+// Asian wide and fullwidth characters and emoji
+var wideRanges = []struct{ lo, hi rune }{
+	{0x1100, 0x115f},   // Hangul Jamo
+	{0x2e80, 0x303e},   // CJK radicals and punctuation
+	{0x3041, 0x33ff},   // Kana and CJK symbols
+	{0x3400, 0x4dbf},   // CJK extension A
+	{0x4e00, 0x9fff},   // CJK ideographs
+	{0xa000, 0xa4cf},   // Yi
+	{0xac00, 0xd7a3},   // Hangul syllables
+	{0xf900, 0xfaff},   // CJK compatibility ideographs
+	{0xfe30, 0xfe4f},   // CJK compatibility forms
+	{0xff00, 0xff60},   // Fullwidth forms
+	{0xffe0, 0xffe6},   // Fullwidth signs
+	{0x1f300, 0x1f64f}, // Pictographs and emoticons
+	{0x1f680, 0x1f6ff}, // Transport and map symbols
+	{0x1f900, 0x1f9ff}, // Supplemental pictographs
+	{0x20000, 0x2fffd}, // CJK extensions
+	{0x30000, 0x3fffd},
+}
+
+// runeWidth returns the number of columns c takes up: none for control
+// characters and marks that combine with the character before, two for
+// wide characters and one otherwise
+func runeWidth(c rune) int {
+	switch {
+	case c < 0x20 || c >= 0x7f && c < 0xa0:
+		return 0
+	case c == 0x200d || unicode.In(c, unicode.Mn, unicode.Me):
+		return 0
+	}
+	for _, r := range wideRanges {
+		if c >= r.lo && c <= r.hi {
+			return 2
+		}
+	}
+	return 1
 }
 
 // SetPrompt changes the prompt
@@ -437,8 +669,14 @@ func (r *Readline) SetPrompt(prompt string) {
 	r.prompt = prompt
 }
 
+// SetRightPrompt sets text shown at the right edge of the terminal, on the
+// prompt's row. It is left out when the line grows too long for it.
+func (r *Readline) SetRightPrompt(prompt string) {
+	r.rprompt = prompt
+}
+
 // ClearHistory clears the command history
 func (r *Readline) ClearHistory() {
-	r.history = make([]string, 0)
+	r.history = make([]historyEntry, 0)
 	r.historyIdx = -1
 }
@@ -0,0 +1,95 @@
+package readline
+
+import (
+	"testing"
+)
+
+func TestDisplayWidth(t *testing.T) {
+	tests := []struct {
+		text     string
+		expected int
+	}{
+		{"", 0},
+		{"> ", 2},
+		{"\033[1;32m>\033[0m ", 2},
+		{"\033[38;5;208mraven\033[0m:\033[34m~/src\033[0m$ ", 13},
+		{"日本> ", 6},
+		{"\033[31m日本\033[0m> ", 6},
+		{"😀 ", 3},
+		{"e\u0301> ", 3},
+		{"a\x07b", 2},
+		{"\033[", 0},
+	}
+
+	for _, tt := range tests {
+		if got := displayWidth(tt.text); got != tt.expected {
+			t.Errorf("displayWidth(%q): expected %d, got %d", tt.text, tt.expected, got)
+		}
+	}
+}
+
+func TestRuneWidth(t *testing.T) {
+	tests := []struct {
+		c        rune
+		expected int
+	}{
+		{'a', 1},
+		{'é', 1},
+		{'\u0301', 0},
+		{'\u200d', 0},
+		{'\x1b', 0},
+		{'\u0085', 0},
+		{'日', 2},
+		{'한', 2},
+		{'Ａ', 2},
+		{'😀', 2},
+		{'→', 1},
+	}
+
+	for _, tt := range tests {
+		if got := runeWidth(tt.c); got != tt.expected {
+			t.Errorf("runeWidth(%q): expected %d, got %d", tt.c, tt.expected, got)
+		}
+	}
+}
+
+func TestCursorPosition(t *testing.T) {
+	tests := []struct {
+		name        string
+		prompt      string
+		text        string
+		width       int
+		row, column int
+	}{
+		{"short", "> ", "abc", 10, 0, 5},
+		{"empty", "> ", "", 10, 0, 2},
+		{"fills the row", "> ", "abcdefgh", 10, 1, 0},
+		{"wraps", "> ", "abcdefghij", 10, 1, 2},
+		{"two wraps", "> ", "abcdefghijklmnopqrs", 10, 2, 1},
+		{"newline", "> ", "ab\ncd", 10, 1, 2},
+		{"newline at the edge", "> ", "abcdefgh\ncd", 10, 1, 2},
+		{"tab", "> ", "a\tb", 10, 0, 5},
+		{"colored prompt", "\033[1;32m>\033[0m ", "abcdefgh", 10, 1, 0},
+		{"colored prompt wraps", "\033[1;32m>\033[0m ", "abcdefghij", 10, 1, 2},
+		{"prompt fills the row", "\033[34m0123456789\033[0m", "", 10, 1, 0},
+		{"prompt fills the row then text", "\033[34m0123456789\033[0m", "a", 10, 1, 1},
+		{"prompt wraps", "\033[34m0123456789ab\033[0m", "cd", 10, 1, 4},
+		{"wide prompt", "日本> ", "ab", 10, 0, 8},
+		{"wide prompt wraps", "日本語日本> ", "ab", 10, 1, 4},
+		{"wide colored prompt", "\033[31m日本語\033[0m> ", "abc", 10, 1, 1},
+		{"wide text", "> ", "日本", 10, 0, 6},
+		{"wide text fills the row", "> ", "日本語日", 10, 1, 0},
+		{"wide text past the edge", "$ ", "a日本語日", 10, 1, 2},
+		{"wide text wraps twice", "", "日本語日本語日本語日本語", 10, 2, 4},
+		{"combining mark", "> ", "e\u0301x", 10, 0, 4},
+		{"emoji", "> ", "😀😀", 6, 1, 0},
+	}
+
+	for _, tt := range tests {
+		row, col := cursorPosition(displayWidth(tt.prompt), []rune(tt.text), tt.width)
+		if row != tt.row || col != tt.column {
+			t.Errorf("%s: expected row %d column %d, got row %d column %d",
+				tt.name, tt.row, tt.column, row, col)
+		}
+	}
+}
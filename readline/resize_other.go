@@ -0,0 +1,9 @@
+//go:build !unix
+
+package readline
+
+// watchResize does nothing on platforms without SIGWINCH; the line is
+// laid out for the new width from the next key on
+func (r *Readline) watchResize() (stop func()) {
+	return func() {}
+}
@@ -0,0 +1,31 @@
+//go:build unix
+
+package readline
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// watchResize redraws the line whenever the terminal is resized, until the
+// returned function is called
+func (r *Readline) watchResize() (stop func()) {
+	sigs := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	signal.Notify(sigs, syscall.SIGWINCH)
+	go func() {
+		for {
+			select {
+			case <-sigs:
+				r.resized()
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() {
+		signal.Stop(sigs)
+		close(done)
+	}
+}
@@ -0,0 +1,65 @@
+package readline
+
+import "strings"
+
+// Autosuggestions: as a line is typed, the rest of the most recent history
+// entry that starts with it is shown dimmed after the cursor. Entries run in
+// the current directory are preferred, since the same commands tend to be
+// run in the same place. Right arrow or End takes the whole suggestion and
+// Alt+F its next word.
+
+// suggestion returns the text that would complete line from history, or ""
+// when the cursor isn't at the end of a non-empty line or nothing matches
+func (r *Readline) suggestion(line []rune, pos int) string {
+	if len(line) == 0 || pos != len(line) {
+		return ""
+	}
+	typed := string(line)
+	dir := r.currentDir()
+
+	fallback := ""
+	for i := len(r.history) - 1; i >= 0; i-- {
+		entry := r.history[i]
+		if len(entry.line) <= len(typed) || !strings.HasPrefix(entry.line, typed) {
+			continue
+		}
+		if entry.dir == dir {
+			return entry.line[len(typed):]
+		}
+		if fallback == "" {
+			fallback = entry.line[len(typed):]
+		}
+	}
+	return fallback
+}
+
+// moveToEnd handles End: it moves the cursor to the end of the line, or if
+// it is already there takes the suggestion
+func (r *Readline) moveToEnd(line []rune, pos int) ([]rune, int) {
+	if pos == len(line) {
+		line = append(line, []rune(r.suggestion(line, pos))...)
+	}
+	return line, len(line)
+}
+
+// forwardWord handles Alt+F: it moves the cursor past the next word, or at
+// the end of the line takes the suggestion up to the end of its next word
+func (r *Readline) forwardWord(line []rune, pos int) ([]rune, int) {
+	if pos == len(line) {
+		suggestion := []rune(r.suggestion(line, pos))
+		line = append(line, suggestion[:wordEnd(suggestion, 0)]...)
+		return line, len(line)
+	}
+	return line, wordEnd(line, pos)
+}
+
+// wordEnd returns the position after the word at or following pos
+func wordEnd(text []rune, pos int) int {
+	for pos < len(text) && text[pos] == ' ' {
+		pos++
+	}
+	for pos < len(text) && text[pos] != ' ' {
+		pos++
+	}
+	return pos
+}
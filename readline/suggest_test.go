@@ -0,0 +1,96 @@
+package readline
+
+import (
+	"testing"
+)
+
+// newHistoryReadline returns a Readline whose history holds lines run in
+// the directories given after each
+func newHistoryReadline(entries ...[2]string) (*Readline, *string) {
+	r := New("> ")
+	dir := ""
+	r.SetCwdFunc(func() string { return dir })
+	for _, entry := range entries {
+		dir = entry[1]
+		r.AddHistory(entry[0])
+	}
+	dir = "/home"
+	return r, &dir
+}
+
+func TestSuggestion(t *testing.T) {
+	r, _ := newHistoryReadline(
+		[2]string{"git status", "/src"},
+		[2]string{"git stash", "/home"},
+		[2]string{"ls -l", "/home"},
+		[2]string{"git log", "/src"},
+		[2]string{"echo 日本語", "/home"},
+	)
+	tests := []struct {
+		line     string
+		pos      int
+		expected string
+	}{
+		{"git s", 5, "tash"},
+		{"git", 3, " stash"},
+		{"git l", 5, "og"},
+		{"ls", 2, " -l"},
+		{"echo 日", 6, "本語"},
+		{"git stash", 9, ""},
+		{"git stashed", 11, ""},
+		{"cd", 2, ""},
+		{"", 0, ""},
+		{"git s", 3, ""},
+		{"Git", 3, ""},
+	}
+
+	for _, tt := range tests {
+		if got := r.suggestion([]rune(tt.line), tt.pos); got != tt.expected {
+			t.Errorf("suggestion(%q, %d): expected %q, got %q", tt.line, tt.pos, tt.expected, got)
+		}
+	}
+}
+
+func TestSuggestionPrefersDirectory(t *testing.T) {
+	r, dir := newHistoryReadline(
+		[2]string{"make test", "/src"},
+		[2]string{"make clean", "/home"},
+		[2]string{"make build", "/tmp"},
+	)
+
+	*dir = "/src"
+	if got := r.suggestion([]rune("make"), 4); got != " test" {
+		t.Errorf("expected the entry run here, got %q", got)
+	}
+	*dir = "/home"
+	if got := r.suggestion([]rune("make"), 4); got != " clean" {
+		t.Errorf("expected the entry run here, got %q", got)
+	}
+	*dir = "/var"
+	if got := r.suggestion([]rune("make"), 4); got != " build" {
+		t.Errorf("expected the newest entry anywhere, got %q", got)
+	}
+}
+
+func TestTakeSuggestion(t *testing.T) {
+	tests := []struct {
+		name     string
+		line     string
+		keys     []string
+		expected string
+	}{
+		{"end", "git c", []string{"\x1b[F"}, "git commit -m wip"},
+		{"right", "git c", []string{"\x1b[C"}, "git commit -m wip"},
+		{"alt-f", "git c", []string{"\x1bf"}, "git commit"},
+		{"alt-f twice", "git c", []string{"\x1bf", "\x1bf"}, "git commit -m"},
+		{"nothing to take", "cd", []string{"\x1b[F"}, "cd"},
+	}
+
+	for _, tt := range tests {
+		r, _ := newHistoryReadline([2]string{"git commit -m wip", "/home"})
+		line, pos := typeKeys(t, r, tt.line, len([]rune(tt.line)), tt.keys...)
+		if line != tt.expected || pos != len([]rune(tt.expected)) {
+			t.Errorf("%s: expected %q at the end, got %q at %d", tt.name, tt.expected, line, pos)
+		}
+	}
+}
@@ -0,0 +1,448 @@
+package readline
+
+import (
+	"fmt"
+	"unicode"
+)
+
+// Vi mode: each line starts in insert mode, where keys type text as they
+// do otherwise, and Escape switches to normal mode, where keys are
+// commands. Motions (h l w b e W B E 0 ^ $ f t F T) move the cursor, the
+// operators d, c and y act on the text a motion moves over (dd, cc and yy
+// on the whole line), . repeats the last change and u undoes changes one
+// at a time. A count before a motion or command repeats it. The prompt
+// starts with an indicator of the mode the line is in.
+//
+// Changes are undone through the same undo stack as Ctrl+_.
+
+// viState is what vi mode keeps between keys
+type viState struct {
+	normal     bool   // In normal mode rather than insert mode
+	register   []rune // Text last deleted or yanked, put back by p and P
+	keys       []byte // Keys of the change being made, while recording
+	recording  bool   // Keys typed are added to keys
+	lastChange []byte // Keys of the last change, replayed by .
+	// Where changes replayed by . end, as the number of input bytes left
+	// after each, nearest last
+	replayEnds  []int
+	atReplayEnd bool // The byte just read ended a replayed change
+}
+
+// snapshot is a line and cursor position to go back to
+type snapshot struct {
+	line []rune
+	pos  int
+}
+
+// maxViCount bounds a count typed before a command
+const maxViCount = 999
+
+// SetViMode switches between vi keys and the default emacs-style keys
+func (r *Readline) SetViMode(on bool) {
+	r.viMode = on
+}
+
+// SetModeIndicators sets what is shown before the prompt in vi mode: one
+// text in insert mode and another in normal mode. They may hold colors.
+func (r *Readline) SetModeIndicators(insert, normal string) {
+	r.insertMark = insert
+	r.normalMark = normal
+}
+
+// modeIndicator returns what is shown before the prompt: in vi mode, the
+// indicator of the current mode
+func (r *Readline) modeIndicator() string {
+	switch {
+	case !r.viMode:
+		return ""
+	case r.vi.normal:
+		return r.normalMark
+	}
+	return r.insertMark
+}
+
+// reset starts a new line in insert mode. The register and the change .
+// repeats are kept from line to line.
+func (v *viState) reset() {
+	v.normal = false
+	v.recording = false
+}
+
+// record adds a key to the change being recorded, if any
+func (v *viState) record(b byte) {
+	if v.recording {
+		v.keys = append(v.keys, b)
+	}
+}
+
+// advance notes that a byte was read with left bytes still waiting, and
+// whether it ended a replayed change
+func (v *viState) advance(left int) {
+	v.atReplayEnd = false
+	for n := len(v.replayEnds); n > 0 && v.replayEnds[n-1] >= left; n-- {
+		v.atReplayEnd = v.atReplayEnd || v.replayEnds[n-1] == left
+		v.replayEnds = v.replayEnds[:n-1]
+	}
+}
+
+// isViCommandKey reports whether a key is handled by normal mode rather
+// than as it is otherwise; control keys such as Enter and Ctrl+C are not
+func isViCommandKey(key byte) bool {
+	return key >= 32 && key < 127 || key == keyBackspace
+}
+
+// viEscape handles Escape in insert mode: normal mode starts with the
+// cursor on the last character typed. A change that started insert mode
+// is finished and can now be repeated, and the next change is a new undo
+// step.
+func (r *Readline) viEscape(pos int) int {
+	r.vi.normal = true
+	r.undoGroup = false
+	if r.vi.recording {
+		r.vi.lastChange = r.vi.keys
+		r.vi.recording = false
+	}
+	if pos > 0 {
+		pos--
+	}
+	return pos
+}
+
+// viCommand runs the normal mode command starting with key, reading any
+// further keys it takes. A change is recorded for .; one that enters
+// insert mode goes on being recorded until Escape.
+func (r *Readline) viCommand(key byte, line []rune, pos int) ([]rune, int) {
+	r.vi.keys = []byte{key}
+	r.vi.recording = true
+
+	line, pos, changed, ok := r.viExecute(key, line, pos)
+	if !ok {
+		fmt.Print("\a")
+	}
+	if !changed || r.vi.normal {
+		if changed {
+			r.vi.lastChange = r.vi.keys
+		}
+		r.vi.recording = false
+	}
+	if r.vi.normal {
+		pos = normalPos(line, pos)
+	}
+	return line, pos
+}
+
+// viExecute runs a normal mode command. It reports whether the command
+// changed the line or entered insert mode, and whether it could be done.
+func (r *Readline) viExecute(key byte, line []rune, pos int) ([]rune, int, bool, bool) {
+	count := 1
+	if key >= '1' && key <= '9' {
+		count, key = r.viCount(key)
+	}
+
+	switch key {
+	case 'i':
+		r.vi.normal = false
+		return line, pos, true, true
+	case 'a':
+		r.vi.normal = false
+		return line, min(pos+1, len(line)), true, true
+	case 'I':
+		r.vi.normal = false
+		return line, firstNonBlank(line), true, true
+	case 'A':
+		r.vi.normal = false
+		return line, len(line), true, true
+
+	case 'x':
+		if len(line) == 0 {
+			return line, pos, false, false
+		}
+		return r.viOperate('d', line, pos, min(pos+count, len(line)))
+	case 'X':
+		if pos == 0 {
+			return line, pos, false, false
+		}
+		return r.viOperate('d', line, max(pos-count, 0), pos)
+	case 's':
+		return r.viOperate('c', line, pos, min(pos+count, len(line)))
+	case 'D', 'C':
+		return r.viOperate(unicode.ToLower(rune(key)), line, pos, len(line))
+	case 'S':
+		return r.viOperate('c', line, 0, len(line))
+
+	case 'd', 'c', 'y':
+		start, end, ok := r.viOperatorRange(key, count, line, pos)
+		if !ok {
+			return line, pos, false, false
+		}
+		return r.viOperate(rune(key), line, start, end)
+
+	case 'p', 'P':
+		if len(r.vi.register) == 0 {
+			return line, pos, false, false
+		}
+		at := pos
+		if key == 'p' && len(line) > 0 {
+			at++
+		}
+		var text []rune
+		for i := 0; i < count; i++ {
+			text = append(text, r.vi.register...)
+		}
+		return insertRunes(line, at, text), at + len(text) - 1, true, true
+
+	case 'u':
+		line, pos, ok := r.undoChange(line, pos)
+		return line, pos, false, ok
+
+	case '.':
+		if r.vi.lastChange == nil {
+			return line, pos, false, false
+		}
+		// Type the change's keys again, count times, ahead of the input
+		// waiting. Each copy's end is noted so its closing Escape isn't
+		// read together with the bytes after it as one key.
+		change := r.vi.lastChange
+		keys := make([]byte, 0, count*len(change)+len(r.input))
+		for i := 0; i < count; i++ {
+			keys = append(keys, change...)
+			r.vi.replayEnds = append(r.vi.replayEnds, len(r.input)+i*len(change))
+		}
+		r.input = append(keys, r.input...)
+		return line, pos, false, true
+
+	case 'k':
+		return r.historyBack(line), 0, false, true
+	case 'j':
+		return r.historyForward(line), 0, false, true
+	}
+
+	target, _, ok := r.viMotion(key, count, line, pos)
+	return line, target, false, ok
+}
+
+// viCount reads a count whose first digit is first, returning it and the
+// key after it
+func (r *Readline) viCount(first byte) (int, byte) {
+	count := int(first - '0')
+	for {
+		b, err := r.readByte()
+		if err != nil || b < '0' || b > '9' {
+			return count, b
+		}
+		count = min(count*10+int(b-'0'), maxViCount)
+	}
+}
+
+// viOperatorRange reads the motion after the operator key, or the operator
+// again for the whole line, and returns the range of text it covers
+func (r *Readline) viOperatorRange(op byte, count int, line []rune, pos int) (int, int, bool) {
+	key, err := r.readByte()
+	if err != nil {
+		return 0, 0, false
+	}
+	if key >= '1' && key <= '9' {
+		var n int
+		n, key = r.viCount(key)
+		count = min(count*n, maxViCount)
+	}
+	if key == op {
+		return 0, len(line), true
+	}
+
+	var target int
+	var inclusive, ok bool
+	if op == 'c' && (key == 'w' || key == 'W') && pos < len(line) && !unicode.IsSpace(line[pos]) {
+		// cw changes to the end of the word, leaving the space after it
+		target, inclusive, ok = changeWordEnd(line, pos, count, key == 'W'), true, true
+	} else {
+		target, inclusive, ok = r.viMotion(key, count, line, pos)
+	}
+	if !ok {
+		return 0, 0, false
+	}
+
+	start, end := min(pos, target), max(pos, target)
+	if inclusive && len(line) > 0 {
+		end = min(end+1, len(line))
+	}
+	return start, end, true
+}
+
+// viOperate applies an operator to line[start:end]: the text goes to the
+// register, and d deletes it, c deletes it and enters insert mode, and y
+// only copies it
+func (r *Readline) viOperate(op rune, line []rune, start, end int) ([]rune, int, bool, bool) {
+	if end > start {
+		r.vi.register = append([]rune{}, line[start:end]...)
+	}
+	switch op {
+	case 'y':
+		return line, start, false, true
+	case 'c':
+		r.vi.normal = false
+	}
+	return append(line[:start], line[end:]...), start, true, true
+}
+
+// viMotion returns where a motion key moves the cursor, and whether an
+// operator acting on it takes in the character it lands on
+func (r *Readline) viMotion(key byte, count int, line []rune, pos int) (int, bool, bool) {
+	switch key {
+	case 'h', keyBackspace:
+		return max(pos-count, 0), false, true
+	case 'l', ' ':
+		return min(pos+count, len(line)), false, true
+	case '0':
+		return 0, false, true
+	case '^':
+		return firstNonBlank(line), false, true
+	case '$':
+		return max(len(line)-1, 0), true, true
+	case 'w', 'W':
+		for i := 0; i < count; i++ {
+			pos = nextWordStart(line, pos, key == 'W')
+		}
+		return pos, false, true
+	case 'b', 'B':
+		for i := 0; i < count; i++ {
+			pos = prevWordStart(line, pos, key == 'B')
+		}
+		return pos, false, true
+	case 'e', 'E':
+		for i := 0; i < count; i++ {
+			pos = nextWordEnd(line, pos, key == 'E')
+		}
+		return pos, true, true
+	case 'f', 't', 'F', 'T':
+		c, err := r.readByte()
+		if err != nil || c == keyEscape {
+			return pos, false, false
+		}
+		return findChar(key, rune(c), count, line, pos)
+	}
+	return pos, false, false
+}
+
+// findChar handles f, t, F and T: the count-th c after or before pos, or
+// for t and T the character next to it
+func findChar(key byte, c rune, count int, line []rune, pos int) (int, bool, bool) {
+	forward := key == 'f' || key == 't'
+	at := pos
+	for found := 0; found < count; {
+		if forward {
+			at++
+		} else {
+			at--
+		}
+		if at < 0 || at >= len(line) {
+			return pos, false, false
+		}
+		if line[at] == c {
+			found++
+		}
+	}
+	switch key {
+	case 't':
+		at--
+	case 'T':
+		at++
+	}
+	return at, forward, true
+}
+
+// charClass sorts characters the way vi words do: blanks, word characters
+// (letters, digits and _) and other punctuation. For W, B and E every
+// character that isn't blank is in the same class.
+func charClass(c rune, big bool) int {
+	switch {
+	case unicode.IsSpace(c):
+		return 0
+	case big || c == '_' || unicode.IsLetter(c) || unicode.IsDigit(c):
+		return 1
+	}
+	return 2
+}
+
+// nextWordStart returns where the word after the one at pos starts, or the
+// end of the line
+func nextWordStart(line []rune, pos int, big bool) int {
+	if pos < len(line) {
+		if class := charClass(line[pos], big); class != 0 {
+			for pos < len(line) && charClass(line[pos], big) == class {
+				pos++
+			}
+		}
+	}
+	for pos < len(line) && charClass(line[pos], big) == 0 {
+		pos++
+	}
+	return pos
+}
+
+// prevWordStart returns where the word before pos starts
+func prevWordStart(line []rune, pos int, big bool) int {
+	for pos > 0 && charClass(line[pos-1], big) == 0 {
+		pos--
+	}
+	if pos > 0 {
+		class := charClass(line[pos-1], big)
+		for pos > 0 && charClass(line[pos-1], big) == class {
+			pos--
+		}
+	}
+	return pos
+}
+
+// nextWordEnd returns the last character of the word ending after pos
+func nextWordEnd(line []rune, pos int, big bool) int {
+	pos++
+	for pos < len(line) && charClass(line[pos], big) == 0 {
+		pos++
+	}
+	if pos >= len(line) {
+		return max(len(line)-1, 0)
+	}
+	class := charClass(line[pos], big)
+	for pos+1 < len(line) && charClass(line[pos+1], big) == class {
+		pos++
+	}
+	return pos
+}
+
+// changeWordEnd is where cw stops: the end of the word at pos, so on the
+// word's last character only that character changes
+func changeWordEnd(line []rune, pos, count int, big bool) int {
+	class := charClass(line[pos], big)
+	for pos+1 < len(line) && charClass(line[pos+1], big) == class {
+		pos++
+	}
+	for i := 1; i < count; i++ {
+		pos = nextWordEnd(line, pos, big)
+	}
+	return pos
+}
+
+// firstNonBlank returns the position of the first character that isn't a
+// space
+func firstNonBlank(line []rune) int {
+	for i, c := range line {
+		if !unicode.IsSpace(c) {
+			return i
+		}
+	}
+	return len(line)
+}
+
+// normalPos keeps the cursor on a character in normal mode, where it can't
+// sit after the end of the line
+func normalPos(line []rune, pos int) int {
+	return max(min(pos, len(line)-1), 0)
+}
+
+// insertRunes returns line with text inserted at pos
+func insertRunes(line []rune, pos int, text []rune) []rune {
+	result := append([]rune{}, line[:pos]...)
+	result = append(result, text...)
+	return append(result, line[pos:]...)
+}
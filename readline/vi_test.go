@@ -0,0 +1,265 @@
+package readline
+
+import (
+	"testing"
+)
+
+// typeKeys types each chunk of keys in turn into a line with the cursor at
+// pos, as if each chunk were read from the terminal at once, and returns
+// the line and cursor position after them
+func typeKeys(t *testing.T, r *Readline, line string, pos int, chunks ...string) (string, int) {
+	t.Helper()
+	l := []rune(line)
+	r.before = snapshot{line: []rune(line), pos: pos}
+	for _, chunk := range chunks {
+		r.input = append(r.input, chunk...)
+		for r.buffered() {
+			key, err := r.readKey()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			l, pos = r.editKey(key, l, pos)
+		}
+	}
+	return string(l), pos
+}
+
+// newViReadline returns a Readline in vi normal mode
+func newViReadline() *Readline {
+	r := New("> ")
+	r.SetViMode(true)
+	r.vi.normal = true
+	return r
+}
+
+func TestViCommands(t *testing.T) {
+	tests := []struct {
+		name     string
+		line     string
+		pos      int
+		keys     []string
+		expected string
+		pos2     int
+	}{
+		{"dw", "one two three", 0, []string{"dw"}, "two three", 0},
+		{"2dw", "one two three", 0, []string{"2dw"}, "three", 0},
+		{"d2w", "one two three", 4, []string{"d2w"}, "one ", 3},
+		{"dd", "one two", 3, []string{"dd"}, "", 0},
+		{"cw mid word", "one two", 0, []string{"cwsix", "\x1b"}, "six two", 2},
+		{"cw on last character", "one two", 2, []string{"cwX", "\x1b"}, "onX two", 2},
+		{"cw on last character of line", "one two", 6, []string{"cwX", "\x1b"}, "one twX", 6},
+		{"x", "abcdef", 1, []string{"x"}, "acdef", 1},
+		{"3x", "abcdef", 1, []string{"3x"}, "aef", 1},
+		{"3x past end", "abcdef", 4, []string{"3x"}, "abcd", 3},
+		{"X", "abcdef", 3, []string{"2X"}, "adef", 1},
+		{"d$", "one two", 4, []string{"d$"}, "one ", 3},
+		{"D", "one two", 2, []string{"D"}, "on", 1},
+		{"f", "a-b-c-d", 0, []string{"f-"}, "a-b-c-d", 1},
+		{"2f", "a-b-c-d", 0, []string{"2f-"}, "a-b-c-d", 3},
+		{"2t", "a-b-c-d", 0, []string{"2t-"}, "a-b-c-d", 2},
+		{"2F", "a-b-c-d", 6, []string{"2F-"}, "a-b-c-d", 3},
+		{"2T", "a-b-c-d", 6, []string{"2T-"}, "a-b-c-d", 4},
+		{"f not found", "a-b-c-d", 0, []string{"4f-"}, "a-b-c-d", 0},
+		{"df", "a-b-c-d", 0, []string{"2df-"}, "c-d", 0},
+		{"dt", "a-b-c-d", 0, []string{"dt-"}, "-b-c-d", 0},
+		{"dF", "a-b-c-d", 6, []string{"dF-"}, "a-b-cd", 5},
+		{"dT", "a-b-c-d", 6, []string{"dT-"}, "a-b-c-d", 6},
+		{"xp", "abc", 0, []string{"xp"}, "bac", 1},
+		{"xP", "abc", 1, []string{"xP"}, "abc", 1},
+		{"2p", "abc", 0, []string{"x2p"}, "baac", 2},
+		{"yy P", "ab", 0, []string{"yyP"}, "abab", 1},
+		{"p with nothing yanked", "abc", 0, []string{"p"}, "abc", 0},
+		{".", "a b c d", 0, []string{"dw", "."}, "c d", 0},
+		{"3.", "a b c d e", 0, []string{"dw", "3."}, "e", 0},
+		{". with the count of the change", "a b c d e", 0, []string{"2dw", "."}, "e", 0},
+		{". after insert", "one two three", 0, []string{"cwX", "\x1b", "w."}, "X X three", 2},
+		{". followed by keys", "one two three", 0, []string{"cwX", "\x1b", "w.x"}, "X  three", 2},
+		{"2. after x", "abcdef", 0, []string{"x", "2."}, "def", 0},
+		{"3. after insert", "ab", 0, []string{"aX", "\x1b", "3."}, "aXXXXb", 4},
+		{"u", "one two", 0, []string{"dw", "u"}, "one two", 0},
+		{"u twice", "one two three", 0, []string{"dw", "dw", "u"}, "two three", 0},
+		{"u after insert", "one", 2, []string{"afour", "\x1b", "u"}, "one", 2},
+		{"u with nothing to undo", "one", 0, []string{"u"}, "one", 0},
+	}
+
+	for _, tt := range tests {
+		r := newViReadline()
+		line, pos := typeKeys(t, r, tt.line, tt.pos, tt.keys...)
+		if line != tt.expected || pos != tt.pos2 {
+			t.Errorf("%s: expected %q at %d, got %q at %d", tt.name, tt.expected, tt.pos2, line, pos)
+		}
+	}
+}
+
+func TestViRepeatKeepsEscapeAlone(t *testing.T) {
+	r := newViReadline()
+	line, _ := typeKeys(t, r, "one two", 0, "cwX", "\x1b", "w.")
+	if line != "X X" {
+		t.Fatalf("expected %q, got %q", "X X", line)
+	}
+	if !r.vi.normal {
+		t.Errorf("the replayed Escape should leave insert mode")
+	}
+	if len(r.vi.replayEnds) != 0 {
+		t.Errorf("replay ends left over: %v", r.vi.replayEnds)
+	}
+}
+
+func TestViWordMotions(t *testing.T) {
+	line := []rune("foo.bar  baz_1 (x)")
+	tests := []struct {
+		name     string
+		fn       func([]rune, int, bool) int
+		pos      int
+		big      bool
+		expected int
+	}{
+		{"w word to punctuation", nextWordStart, 0, false, 3},
+		{"w punctuation to word", nextWordStart, 3, false, 4},
+		{"w over blanks", nextWordStart, 4, false, 9},
+		{"W", nextWordStart, 0, true, 9},
+		{"w at the end", nextWordStart, 17, false, 18},
+		{"w past the end", nextWordStart, 18, false, 18},
+		{"b", prevWordStart, 9, false, 4},
+		{"B", prevWordStart, 9, true, 0},
+		{"b mid word", prevWordStart, 12, false, 9},
+		{"b at the start", prevWordStart, 0, false, 0},
+		{"e", nextWordEnd, 0, false, 2},
+		{"e at a word end", nextWordEnd, 2, false, 3},
+		{"E", nextWordEnd, 0, true, 6},
+		{"e at the end", nextWordEnd, 17, false, 17},
+	}
+
+	for _, tt := range tests {
+		if got := tt.fn(line, tt.pos, tt.big); got != tt.expected {
+			t.Errorf("%s from %d: expected %d, got %d", tt.name, tt.pos, tt.expected, got)
+		}
+	}
+}
+
+func TestViChangeWordEnd(t *testing.T) {
+	line := []rune("one two.three")
+	tests := []struct {
+		pos, count int
+		big        bool
+		expected   int
+	}{
+		{0, 1, false, 2},
+		{2, 1, false, 2},
+		{4, 1, false, 6},
+		{4, 1, true, 12},
+		{0, 2, false, 6},
+		{0, 3, false, 7},
+		{12, 1, false, 12},
+	}
+
+	for _, tt := range tests {
+		if got := changeWordEnd(line, tt.pos, tt.count, tt.big); got != tt.expected {
+			t.Errorf("changeWordEnd(%d, %d, %v): expected %d, got %d",
+				tt.pos, tt.count, tt.big, tt.expected, got)
+		}
+	}
+}
+
+func TestViFindChar(t *testing.T) {
+	line := []rune("a,b,c,d")
+	tests := []struct {
+		key       byte
+		count     int
+		pos       int
+		expected  int
+		inclusive bool
+		ok        bool
+	}{
+		{'f', 1, 0, 1, true, true},
+		{'f', 3, 0, 5, true, true},
+		{'f', 4, 0, 0, false, false},
+		{'t', 2, 0, 2, true, true},
+		{'F', 1, 6, 5, false, true},
+		{'F', 2, 6, 3, false, true},
+		{'T', 2, 6, 4, false, true},
+		{'F', 1, 0, 0, false, false},
+	}
+
+	for _, tt := range tests {
+		at, inclusive, ok := findChar(tt.key, ',', tt.count, line, tt.pos)
+		if at != tt.expected || inclusive != tt.inclusive || ok != tt.ok {
+			t.Errorf("%d%c from %d: expected (%d, %v, %v), got (%d, %v, %v)",
+				tt.count, tt.key, tt.pos, tt.expected, tt.inclusive, tt.ok, at, inclusive, ok)
+		}
+	}
+}
+
+func TestViCharClass(t *testing.T) {
+	tests := []struct {
+		c        rune
+		big      bool
+		expected int
+	}{
+		{' ', false, 0},
+		{'\t', true, 0},
+		{'a', false, 1},
+		{'_', false, 1},
+		{'9', false, 1},
+		{'é', false, 1},
+		{'.', false, 2},
+		{'.', true, 1},
+	}
+
+	for _, tt := range tests {
+		if got := charClass(tt.c, tt.big); got != tt.expected {
+			t.Errorf("charClass(%q, %v): expected %d, got %d", tt.c, tt.big, tt.expected, got)
+		}
+	}
+}
+
+func TestViNormalPos(t *testing.T) {
+	tests := []struct {
+		line     string
+		pos      int
+		expected int
+	}{
+		{"abc", 1, 1},
+		{"abc", 3, 2},
+		{"abc", 9, 2},
+		{"", 0, 0},
+		{"", 3, 0},
+	}
+
+	for _, tt := range tests {
+		if got := normalPos([]rune(tt.line), tt.pos); got != tt.expected {
+			t.Errorf("normalPos(%q, %d): expected %d, got %d", tt.line, tt.pos, tt.expected, got)
+		}
+	}
+}
+
+func TestViOperatorRange(t *testing.T) {
+	tests := []struct {
+		op         byte
+		motion     string
+		line       string
+		pos        int
+		start, end int
+		ok         bool
+	}{
+		{'d', "w", "one two", 0, 0, 4, true},
+		{'d', "d", "one two", 3, 0, 7, true},
+		{'d', "e", "one two", 0, 0, 3, true},
+		{'c', "w", "one two", 0, 0, 3, true},
+		{'c', "w", "one two", 3, 3, 4, true},
+		{'d', "2w", "a b c", 0, 0, 4, true},
+		{'d', "b", "one two", 4, 0, 4, true},
+		{'d', "tx", "one two", 0, 0, 0, false},
+		{'d', "z", "one two", 0, 0, 0, false},
+	}
+
+	for _, tt := range tests {
+		r := New("> ")
+		r.input = []byte(tt.motion)
+		start, end, ok := r.viOperatorRange(tt.op, 1, []rune(tt.line), tt.pos)
+		if ok != tt.ok || ok && (start != tt.start || end != tt.end) {
+			t.Errorf("%c%s on %q at %d: expected (%d, %d, %v), got (%d, %d, %v)",
+				tt.op, tt.motion, tt.line, tt.pos, tt.start, tt.end, tt.ok, start, end, ok)
+		}
+	}
+}
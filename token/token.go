@@ -5,6 +5,8 @@ type TokenType string
 type Token struct {
 	Type    TokenType
 	Literal string
+	Pos     int // Byte offset of the token in the input
+	End     int // Byte offset just past the token, including any closing quote
 }
 
 const (
@@ -35,14 +37,42 @@ const (
 	FULLSTOP   TokenType = "FULLSTOP"
 	FSLASH     TokenType = "FSLASH"
 	TILDE      TokenType = "TILDE"
+	FLAG       TokenType = "FLAG" // -l, -la, --all, --name=value (command option)
+	SET        TokenType = "SET"
+	TRASH      TokenType = "TRASH"
+	UNDO       TokenType = "UNDO"
+	COPY       TokenType = "COPY"
+	MOVE       TokenType = "MOVE"
+	LINK       TokenType = "LINK"
+	TOUCH      TokenType = "TOUCH"
+	GREP       TokenType = "GREP"
+	HEAD       TokenType = "HEAD"
+	TAIL       TokenType = "TAIL"
+	WC         TokenType = "WC"
+	SORT       TokenType = "SORT"
+	UNIQ       TokenType = "UNIQ"
+	FIND       TokenType = "FIND"
+	WHERE      TokenType = "WHERE"
+	SORTBY     TokenType = "SORTBY"
+	SELECT     TokenType = "SELECT"
+	FROMJSON   TokenType = "FROMJSON"
+	TOJSON     TokenType = "TOJSON"
+	FROMCSV    TokenType = "FROMCSV"
+	TOCSV      TokenType = "TOCSV"
+	FROMTSV    TokenType = "FROMTSV"
+	TOTSV      TokenType = "TOTSV"
+	BIND       TokenType = "BIND"
 
 	// Control flow keywords
-	FOR    TokenType = "FOR"
-	IN     TokenType = "IN"
-	IF     TokenType = "IF"
-	ELSE   TokenType = "ELSE"
-	RANGE  TokenType = "RANGE"
-	APPEND TokenType = "APPEND"
+	FOR     TokenType = "FOR"
+	IN      TokenType = "IN"
+	IF      TokenType = "IF"
+	ELSE    TokenType = "ELSE"
+	TRY     TokenType = "TRY"
+	CATCH   TokenType = "CATCH"
+	FINALLY TokenType = "FINALLY"
+	RANGE   TokenType = "RANGE"
+	APPEND  TokenType = "APPEND"
 
 	// Delimiters
 	LBRACE   TokenType = "LBRACE"   // {
@@ -52,38 +82,99 @@ const (
 	LBRACKET TokenType = "LBRACKET" // [
 	RBRACKET TokenType = "RBRACKET" // ]
 	COMMA    TokenType = "COMMA"    // ,
+	COLON    TokenType = "COLON"    // : (in slices, arr[1:3])
 
 	// Operators
 	ASSIGN   TokenType = "ASSIGN"   // =
 	PLUS     TokenType = "PLUS"     // +
 	MINUS    TokenType = "MINUS"    // -
+	BANG     TokenType = "BANG"     // ! (logical not)
 	ASTERISK TokenType = "ASTERISK" // *
 	PERCENT  TokenType = "PERCENT"  // %
 	EQ       TokenType = "EQ"       // ==
 	NOT_EQ   TokenType = "NOT_EQ"   // !=
+	MATCH    TokenType = "MATCH"    // =~
+	ARROW    TokenType = "ARROW"    // => (in match arms)
 	LT       TokenType = "LT"       // < (for comparisons, different from LESS for redirection)
 	GT       TokenType = "GT"       // > (for comparisons, different from GREATER for redirection)
 	LTE      TokenType = "LTE"      // <=
 	GTE      TokenType = "GTE"      // >=
 )
 
+// ContextualCommands are the command names that are commands only where a
+// command can stand. Elsewhere they are ordinary names, so scripts can
+// still use them for variables: head = 3, print find.
+var ContextualCommands = map[TokenType]bool{
+	SET:      true,
+	TRASH:    true,
+	UNDO:     true,
+	COPY:     true,
+	MOVE:     true,
+	LINK:     true,
+	TOUCH:    true,
+	GREP:     true,
+	HEAD:     true,
+	TAIL:     true,
+	WC:       true,
+	SORT:     true,
+	UNIQ:     true,
+	FIND:     true,
+	WHERE:    true,
+	SORTBY:   true,
+	SELECT:   true,
+	FROMJSON: true,
+	TOJSON:   true,
+	FROMCSV:  true,
+	TOCSV:    true,
+	FROMTSV:  true,
+	TOTSV:    true,
+	BIND:     true,
+}
+
 var TokenMap = map[string]TokenType{
-	"ls":     LIST,
-	"rm":     REMOVE,
-	"mkdir":  MAKEDIR,
-	"rmdir":  REMOVEDIR,
-	"cd":     CHANGEDIR,
-	"cwd":    CURRENTDIR,
-	"whoami": WHOAMI,
-	"mkfile": MAKEFILE,
-	"output": OUTPUT,
-	"print":  PRINT,
-	"show":   SHOW,
-	"clear":  CLEAR,
-	"for":    FOR,
-	"in":     IN,
-	"if":     IF,
-	"else":   ELSE,
-	"range":  RANGE,
-	"append": APPEND,
+	"ls":        LIST,
+	"rm":        REMOVE,
+	"mkdir":     MAKEDIR,
+	"rmdir":     REMOVEDIR,
+	"cd":        CHANGEDIR,
+	"cwd":       CURRENTDIR,
+	"whoami":    WHOAMI,
+	"mkfile":    MAKEFILE,
+	"output":    OUTPUT,
+	"print":     PRINT,
+	"show":      SHOW,
+	"clear":     CLEAR,
+	"set":       SET,
+	"trash":     TRASH,
+	"undo":      UNDO,
+	"cp":        COPY,
+	"mv":        MOVE,
+	"ln":        LINK,
+	"touch":     TOUCH,
+	"grep":      GREP,
+	"head":      HEAD,
+	"tail":      TAIL,
+	"wc":        WC,
+	"sort":      SORT,
+	"uniq":      UNIQ,
+	"find":      FIND,
+	"where":     WHERE,
+	"sort-by":   SORTBY,
+	"select":    SELECT,
+	"from-json": FROMJSON,
+	"to-json":   TOJSON,
+	"from-csv":  FROMCSV,
+	"to-csv":    TOCSV,
+	"from-tsv":  FROMTSV,
+	"to-tsv":    TOTSV,
+	"bind":      BIND,
+	"for":       FOR,
+	"in":        IN,
+	"if":        IF,
+	"else":      ELSE,
+	"try":       TRY,
+	"catch":     CATCH,
+	"finally":   FINALLY,
+	"range":     RANGE,
+	"append":    APPEND,
 }